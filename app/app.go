@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/bvisness/flowshell/app/core"
 
@@ -18,6 +19,9 @@ var ShouldQuit bool
 func Main() {
 	CurrentSettings = LoadSettings()
 	core.ApplyTheme(CurrentSettings.Theme)
+	core.ResultCacheDir = CurrentSettings.CacheDir
+	core.NodeScheduler = CurrentSettings.Scheduler()
+	defer core.LogEvents(os.Stdout)()
 
 	rl.SetTraceLogLevel(rl.LogError)
 	rl.SetConfigFlags(rl.FlagWindowResizable)
@@ -80,14 +84,24 @@ func Main() {
 		return clay.Dimensions{Width: dims.X, Height: dims.Y}
 	}, nil)
 
+	StartSession()
+
 	rl.SetExitKey(0)
-	for !rl.WindowShouldClose() && !ShouldQuit {
+	for !ShouldQuit {
+		if rl.WindowShouldClose() {
+			ConfirmDiscardChanges("Discard changes and quit?", "Quit", func() { ShouldQuit = true })
+		}
 		frame()
 	}
+
+	// Only a clean exit removes the recovery file. This is not deferred, so
+	// that a panic leaves it behind.
+	RemoveRecovery()
 }
 
 func frame() {
 	core.Drag.Update()
+	UpdateSession()
 
 	// Handle Zoom Input
 	wheel := rl.GetMouseWheelMove()
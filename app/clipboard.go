@@ -70,7 +70,7 @@ func Copy() {
 	data := CopyToData()
 
 	// Serialize
-	s := core.NewEncoder(3)
+	s := core.NewEncoder(core.SerializeVersion)
 	if core.SThing(s, data) {
 		str := base64.StdEncoding.EncodeToString(s.Bytes())
 		rl.SetClipboardText(str)
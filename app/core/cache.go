@@ -0,0 +1,264 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The serializer version used when hashing actions and values. Bump this if
+// the hashed encoding ever changes, so that stale keys stop matching.
+const resultKeyVersion = 12
+
+// A ResultKey identifies the result a node would produce: the node's action,
+// its settings, and the values arriving on its input ports. Two runs with the
+// same key are expected to produce the same outputs.
+type ResultKey [sha256.Size]byte
+
+// NodeActionWithSideEffects can be implemented by actions whose results depend
+// on more than their settings and inputs (the file system, processes, the
+// network, the user). Such nodes always execute instead of reusing a cached
+// result.
+type NodeActionWithSideEffects interface {
+	HasSideEffects() bool
+}
+
+// NodeActionWithCacheKey can be implemented by actions whose results depend on
+// external state that can be cheaply fingerprinted, such as a file's size and
+// modification time. The returned bytes are mixed into the node's ResultKey.
+// Returning !ok makes the node uncacheable for this run.
+type NodeActionWithCacheKey interface {
+	CacheKey(n *Node) ([]byte, bool)
+}
+
+// ResultKey computes the content hash of the node's action config and current
+// input values. ok is false if the node cannot be cached, either because
+// caching is disabled for it, its action has side effects, or an input is
+// missing or a stream.
+func (n *Node) ResultKey() (key ResultKey, ok bool) {
+	if n.Action == nil || n.NoCache {
+		return ResultKey{}, false
+	}
+	if se, isSE := n.Action.(NodeActionWithSideEffects); isSE && se.HasSideEffects() {
+		return ResultKey{}, false
+	}
+
+	h := sha256.New()
+
+	s := NewEncoder(resultKeyVersion)
+	s.WriteStr(n.Action.Tag())
+	n.Action.Serialize(s)
+	if !s.Ok() {
+		return ResultKey{}, false
+	}
+	h.Write(s.Bytes())
+
+	if ck, isCK := n.Action.(NodeActionWithCacheKey); isCK {
+		extra, ok := ck.CacheKey(n)
+		if !ok {
+			return ResultKey{}, false
+		}
+		h.Write(extra)
+	}
+
+	for i := range n.InputPorts {
+		val, wired, err := n.GetInputValue(i)
+		if err != nil {
+			return ResultKey{}, false
+		}
+		if !wired {
+			if n.InputIsWired(i) {
+				// Wired, but upstream has no result yet.
+				return ResultKey{}, false
+			}
+			h.Write([]byte{0})
+			continue
+		}
+
+		valHash, ok := HashFlowValue(val)
+		if !ok {
+			return ResultKey{}, false
+		}
+		h.Write([]byte{1})
+		h.Write(valHash[:])
+	}
+
+	copy(key[:], h.Sum(nil))
+	return key, true
+}
+
+// HashFlowValue returns a content hash of a value. Streams cannot be hashed
+// without consuming them, so values containing streams report !ok.
+func HashFlowValue(v FlowValue) (ResultKey, bool) {
+	if ContainsStream(v) {
+		return ResultKey{}, false
+	}
+
+	s := NewEncoder(resultKeyVersion)
+	SThing(s, &v)
+	if !s.Ok() {
+		return ResultKey{}, false
+	}
+	return sha256.Sum256(s.Bytes()), true
+}
+
+// ContainsStream reports whether a value is, or contains, a stream.
+func ContainsStream(v FlowValue) bool {
+	if v.Type != nil && v.Type.Kind == FSKindStream {
+		return true
+	}
+	for _, item := range v.ListValue {
+		if ContainsStream(item) {
+			return true
+		}
+	}
+	for _, field := range v.RecordValue {
+		if ContainsStream(field.Value) {
+			return true
+		}
+	}
+	for _, e := range v.MapValue {
+		if ContainsStream(e.Value) {
+			return true
+		}
+	}
+	for _, row := range v.TableValue {
+		for _, field := range row {
+			if ContainsStream(field.Value) {
+				return true
+			}
+		}
+	}
+	if v.Table != nil {
+		// Only cells kept as values, rather than in a typed vector, can be
+		// streams.
+		for _, c := range v.Table.Columns {
+			for _, val := range c.values {
+				if ContainsStream(val) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func resultIsCacheable(res NodeActionResult) bool {
+	if res.Err != nil {
+		return false
+	}
+	for _, output := range res.Outputs {
+		if ContainsStream(output) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Node) forgetCachedResult() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cachedResult = nil
+}
+
+// ---------------------------
+// Persistent cache
+
+// ResultCacheDir, if non-empty, is a directory where cacheable node results
+// are stored so that they survive restarts. The GUI and headless runs share it.
+var ResultCacheDir string
+
+const resultCacheExt = ".fsresult"
+
+func resultCachePath(key ResultKey) string {
+	return filepath.Join(ResultCacheDir, hex.EncodeToString(key[:])+resultCacheExt)
+}
+
+// LoadCachedResult looks up previously stored outputs for a key in
+// ResultCacheDir. A corrupt entry is not found, and reported in err.
+func LoadCachedResult(key ResultKey) (outputs []FlowValue, ok bool, err error) {
+	if ResultCacheDir == "" {
+		return nil, false, nil
+	}
+
+	data, err := os.ReadFile(resultCachePath(key))
+	if err != nil {
+		return nil, false, nil
+	}
+
+	s := NewDecoder(data)
+	if !SSlice(s, &outputs) {
+		return nil, false, fmt.Errorf("ignoring corrupt cache entry %s: %v", resultCachePath(key), s.Errs)
+	}
+	return outputs, true, nil
+}
+
+// StoreCachedResult writes outputs to ResultCacheDir under the given key. It
+// does nothing if no cache directory is configured.
+func StoreCachedResult(key ResultKey, outputs []FlowValue) error {
+	if ResultCacheDir == "" {
+		return nil
+	}
+
+	s := NewEncoder(resultKeyVersion)
+	SSlice(s, &outputs)
+	if !s.Ok() {
+		return fmt.Errorf("failed to serialize result: %v", s.Errs)
+	}
+
+	if err := os.MkdirAll(ResultCacheDir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename so that concurrent readers never see a
+	// partially written entry.
+	tmp, err := os.CreateTemp(ResultCacheDir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(s.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), resultCachePath(key))
+}
+
+// ClearResultCache deletes every stored result in ResultCacheDir.
+func ClearResultCache() error {
+	if ResultCacheDir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(ResultCacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), resultCacheExt) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(ResultCacheDir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearCachedResults drops the in-memory cached results of every node in the
+// graph, so that the next run executes them again.
+func (g *Graph) ClearCachedResults() {
+	for _, n := range g.Nodes {
+		n.forgetCachedResult()
+	}
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"slices"
+)
+
+// The name of the optional output port that receives a node's error. A node
+// with an error port does not fail its downstream nodes; instead its regular
+// outputs are skipped and the error is sent down the error port as an FSError
+// record.
+const ErrorPortName = "Error"
+
+// FSError is the type of values produced by error ports and the Try node.
+var FSError = &FlowType{
+	Kind: FSKindRecord,
+	Fields: []FlowField{
+		{Name: "node", Type: &FlowType{Kind: FSKindBytes}},
+		{Name: "message", Type: &FlowType{Kind: FSKindBytes}},
+		{Name: "stack", Type: &FlowType{Kind: FSKindBytes}},
+	},
+}
+
+func NewErrorValue(node string, err error, stack string) FlowValue {
+	return FlowValue{
+		Type: FSError,
+		RecordValue: []FlowValueField{
+			{Name: "node", Value: NewStringValue(node)},
+			{Name: "message", Value: NewStringValue(err.Error())},
+			{Name: "stack", Value: NewStringValue(stack)},
+		},
+	}
+}
+
+// SetErrorPort adds or removes the node's error port. Removing it also
+// removes any wires attached to it.
+func (n *Node) SetErrorPort(enabled bool) {
+	if enabled == n.ErrorPort {
+		return
+	}
+
+	if enabled {
+		n.OutputPorts = append(n.OutputPorts, NodePort{Name: ErrorPortName, Type: *FSError})
+	} else {
+		port := n.ErrorPortIndex()
+		if n.Graph != nil {
+			n.Graph.Wires = slices.DeleteFunc(n.Graph.Wires, func(w *Wire) bool {
+				return w.StartNode == n && w.StartPort == port
+			})
+		}
+		n.OutputPorts = n.OutputPorts[:port]
+	}
+	n.ErrorPort = enabled
+	n.ClearResult()
+}
+
+// ErrorPortIndex returns the index of the error port in OutputPorts, or -1 if
+// the node has none. The error port is always last.
+func (n *Node) ErrorPortIndex() int {
+	if !n.ErrorPort {
+		return -1
+	}
+	return len(n.OutputPorts) - 1
+}
+
+// ActionOutputPorts returns the output ports filled in by the node's action,
+// i.e. every port except the error port.
+func (n *Node) ActionOutputPorts() []NodePort {
+	if n.ErrorPort {
+		return n.OutputPorts[:len(n.OutputPorts)-1]
+	}
+	return n.OutputPorts
+}
+
+// Builds the result of a node whose error is routed to its error port.
+func (n *Node) errorPortResult(err error, stack string) NodeActionResult {
+	outputs := make([]FlowValue, len(n.OutputPorts))
+	for i := range outputs {
+		outputs[i] = FlowValue{Type: &n.OutputPorts[i].Type, Skipped: true}
+	}
+	outputs[n.ErrorPortIndex()] = NewErrorValue(n.String(), err, stack)
+	return NodeActionResult{Outputs: outputs}
+}
+
+// GetInputError returns the error of the node wired to the given input port,
+// if it failed. Only useful for nodes with HandleFailedInputs set.
+func (n *Node) GetInputError(port int) error {
+	wire, ok := n.GetInputWire(port)
+	if !ok {
+		return nil
+	}
+	res, ok := wire.StartNode.GetResult()
+	if !ok {
+		return nil
+	}
+	return res.Err
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+type NodeEventKind int
+
+const (
+	// The node was asked to run and is waiting on its inputs.
+	NodeQueued NodeEventKind = iota + 1
+	// All inputs are ready and the action has started.
+	NodeStarted
+	// The node did not execute because one of its inputs was skipped.
+	NodeSkipped
+	// The node produced a result, either by running or from the cache.
+	NodeFinished
+	// The node, or one of its inputs, produced an error.
+	NodeFailed
+	// The run's context was cancelled before the node finished.
+	NodeCancelled
+	// An attempt of the action failed and will be retried. See RetryPolicy.
+	NodeRetrying
+	// The node's entry in ResultCacheDir could not be read or written. The
+	// node runs, or keeps its result, as if there were no entry.
+	NodeCacheError
+)
+
+func (k NodeEventKind) String() string {
+	switch k {
+	case NodeQueued:
+		return "queued"
+	case NodeStarted:
+		return "started"
+	case NodeSkipped:
+		return "skipped"
+	case NodeFinished:
+		return "finished"
+	case NodeFailed:
+		return "failed"
+	case NodeCancelled:
+		return "cancelled"
+	case NodeRetrying:
+		return "retrying"
+	case NodeCacheError:
+		return "cache error"
+	default:
+		return fmt.Sprintf("NodeEventKind(%d)", int(k))
+	}
+}
+
+// Terminal reports whether this is the last event of a node's run.
+func (k NodeEventKind) Terminal() bool {
+	return k == NodeSkipped || k == NodeFinished || k == NodeFailed || k == NodeCancelled
+}
+
+type NodeEvent struct {
+	Kind NodeEventKind
+	Node *Node
+	Time time.Time
+
+	// On terminal events: Waiting is the time between being queued and the
+	// action starting (i.e. time spent on inputs), and Running is the time
+	// spent in the action itself. Running is zero if the action never started.
+	Waiting time.Duration
+	Running time.Duration
+
+	Err     error // for NodeFailed, NodeCancelled, NodeRetrying, and NodeCacheError
+	Cached  bool  // for NodeFinished, if the result came from the cache
+	Attempt int   // for NodeRetrying, the number of the attempt that failed
+}
+
+func (e NodeEvent) String() string {
+	switch e.Kind {
+	case NodeFailed, NodeCancelled:
+		return fmt.Sprintf("%s %s after %v: %v", e.Node, e.Kind, e.Waiting+e.Running, e.Err)
+	case NodeRetrying:
+		if e.Err != nil {
+			return fmt.Sprintf("%s %s after attempt %d: %v", e.Node, e.Kind, e.Attempt, e.Err)
+		}
+		return fmt.Sprintf("%s %s after attempt %d", e.Node, e.Kind, e.Attempt)
+	case NodeCacheError:
+		return fmt.Sprintf("%s %s: %v", e.Node, e.Kind, e.Err)
+	case NodeFinished:
+		if e.Cached {
+			return fmt.Sprintf("%s %s (cached)", e.Node, e.Kind)
+		}
+		return fmt.Sprintf("%s %s in %v", e.Node, e.Kind, e.Running)
+	default:
+		return fmt.Sprintf("%s %s", e.Node, e.Kind)
+	}
+}
+
+// EventBus delivers node lifecycle events to subscribers. Handlers are called
+// synchronously on the goroutine running the node, so they should be quick
+// and must not call back into the node.
+type EventBus struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]func(NodeEvent)
+}
+
+// Events receives the lifecycle events of every node run.
+var Events = &EventBus{}
+
+// Subscribe registers a handler for all future events. Call the returned
+// function to unsubscribe.
+func (b *EventBus) Subscribe(handler func(NodeEvent)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs == nil {
+		b.subs = make(map[int]func(NodeEvent))
+	}
+	b.nextID++
+	id := b.nextID
+	b.subs[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+func (b *EventBus) Publish(ev NodeEvent) {
+	b.mu.Lock()
+	handlers := make([]func(NodeEvent), 0, len(b.subs))
+	for _, h := range b.subs {
+		handlers = append(handlers, h)
+	}
+	b.mu.Unlock()
+
+	for _, h := range handlers {
+		h(ev)
+	}
+}
+
+// LogEvents prints every event to w, one per line.
+func LogEvents(w io.Writer) (unsubscribe func()) {
+	return Events.Subscribe(func(ev NodeEvent) {
+		fmt.Fprintf(w, "[%s] %s\n", ev.Time.Format("15:04:05.000"), ev)
+	})
+}
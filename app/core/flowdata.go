@@ -13,13 +13,27 @@ type FlowValue struct {
 
 	Skipped bool
 
+	// Null marks a missing value. Its type is nullable, and the other fields
+	// are unset.
+	Null bool
+
 	BytesValue   []byte
 	StreamValue  io.ReadCloser
 	Int64Value   int64
 	Float64Value float64
+	BoolValue    bool
 	ListValue    []FlowValue
 	RecordValue  []FlowValueField
 	TableValue   [][]FlowValueField
+
+	// Table, if set, holds the rows of a table by column, in place of
+	// TableValue. Read tables through NumRows, RowFields, TableRows and
+	// ColumnValues to handle both.
+	Table *Table `json:"-"`
+
+	// MapValue holds the entries of a map, sorted by key, with the key as
+	// the name of each field.
+	MapValue []FlowValueField
 }
 
 type FlowValueField struct {
@@ -33,11 +47,60 @@ func (f *FlowValueField) Serialize(s *Serializer) bool {
 	return s.Ok()
 }
 
+// NewTableValue makes a table value held by column in t.
+func NewTableValue(t *Table) FlowValue {
+	tableType := NewTableType(t.Fields)
+	return FlowValue{Type: &tableType, Table: t}
+}
+
+// NumRows returns the number of rows of a table.
+func (v *FlowValue) NumRows() int {
+	if v.Table != nil {
+		return v.Table.Len()
+	}
+	return len(v.TableValue)
+}
+
+// RowFields returns row i of a table. For a table held by column, the row is
+// made on each call.
+func (v *FlowValue) RowFields(i int) []FlowValueField {
+	if v.Table != nil {
+		return v.Table.Row(i)
+	}
+	return v.TableValue[i]
+}
+
+// TableRows returns the rows of a table. For a table held by column, they
+// are made on each call, so loop over RowFields to avoid holding them all.
+func (v *FlowValue) TableRows() [][]FlowValueField {
+	if v.Table != nil {
+		return v.Table.Rows()
+	}
+	return v.TableValue
+}
+
+// Cell returns column col of row i of a table.
+func (v *FlowValue) Cell(i, col int) FlowValue {
+	if v.Table != nil {
+		return v.Table.Columns[col].Value(i)
+	}
+	return v.TableValue[i][col].Value
+}
+
 func (v *FlowValue) ColumnValues(col int) []FlowValue {
 	if v.Type.Kind != FSKindTable {
 		panic(fmt.Errorf("value %s was not a table", v))
 	}
 
+	if v.Table != nil {
+		c := v.Table.Columns[col]
+		res := make([]FlowValue, c.Len())
+		for i := range res {
+			res[i] = c.Value(i)
+		}
+		return res
+	}
+
 	var res []FlowValue
 	for _, row := range v.TableValue {
 		res = append(res, row[col].Value)
@@ -45,8 +108,61 @@ func (v *FlowValue) ColumnValues(col int) []FlowValue {
 	return res
 }
 
-func (v FlowValue) String() string {
-	return "???(" + v.Type.String() + ")"
+// Column returns the values of the named column of a table.
+func (v *FlowValue) Column(name string) ([]FlowValue, bool) {
+	col := v.Type.FieldIndex(name)
+	if col < 0 {
+		return nil, false
+	}
+	if v.Table != nil {
+		return v.ColumnValues(col), true
+	}
+	res := make([]FlowValue, 0, len(v.TableValue))
+	for _, row := range v.TableValue {
+		val, _ := RowField(row, name)
+		res = append(res, val)
+	}
+	return res, true
+}
+
+// Row returns row i of a table as a record.
+func (v *FlowValue) Row(i int) FlowValue {
+	t := FlowType{Kind: FSKindRecord}
+	if v.Type.ContainedType != nil {
+		t = *v.Type.ContainedType
+	}
+	return FlowValue{Type: &t, RecordValue: v.RowFields(i)}
+}
+
+// Field returns the named field of a record.
+func (v FlowValue) Field(name string) (FlowValue, bool) {
+	return RowField(v.RecordValue, name)
+}
+
+// RowField returns the named field of a record's fields or a table row.
+// Fields are found by name, since a value may have more fields than its
+// type asks for, in a different order (see Typecheck).
+func RowField(fields []FlowValueField, name string) (FlowValue, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return FlowValue{}, false
+}
+
+// ProjectFields returns the fields of a record's fields or a table row that
+// are named in want, in the order of want. It fails if any are missing.
+func ProjectFields(fields []FlowValueField, want []FlowField) ([]FlowValueField, bool) {
+	res := make([]FlowValueField, len(want))
+	for i, w := range want {
+		val, ok := RowField(fields, w.Name)
+		if !ok {
+			return nil, false
+		}
+		res[i] = FlowValueField{Name: w.Name, Value: val}
+	}
+	return res, true
 }
 
 func (v *FlowValue) Serialize(s *Serializer) bool {
@@ -90,6 +206,13 @@ func (v *FlowValue) Serialize(s *Serializer) bool {
 		return s.Ok()
 	}
 
+	if s.Version >= 12 {
+		SBool(s, &v.Null)
+		if v.Null {
+			return s.Ok()
+		}
+	}
+
 	switch v.Type.Kind {
 	case FSKindBytes:
 		nBytes := len(v.BytesValue)
@@ -113,19 +236,30 @@ func (v *FlowValue) Serialize(s *Serializer) bool {
 		SInt(s, &v.Int64Value)
 	case FSKindFloat64:
 		SFloat(s, &v.Float64Value)
+	case FSKindBool:
+		SBool(s, &v.BoolValue)
 	case FSKindList:
 		SSlice(s, &v.ListValue)
 	case FSKindRecord:
 		SSlice(s, &v.RecordValue)
 	case FSKindTable:
-		nTable := len(v.TableValue)
+		// A table held by column is written row by row all the same, and
+		// read back as rows.
+		nTable := v.NumRows()
 		SInt(s, &nTable)
 		if !s.Encode {
 			v.TableValue = make([][]FlowValueField, nTable)
 		}
 		for i := 0; i < nTable; i++ {
-			SSlice(s, &v.TableValue[i])
+			if s.Encode && v.Table != nil {
+				row := v.Table.Row(i)
+				SSlice(s, &row)
+			} else {
+				SSlice(s, &v.TableValue[i])
+			}
 		}
+	case FSKindMap:
+		SSlice(s, &v.MapValue)
 	}
 
 	return s.Ok()
@@ -134,7 +268,7 @@ func (v *FlowValue) Serialize(s *Serializer) bool {
 type FlowTypeKind int
 
 const (
-	FSKindAny FlowTypeKind = iota // not valid for use on a FlowValue
+	FSKindAny FlowTypeKind = iota // not valid for use on a FlowValue, except for a null of unknown type
 	FSKindBytes
 	FSKindStream
 	FSKindInt64
@@ -142,6 +276,8 @@ const (
 	FSKindList
 	FSKindRecord
 	FSKindTable
+	FSKindBool
+	FSKindMap // string keys, and values of the contained type
 )
 
 type FlowType struct {
@@ -157,9 +293,19 @@ type FlowType struct {
 	// If set, this type has been annotated as "well-known", meaning some other
 	// operations may be conveniently available on it.
 	WellKnownType FlowWellKnownType
+
+	// If set, values of this type may be null.
+	Nullable bool
 }
 
 func (t FlowType) String() string {
+	if t.Nullable {
+		return t.nonNullString() + "?"
+	}
+	return t.nonNullString()
+}
+
+func (t FlowType) nonNullString() string {
 	switch t.WellKnownType {
 	case FSWKTFile:
 		return "File"
@@ -186,6 +332,8 @@ func (t FlowType) String() string {
 		return "Int64"
 	case FSKindFloat64:
 		return "Float64"
+	case FSKindBool:
+		return "Bool"
 	case FSKindList:
 		if t.ContainedType == nil {
 			return "List[?]"
@@ -198,16 +346,37 @@ func (t FlowType) String() string {
 			return "Table[?]"
 		}
 		return fmt.Sprintf("Table[%s]", joinFields(t.ContainedType.Fields))
+	case FSKindMap:
+		if t.ContainedType == nil {
+			return "Map[?]"
+		}
+		return fmt.Sprintf("Map[%s]", t.ContainedType.String())
 	default:
 		return "<UNKNOWN TYPE>"
 	}
 }
 
+// FieldIndex returns the index of the named field of a record type, or of
+// the rows of a table type, or -1 if there is no such field.
+func (t *FlowType) FieldIndex(name string) int {
+	fields := t.Fields
+	if t.Kind == FSKindTable {
+		if t.ContainedType == nil {
+			return -1
+		}
+		fields = t.ContainedType.Fields
+	}
+	return slices.IndexFunc(fields, func(f FlowField) bool { return f.Name == name })
+}
+
 func (t *FlowType) Serialize(s *Serializer) bool {
 	SInt(s, &t.Kind)
 	SMaybeThing(s, &t.ContainedType)
 	SSlice(s, &t.Fields)
 	SInt(s, &t.Unit)
+	if s.Version >= 12 {
+		SBool(s, &t.Nullable)
+	}
 	return s.Ok()
 }
 
@@ -217,15 +386,24 @@ func Typecheck(a, b FlowType) error {
 		return nil
 	}
 
+	// Values that may be null only go where null is expected, and a null of
+	// unknown type goes anywhere it is.
+	if a.Nullable && !b.Nullable {
+		return fmt.Errorf("expected type %s, but got %s, which may be null", b.String(), a.String())
+	}
+	if a.Nullable && a.Kind == FSKindAny {
+		return nil
+	}
+
 	// Kinds must always match.
 	if a.Kind != b.Kind {
 		return fmt.Errorf("expected type %s, but got %s", b.String(), a.String())
 	}
 
 	switch b.Kind {
-	case FSKindBytes, FSKindInt64, FSKindFloat64:
+	case FSKindBytes, FSKindInt64, FSKindFloat64, FSKindBool:
 		// These are primitives, so if their kinds are the same, there is nothing else to check.
-	case FSKindList, FSKindTable:
+	case FSKindList, FSKindTable, FSKindMap:
 		if b.ContainedType == nil {
 			return nil // Accept any contained type if expected is generic
 		}
@@ -236,15 +414,15 @@ func Typecheck(a, b FlowType) error {
 			return fmt.Errorf("expected type %s, but got %s: %v", b.String(), a.String(), err)
 		}
 	case FSKindRecord:
-		if len(a.Fields) != len(b.Fields) {
-			return fmt.Errorf("record types have different number of fields")
-		}
-		for i := range a.Fields {
-			if a.Fields[i].Name != b.Fields[i].Name {
-				return fmt.Errorf("fields have different names: expected %s, got %s", b.Fields[i].Name, a.Fields[i].Name)
+		// Records match structurally: a must have every field of b, but may
+		// have them in any order, and may have others.
+		for _, field := range b.Fields {
+			i := a.FieldIndex(field.Name)
+			if i < 0 {
+				return fmt.Errorf("expected type %s, but got %s, which has no field %s", b.String(), a.String(), field.Name)
 			}
-			if err := Typecheck(*a.Fields[i].Type, *b.Fields[i].Type); err != nil {
-				return fmt.Errorf("bad type for field %s: %v", a.Fields[i].Name, err)
+			if err := Typecheck(*a.Fields[i].Type, *field.Type); err != nil {
+				return fmt.Errorf("bad type for field %s: %v", field.Name, err)
 			}
 		}
 	}
@@ -349,6 +527,22 @@ func NewTimestampValue(t time.Time) FlowValue {
 	return FlowValue{Type: FSTimestamp, Int64Value: t.Unix()}
 }
 
+func NewBoolValue(v bool) FlowValue {
+	return FlowValue{Type: &FlowType{Kind: FSKindBool}, BoolValue: v}
+}
+
+// NewNullValue returns a missing value of type t, made nullable.
+func NewNullValue(t FlowType) FlowValue {
+	t = NullableType(t)
+	return FlowValue{Type: &t, Null: true}
+}
+
+// NullableType returns t, allowing null values.
+func NullableType(t FlowType) FlowType {
+	t.Nullable = true
+	return t
+}
+
 func NewListValue(contained FlowType, items []FlowValue) FlowValue {
 	t := NewListType(contained)
 	for _, item := range items {
@@ -362,48 +556,145 @@ func NewListValue(contained FlowType, items []FlowValue) FlowValue {
 	}
 }
 
+// CommonType returns a type that all of vals have: the type of the first
+// value that is not null, or Any if their kinds differ. Nulls take the type of
+// the other values, and make it nullable.
+func CommonType(vals []FlowValue) FlowType {
+	var t *FlowType
+	hasNull := false
+	for _, v := range vals {
+		if v.Null {
+			hasNull = true
+			continue
+		}
+		if t == nil {
+			t = v.Type
+		} else if v.Type.Kind != t.Kind {
+			t = &FlowType{Kind: FSKindAny}
+		}
+	}
+	if t == nil {
+		t = &FlowType{Kind: FSKindAny}
+	}
+	if hasNull {
+		return NullableType(*t)
+	}
+	return *t
+}
+
+func NewMapType(value FlowType) FlowType {
+	return FlowType{
+		Kind:          FSKindMap,
+		ContainedType: &value,
+	}
+}
+
+// NewMapValue makes a map from entries, named by key, which it sorts. Keys
+// must be unique.
+func NewMapValue(value FlowType, entries []FlowValueField) FlowValue {
+	t := NewMapType(value)
+	for _, entry := range entries {
+		if err := Typecheck(*entry.Value.Type, value); err != nil {
+			panic(err)
+		}
+	}
+	entries = slices.Clone(entries)
+	slices.SortFunc(entries, func(a, b FlowValueField) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return FlowValue{
+		Type:     &t,
+		MapValue: entries,
+	}
+}
+
+// MapFromEntries makes a map from entries, named by key, whose value type is
+// the CommonType of their values.
+func MapFromEntries(entries []FlowValueField) FlowValue {
+	entries = slices.Clone(entries)
+	vals := make([]FlowValue, len(entries))
+	for i, entry := range entries {
+		vals[i] = entry.Value
+	}
+	valueType := CommonType(vals)
+	for i := range entries {
+		if entries[i].Value.Null {
+			entries[i].Value.Type = &valueType
+		}
+	}
+	return NewMapValue(valueType, entries)
+}
+
+// MapEntry returns the value for key in a map.
+func (v FlowValue) MapEntry(key string) (FlowValue, bool) {
+	i, ok := slices.BinarySearchFunc(v.MapValue, key, func(e FlowValueField, key string) int {
+		return strings.Compare(e.Name, key)
+	})
+	if !ok {
+		return FlowValue{}, false
+	}
+	return v.MapValue[i].Value, true
+}
+
+// A NativeMap is how FlowValueToNative gives a map, so that a map survives
+// the trip to native values and back, where a map[string]any would become
+// a record.
+type NativeMap map[string]any
+
 func NativeToFlowValue(v any) (FlowValue, error) {
+	return nativeToFlowValue(v, false)
+}
+
+// NativeMapsToFlowValue is like NativeToFlowValue, but makes every
+// map[string]any into a map rather than a record, for objects whose keys are
+// data rather than a schema.
+func NativeMapsToFlowValue(v any) (FlowValue, error) {
+	return nativeToFlowValue(v, true)
+}
+
+func nativeToFlowValue(v any, objectsAsMaps bool) (FlowValue, error) {
 	switch val := v.(type) {
 	case nil:
-		return NewStringValue(""), nil
+		return NewNullValue(FlowType{Kind: FSKindAny}), nil
 	case string:
 		return NewStringValue(val), nil
+	case int:
+		return NewInt64Value(int64(val), 0), nil
+	case int64:
+		return NewInt64Value(val, 0), nil
 	case float64:
 		if float64(int64(val)) == val {
 			return NewInt64Value(int64(val), 0), nil
 		}
 		return NewFloat64Value(val, 0), nil
 	case bool:
-		if val {
-			return NewInt64Value(1, 0), nil
-		}
-		return NewInt64Value(0, 0), nil
+		return NewBoolValue(val), nil
 	case []any:
 		var list []FlowValue
-		var elemType *FlowType
 		for _, item := range val {
-			fv, err := NativeToFlowValue(item)
+			fv, err := nativeToFlowValue(item, objectsAsMaps)
 			if err != nil {
 				return FlowValue{}, err
 			}
-			if elemType == nil {
-				elemType = fv.Type
-			} else {
-				if fv.Type.Kind != elemType.Kind {
-					elemType = &FlowType{Kind: FSKindAny}
-				}
-			}
 			list = append(list, fv)
 		}
-		if elemType == nil {
-			elemType = &FlowType{Kind: FSKindAny}
+		elemType := CommonType(list)
+		for i := range list {
+			if list[i].Null {
+				list[i].Type = &elemType
+			}
 		}
-		return NewListValue(*elemType, list), nil
+		return NewListValue(elemType, list), nil
+	case NativeMap:
+		return nativeMapToFlowValue(val, objectsAsMaps)
 	case map[string]any:
+		if objectsAsMaps {
+			return nativeMapToFlowValue(val, objectsAsMaps)
+		}
 		var fields []FlowValueField
 		var fieldTypes []FlowField
 		for k, v := range val {
-			fv, err := NativeToFlowValue(v)
+			fv, err := nativeToFlowValue(v, objectsAsMaps)
 			if err != nil {
 				return FlowValue{}, err
 			}
@@ -425,12 +716,29 @@ func NativeToFlowValue(v any) (FlowValue, error) {
 	}
 }
 
+func nativeMapToFlowValue(m map[string]any, objectsAsMaps bool) (FlowValue, error) {
+	var entries []FlowValueField
+	for k, v := range m {
+		fv, err := nativeToFlowValue(v, objectsAsMaps)
+		if err != nil {
+			return FlowValue{}, err
+		}
+		entries = append(entries, FlowValueField{Name: k, Value: fv})
+	}
+	return MapFromEntries(entries), nil
+}
+
 func FlowValueToNative(v FlowValue) interface{} {
+	if v.Null {
+		return nil
+	}
 	switch v.Type.Kind {
 	case FSKindInt64:
 		return v.Int64Value
 	case FSKindFloat64:
 		return v.Float64Value
+	case FSKindBool:
+		return v.BoolValue
 	case FSKindBytes:
 		return string(v.BytesValue) // Expose as string
 	case FSKindList:
@@ -446,8 +754,9 @@ func FlowValueToNative(v FlowValue) interface{} {
 		}
 		return rec
 	case FSKindTable:
-		table := make([]interface{}, len(v.TableValue))
-		for i, row := range v.TableValue {
+		table := make([]interface{}, v.NumRows())
+		for i := range table {
+			row := v.RowFields(i)
 			rec := make(map[string]interface{})
 			for _, field := range row {
 				rec[field.Name] = FlowValueToNative(field.Value)
@@ -455,6 +764,12 @@ func FlowValueToNative(v FlowValue) interface{} {
 			table[i] = rec
 		}
 		return table
+	case FSKindMap:
+		m := make(NativeMap, len(v.MapValue))
+		for _, entry := range v.MapValue {
+			m[entry.Name] = FlowValueToNative(entry.Value)
+		}
+		return m
 	}
 	return nil
 }
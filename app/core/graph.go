@@ -17,6 +17,14 @@ type Graph struct {
 	VarMutex    sync.RWMutex
 	NextNodeID  int
 	NextGroupID int
+
+	// Problems found while loading that did not prevent loading, such as
+	// wires to missing nodes. See ValidateGraph.
+	LoadProblems []string
+
+	// The graph containing the subgraph node this graph is embedded in, if
+	// any. Variables not set here are looked up in the parent.
+	Parent *Graph
 }
 
 func NewGraph() *Graph {
@@ -66,6 +74,20 @@ func (g *Graph) GetNode(id int) (*Node, bool) {
 	return nil, false
 }
 
+// LookupVariable returns the value of a graph variable, looking in the parent
+// graphs of a subgraph if it is not set in g itself.
+func (g *Graph) LookupVariable(name string) (string, bool) {
+	for ; g != nil; g = g.Parent {
+		g.VarMutex.RLock()
+		v, ok := g.Variables[name]
+		g.VarMutex.RUnlock()
+		if ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
 type Group struct {
 	ID    int
 	Title string
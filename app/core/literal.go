@@ -0,0 +1,542 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Values are written and read as literals like these:
+//
+//	null  true  42  -1.5  1024B  30s  "text"
+//	[1, 2, 3]
+//	{name: "Al", "first name": "Al"}
+//	map{"a": 1, "b": 2}
+//	table[{a: 1, b: "x"}, {a: 2, b: "y"}]
+//	timestamp("2024-01-02T03:04:05Z")
+//	file{name: "a.txt", ...}
+//
+// A number with a unit has the unit's suffix: B for bytes and s for seconds.
+// Floats always have a point or an exponent, or are inf, -inf or nan. Field
+// names are quoted unless they are identifiers. Timestamps that RFC 3339
+// cannot express, before year 0 or after 9999, are written in Unix seconds,
+// as in timestamp(253402300800).
+//
+// Types are not written, so ParseValue infers them from the values, as
+// CommonType does: nulls, and the contents of empty lists, maps and tables,
+// come back as Any, and types are nullable only where a null appears.
+
+func (v FlowValue) String() string {
+	var b strings.Builder
+	writeValue(&b, v)
+	return b.String()
+}
+
+var unitSuffixes = map[FlowUnit]string{
+	FSUnitBytes:   "B",
+	FSUnitSeconds: "s",
+}
+
+func writeValue(b *strings.Builder, v FlowValue) {
+	if v.Type == nil {
+		b.WriteString("<no value>")
+		return
+	}
+	if v.Null {
+		b.WriteString("null")
+		return
+	}
+
+	switch v.Type.Kind {
+	case FSKindBytes:
+		b.WriteString(strconv.Quote(string(v.BytesValue)))
+	case FSKindStream:
+		b.WriteString("<stream>")
+	case FSKindInt64:
+		if v.Type.WellKnownType == FSWKTTimestamp {
+			if t := time.Unix(v.Int64Value, 0).UTC(); 0 <= t.Year() && t.Year() <= 9999 {
+				fmt.Fprintf(b, "timestamp(%q)", t.Format(time.RFC3339))
+			} else {
+				fmt.Fprintf(b, "timestamp(%d)", v.Int64Value)
+			}
+			return
+		}
+		b.WriteString(strconv.FormatInt(v.Int64Value, 10))
+		b.WriteString(unitSuffixes[v.Type.Unit])
+	case FSKindFloat64:
+		f := v.Float64Value
+		switch {
+		case math.IsNaN(f):
+			b.WriteString("nan")
+		case math.IsInf(f, 1):
+			b.WriteString("inf")
+		case math.IsInf(f, -1):
+			b.WriteString("-inf")
+		default:
+			str := strconv.FormatFloat(f, 'g', -1, 64)
+			if !strings.ContainsAny(str, ".e") {
+				str += ".0"
+			}
+			b.WriteString(str)
+		}
+		b.WriteString(unitSuffixes[v.Type.Unit])
+	case FSKindBool:
+		b.WriteString(strconv.FormatBool(v.BoolValue))
+	case FSKindList:
+		b.WriteString("[")
+		for i, item := range v.ListValue {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeValue(b, item)
+		}
+		b.WriteString("]")
+	case FSKindRecord:
+		if v.Type.WellKnownType == FSWKTFile {
+			b.WriteString("file")
+		}
+		writeFields(b, v.RecordValue, false)
+	case FSKindTable:
+		b.WriteString("table[")
+		for i := range v.NumRows() {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if v.Type.ContainedType != nil && v.Type.ContainedType.WellKnownType == FSWKTFile {
+				b.WriteString("file")
+			}
+			writeFields(b, v.RowFields(i), false)
+		}
+		b.WriteString("]")
+	case FSKindMap:
+		b.WriteString("map")
+		writeFields(b, v.MapValue, true)
+	default:
+		fmt.Fprintf(b, "<%s>", v.Type)
+	}
+}
+
+// writeFields writes the fields of a record, or with quoteNames, the entries
+// of a map.
+func writeFields(b *strings.Builder, fields []FlowValueField, quoteNames bool) {
+	b.WriteString("{")
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if quoteNames || !isIdent(f.Name) {
+			b.WriteString(strconv.Quote(f.Name))
+		} else {
+			b.WriteString(f.Name)
+		}
+		b.WriteString(": ")
+		writeValue(b, f.Value)
+	}
+	b.WriteString("}")
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ParseValue reads a value literal, as written by FlowValue.String.
+func ParseValue(src string) (FlowValue, error) {
+	p := literalParser{src: src}
+	v, err := p.value()
+	if err != nil {
+		return FlowValue{}, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return FlowValue{}, p.errorf("unexpected %q after value", p.src[p.pos:])
+	}
+	return v, nil
+}
+
+type literalParser struct {
+	src string
+	pos int
+}
+
+func (p *literalParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *literalParser) skipSpace() {
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+		p.pos += size
+	}
+}
+
+// peek returns the next byte that is not space, or 0 at the end.
+func (p *literalParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *literalParser) expect(c byte) error {
+	if p.peek() != c {
+		if p.pos >= len(p.src) {
+			return p.errorf("expected %q, but the value ended", c)
+		}
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// word reads an identifier, or returns "" if there is none.
+func (p *literalParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if !(r == '_' || unicode.IsLetter(r) || (p.pos > start && unicode.IsDigit(r))) {
+			break
+		}
+		p.pos += size
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *literalParser) str() (string, error) {
+	p.skipSpace()
+	quoted, err := strconv.QuotedPrefix(p.src[p.pos:])
+	if err != nil {
+		return "", p.errorf("bad string")
+	}
+	s, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", p.errorf("bad string: %v", err)
+	}
+	p.pos += len(quoted)
+	return s, nil
+}
+
+func (p *literalParser) value() (FlowValue, error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return FlowValue{}, p.errorf("expected a value, but the value ended")
+	case c == '"' || c == '`':
+		s, err := p.str()
+		return NewStringValue(s), err
+	case c == '-' || c == '.' || ('0' <= c && c <= '9'):
+		return p.number()
+	case strings.HasPrefix(p.src[p.pos:], "inf") || strings.HasPrefix(p.src[p.pos:], "nan"):
+		// Not a word, since a unit may follow, as in infs.
+		return p.number()
+	case c == '[':
+		return p.list()
+	case c == '{':
+		return p.record()
+	}
+
+	start := p.pos
+	switch word := p.word(); word {
+	case "null":
+		return NewNullValue(FlowType{Kind: FSKindAny}), nil
+	case "true", "false":
+		return NewBoolValue(word == "true"), nil
+	case "map":
+		entries, err := p.fields(true)
+		if err != nil {
+			return FlowValue{}, err
+		}
+		return MapFromEntries(entries), nil
+	case "table":
+		return p.table()
+	case "timestamp":
+		if err := p.expect('('); err != nil {
+			return FlowValue{}, err
+		}
+		var t time.Time
+		if c := p.peek(); c == '-' || ('0' <= c && c <= '9') {
+			n, err := p.number()
+			if err != nil {
+				return FlowValue{}, err
+			}
+			if n.Type.Kind != FSKindInt64 || n.Type.Unit != 0 {
+				return FlowValue{}, p.errorf("bad timestamp: expected Unix seconds")
+			}
+			t = time.Unix(n.Int64Value, 0)
+		} else {
+			s, err := p.str()
+			if err != nil {
+				return FlowValue{}, err
+			}
+			if t, err = time.Parse(time.RFC3339, s); err != nil {
+				return FlowValue{}, p.errorf("bad timestamp: %v", err)
+			}
+		}
+		if err := p.expect(')'); err != nil {
+			return FlowValue{}, err
+		}
+		return NewTimestampValue(t), nil
+	case "file":
+		p.pos = start
+		return p.record()
+	case "":
+		r, _ := utf8.DecodeRuneInString(p.src[p.pos:])
+		return FlowValue{}, p.errorf("unexpected %q", r)
+	default:
+		p.pos = start
+		return FlowValue{}, p.errorf("unknown word %q", word)
+	}
+}
+
+func (p *literalParser) number() (FlowValue, error) {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	var f float64
+	isFloat := false
+	if word := p.src[p.pos:min(p.pos+3, len(p.src))]; word == "inf" || word == "nan" {
+		isFloat = true
+		f = math.Inf(1)
+		if word == "nan" {
+			f = math.NaN()
+		} else if p.src[start] == '-' {
+			f = math.Inf(-1)
+		}
+		// Anything after is a unit suffix, as in infs.
+		p.pos += len(word)
+	} else {
+		for p.pos < len(p.src) {
+			c := p.src[p.pos]
+			if c == '.' {
+				isFloat = true
+			} else if c == 'e' || c == 'E' {
+				isFloat = true
+				if p.pos+1 < len(p.src) && (p.src[p.pos+1] == '+' || p.src[p.pos+1] == '-') {
+					p.pos++
+				}
+			} else if c < '0' || '9' < c {
+				break
+			}
+			p.pos++
+		}
+	}
+	text := p.src[start:p.pos]
+
+	var unit FlowUnit
+	if suffix := p.word(); suffix != "" {
+		found := false
+		for u, s := range unitSuffixes {
+			if s == suffix {
+				unit, found = u, true
+			}
+		}
+		if !found {
+			return FlowValue{}, p.errorf("unknown unit %q", suffix)
+		}
+	}
+
+	if isFloat {
+		if !math.IsInf(f, 0) && !math.IsNaN(f) {
+			var err error
+			if f, err = strconv.ParseFloat(text, 64); err != nil {
+				return FlowValue{}, fmt.Errorf("bad number %q", text)
+			}
+		}
+		return NewFloat64Value(f, unit), nil
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return FlowValue{}, fmt.Errorf("bad number %q", text)
+	}
+	return NewInt64Value(n, unit), nil
+}
+
+func (p *literalParser) list() (FlowValue, error) {
+	if err := p.expect('['); err != nil {
+		return FlowValue{}, err
+	}
+	var items []FlowValue
+	for p.peek() != ']' {
+		item, err := p.value()
+		if err != nil {
+			return FlowValue{}, err
+		}
+		items = append(items, item)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(']'); err != nil {
+		return FlowValue{}, err
+	}
+
+	t, err := commonValues(items)
+	if err != nil {
+		return FlowValue{}, err
+	}
+	return NewListValue(t, items), nil
+}
+
+// fields reads the fields of a record, or with quotedNames, the entries of a
+// map. Names must be unique.
+func (p *literalParser) fields(quotedNames bool) ([]FlowValueField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []FlowValueField
+	for p.peek() != '}' {
+		var name string
+		if c := p.peek(); c == '"' || c == '`' {
+			var err error
+			if name, err = p.str(); err != nil {
+				return nil, err
+			}
+		} else if quotedNames {
+			return nil, p.errorf("expected a quoted key")
+		} else if name = p.word(); name == "" {
+			return nil, p.errorf("expected a field name")
+		}
+		if _, dup := RowField(fields, name); dup {
+			return nil, p.errorf("%q appears twice", name)
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, FlowValueField{Name: name, Value: val})
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect('}'); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// record reads a record, which is a file if it starts with "file".
+func (p *literalParser) record() (FlowValue, error) {
+	start := p.pos
+	word := p.word()
+	if word != "" && word != "file" {
+		p.pos = start
+		return FlowValue{}, p.errorf("expected a record")
+	}
+	fields, err := p.fields(false)
+	if err != nil {
+		return FlowValue{}, err
+	}
+	v := recordFromFields(fields)
+	if word == "file" {
+		if err := Typecheck(*v.Type, *FSFile); err != nil {
+			return FlowValue{}, fmt.Errorf("bad file: %v", err)
+		}
+		v.Type.WellKnownType = FSWKTFile
+	}
+	return v, nil
+}
+
+func (p *literalParser) table() (FlowValue, error) {
+	if err := p.expect('['); err != nil {
+		return FlowValue{}, err
+	}
+	var rows [][]FlowValueField
+	var wellKnownType FlowWellKnownType
+	for p.peek() != ']' {
+		record, err := p.record()
+		if err != nil {
+			return FlowValue{}, err
+		}
+		row := record.RecordValue
+		if len(rows) > 0 && !sameNames(row, rows[0]) {
+			return FlowValue{}, p.errorf("every row of a table must have the same fields, in the same order")
+		}
+		if len(rows) > 0 && record.Type.WellKnownType != wellKnownType {
+			return FlowValue{}, p.errorf("every row of a table must be a file, or none")
+		}
+		wellKnownType = record.Type.WellKnownType
+		rows = append(rows, row)
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	if err := p.expect(']'); err != nil {
+		return FlowValue{}, err
+	}
+
+	if len(rows) == 0 {
+		t := NewAnyTableType()
+		return FlowValue{Type: &t}, nil
+	}
+	fields := make([]FlowField, len(rows[0]))
+	for col, f := range rows[0] {
+		column := make([]FlowValue, len(rows))
+		for i, row := range rows {
+			column[i] = row[col].Value
+		}
+		t, err := commonValues(column)
+		if err != nil {
+			return FlowValue{}, fmt.Errorf("in column %s: %v", f.Name, err)
+		}
+		for i := range rows {
+			rows[i][col].Value = column[i]
+		}
+		fields[col] = FlowField{Name: f.Name, Type: &t}
+	}
+	t := NewTableType(fields)
+	t.ContainedType.WellKnownType = wellKnownType
+	return FlowValue{Type: &t, TableValue: rows}, nil
+}
+
+func sameNames(a, b []FlowValueField) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+func recordFromFields(fields []FlowValueField) FlowValue {
+	types := make([]FlowField, len(fields))
+	for i, f := range fields {
+		types[i] = FlowField{Name: f.Name, Type: f.Value.Type}
+	}
+	t := NewRecordType(types)
+	return FlowValue{Type: &t, RecordValue: fields}
+}
+
+// commonValues returns the CommonType of vals, which it gives to the nulls
+// among them, or an error if some do not fit it.
+func commonValues(vals []FlowValue) (FlowType, error) {
+	t := CommonType(vals)
+	for i, v := range vals {
+		if v.Null {
+			vals[i].Type = &t
+		} else if err := Typecheck(*v.Type, t); err != nil {
+			return FlowType{}, fmt.Errorf("item %d does not fit the others: %v", i, err)
+		}
+	}
+	return t, nil
+}
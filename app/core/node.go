@@ -6,6 +6,7 @@ import (
 	"runtime/debug"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/bvisness/flowshell/clay"
 	"github.com/bvisness/flowshell/util"
@@ -25,12 +26,24 @@ type Node struct {
 	Name   string
 	Pinned bool
 
+	// If set, this node's results are never cached, in memory or on disk.
+	NoCache bool
+
+	// If set, the last output port is an error port. See SetErrorPort.
+	ErrorPort bool
+
+	// How to retry the action when it fails, and how long each attempt may
+	// take. A zero Timeout means no limit.
+	Retry   RetryPolicy
+	Timeout time.Duration
+
 	InputPorts  []NodePort
 	OutputPorts []NodePort
 
 	Action              NodeAction
 	Valid               bool
 	HandleSkippedInputs bool
+	HandleFailedInputs  bool // let the action see failed inputs (see GetInputError)
 
 	// Runtime state
 	mu      sync.Mutex
@@ -40,6 +53,11 @@ type Node struct {
 	resultAvailable bool
 	result          NodeActionResult
 
+	// The last successful result and the key it was computed under. If a
+	// rerun computes the same key, the action is not executed again.
+	cachedKey    ResultKey
+	cachedResult *NodeActionResult
+
 	InputPortPositions  []V2
 	OutputPortPositions []V2
 	DragRect            rl.Rectangle
@@ -61,21 +79,54 @@ func (n *Node) Serialize(s *Serializer) bool {
 	SV2(s, &n.Pos)
 	SStr(s, &n.Name)
 	SBool(s, &n.Pinned)
+	if s.Version >= 5 {
+		SBool(s, &n.NoCache)
+	}
+	if s.Version >= 6 {
+		SBool(s, &n.ErrorPort)
+	}
+	if s.Version >= 7 {
+		SThing(s, &n.Retry)
+		SInt(s, &n.Timeout)
+	}
 
 	SSlice(s, &n.InputPorts)
 	SSlice(s, &n.OutputPorts)
 
 	if s.Encode {
 		s.WriteStr(n.Action.Tag())
-		n.Action.Serialize(s)
 	} else {
 		tag, ok := s.ReadStr()
 		if !ok {
 			return false
 		}
-		meta := GetNodeActionMeta(tag)
-		n.Action = meta.Alloc()
-		n.Action.Serialize(s)
+		if meta, ok := LookupNodeActionMeta(tag); ok {
+			n.Action = meta.Alloc()
+		} else if s.Version >= 10 {
+			n.Action = &UnknownAction{OriginalTag: tag}
+		} else {
+			// Before version 10, there is no way to skip the settings.
+			return s.Error(fmt.Errorf("%s: %w", n, &UnknownActionError{Tag: tag, Version: s.Version}))
+		}
+	}
+
+	// Since version 11, the action's schema version is written with its tag.
+	// Older files have schema version 0 for every action.
+	var schema int
+	if s.Encode {
+		schema = ActionSchemaVersion(n.Action)
+	}
+	if s.Version >= 11 {
+		SInt(s, &schema)
+	}
+	if unknown, ok := n.Action.(*UnknownAction); ok && !s.Encode {
+		unknown.Schema = schema
+	}
+
+	if s.Version >= 10 {
+		serializeLengthPrefixedSettings(s, n.Action, schema)
+	} else {
+		serializeActionSettings(s, n.Action, schema)
 	}
 
 	// The remainder of the fields are dynamic and need not be serialized.
@@ -140,7 +191,6 @@ func (w *Wire) Type() FlowType {
 func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 	n.mu.Lock()
 	if n.Running {
-		fmt.Printf("Node %s is already running; joining existing run\n", n)
 		if n.done == nil {
 			// This should theoretically not happen if n.Running is true,
 			// but if it does, return a closed channel.
@@ -154,7 +204,6 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 		return done
 	}
 
-	fmt.Printf("Running node %s\n", n)
 	n.Running = true
 	n.resultAvailable = false
 	n.done = make(chan struct{})
@@ -163,11 +212,47 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 	doneCh := n.done
 	n.mu.Unlock()
 
+	queuedAt := time.Now()
+	var startedAt time.Time
+	Events.Publish(NodeEvent{Kind: NodeQueued, Node: n, Time: queuedAt})
+
+	// Records the final result of this run and announces it. The event's
+	// error may differ from the result's when it was sent to an error port.
+	finishWithErr := func(kind NodeEventKind, res NodeActionResult, err error, cached bool) {
+		n.mu.Lock()
+		n.result = res
+		n.resultAvailable = true
+		n.mu.Unlock()
+
+		ev := NodeEvent{Kind: kind, Node: n, Time: time.Now(), Err: err, Cached: cached}
+		if startedAt.IsZero() {
+			ev.Waiting = ev.Time.Sub(queuedAt)
+		} else {
+			ev.Waiting = startedAt.Sub(queuedAt)
+			ev.Running = ev.Time.Sub(startedAt)
+		}
+		Events.Publish(ev)
+	}
+	finish := func(kind NodeEventKind, res NodeActionResult, cached bool) {
+		finishWithErr(kind, res, res.Err, cached)
+	}
+	// Records a failure, routing it to the error port if the node has one.
+	// Cancellation is never routed.
+	failWithStack := func(err error, stack string) {
+		if ctx.Err() != nil {
+			finish(NodeCancelled, NodeActionResult{Err: err}, false)
+		} else if n.ErrorPort {
+			finishWithErr(NodeFailed, n.errorPortResult(err, stack), err, false)
+		} else {
+			finish(NodeFailed, NodeActionResult{Err: err}, false)
+		}
+	}
+	fail := func(err error) {
+		failWithStack(err, "")
+	}
+
 	go func() {
 		defer func() {
-			n.mu.Lock()
-			defer n.mu.Unlock()
-
 			if r := recover(); r != nil {
 				stack := debug.Stack()
 				var err error
@@ -176,9 +261,11 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 				} else {
 					err = fmt.Errorf("panic: %v\n%s", r, stack)
 				}
-				n.result = NodeActionResult{Err: err}
-				n.resultAvailable = true
+				failWithStack(err, string(stack))
 			}
+
+			n.mu.Lock()
+			defer n.mu.Unlock()
 			n.Running = false
 			if n.done != nil {
 				close(n.done)
@@ -187,10 +274,7 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 
 		// Check context before starting
 		if ctx.Err() != nil {
-			n.mu.Lock()
-			n.result = NodeActionResult{Err: ctx.Err()}
-			n.resultAvailable = true
-			n.mu.Unlock()
+			fail(ctx.Err())
 			return
 		}
 
@@ -200,7 +284,6 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 			rerunThisNode := rerunInputs && !inputNode.Pinned
 			// Use thread-safe check
 			if rerunThisNode || !inputNode.IsResultAvailable() {
-				fmt.Printf("Node %s wants node %s to run\n", n, inputNode)
 				inputRuns = append(inputRuns, inputNode.Run(ctx, rerunInputs))
 			}
 		}
@@ -208,29 +291,23 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 			select {
 			case <-inputRun:
 			case <-ctx.Done():
-				n.mu.Lock()
-				n.result = NodeActionResult{Err: ctx.Err()}
-				n.resultAvailable = true
-				n.mu.Unlock()
+				fail(ctx.Err())
 				return
 			}
 		}
 
 		// If any inputs have errors, stop.
 		for _, inputNode := range n.Inputs() {
+			if n.HandleFailedInputs {
+				break
+			}
 			res, ok := inputNode.GetResult()
 			if !ok {
-				n.mu.Lock()
-				n.result = NodeActionResult{Err: fmt.Errorf("input node %s produced no result", inputNode)}
-				n.resultAvailable = true
-				n.mu.Unlock()
+				fail(fmt.Errorf("input node %s produced no result", inputNode))
 				return
 			}
 			if res.Err != nil {
-				n.mu.Lock()
-				n.result = NodeActionResult{Err: fmt.Errorf("input node %s failed: %w", inputNode, res.Err)}
-				n.resultAvailable = true
-				n.mu.Unlock()
+				fail(fmt.Errorf("input node %s failed: %w", inputNode, res.Err))
 				return
 			}
 		}
@@ -244,58 +321,97 @@ func (n *Node) Run(ctx context.Context, rerunInputs bool) <-chan struct{} {
 			}
 		}
 
-		if anySkipped {
-			fmt.Printf("Node %s: skipping execution due to skipped input\n", n)
+		if anySkipped && !n.HandleSkippedInputs {
 			outputs := make([]FlowValue, len(n.OutputPorts))
 			for i := range outputs {
 				outputs[i] = FlowValue{Type: &n.OutputPorts[i].Type, Skipped: true}
 			}
+			finish(NodeSkipped, NodeActionResult{Outputs: outputs}, false)
+			return
+		}
 
+		// Reuse the previous result if neither settings nor inputs changed.
+		key, cacheable := n.ResultKey()
+		if cacheable {
 			n.mu.Lock()
-			n.result = NodeActionResult{Outputs: outputs}
-			n.resultAvailable = true
+			cached := n.cachedResult
+			if cached != nil && (n.cachedKey != key || len(cached.Outputs) != len(n.OutputPorts)) {
+				cached = nil
+			}
 			n.mu.Unlock()
-			return
+
+			if cached != nil {
+				finish(NodeFinished, *cached, true)
+				return
+			}
+
+			outputs, ok, err := LoadCachedResult(key)
+			if err != nil {
+				Events.Publish(NodeEvent{Kind: NodeCacheError, Node: n, Time: time.Now(), Err: err})
+			}
+			if ok && len(outputs) == len(n.OutputPorts) {
+				res := NodeActionResult{Outputs: outputs}
+				n.mu.Lock()
+				n.cachedKey = key
+				n.cachedResult = &res
+				n.mu.Unlock()
+				finish(NodeFinished, res, true)
+				return
+			}
 		}
 
-		fmt.Printf("Node %s: all inputs are done\n", n)
+		// Wait for a free slot before starting the action.
+		release, err := NodeScheduler.Acquire(ctx, schedCategory(n.Action))
+		if err != nil {
+			fail(err)
+			return
+		}
+		defer func() { release() }() // retries replace release
 
 		// Run action
-		var resCh <-chan NodeActionResult
-		if actionCtx, ok := n.Action.(NodeActionWithContext); ok {
-			resCh = actionCtx.RunContext(ctx, n)
-		} else {
-			resCh = n.Action.Run(n)
+		startedAt = time.Now()
+		Events.Publish(NodeEvent{Kind: NodeStarted, Node: n, Time: startedAt, Waiting: startedAt.Sub(queuedAt)})
+
+		res := n.runActionWithRetry(ctx, &release)
+		if res.Err != nil {
+			if n.ErrorPort {
+				fail(res.Err)
+			} else {
+				finish(util.Tern(ctx.Err() != nil, NodeCancelled, NodeFailed), res, false)
+			}
+			return
 		}
-
-		select {
-		case res := <-resCh:
-			if res.Err == nil && len(res.Outputs) != len(n.OutputPorts) {
-				n.mu.Lock()
-				n.result = NodeActionResult{Err: fmt.Errorf("bad num outputs for %s: got %d, expected %d", n, len(n.OutputPorts), len(res.Outputs))}
-				n.resultAvailable = true
-				n.mu.Unlock()
+		if len(res.Outputs) != len(n.ActionOutputPorts()) {
+			fail(fmt.Errorf("bad num outputs for %s: got %d, expected %d", n, len(res.Outputs), len(n.ActionOutputPorts())))
+			return
+		}
+		for i, output := range res.Outputs {
+			if err := Typecheck(*output.Type, n.OutputPorts[i].Type); err != nil {
+				fail(fmt.Errorf("bad value type for %s output port %d: %v", n, i, err))
 				return
 			}
-			for i, output := range res.Outputs {
-				if err := Typecheck(*output.Type, n.OutputPorts[i].Type); err != nil {
-					n.mu.Lock()
-					n.result = NodeActionResult{Err: fmt.Errorf("bad value type for %s output port %d: %v", n, i, err)}
-					n.resultAvailable = true
-					n.mu.Unlock()
-					return
-				}
+		}
+		if n.ErrorPort {
+			errPort := n.ErrorPortIndex()
+			res.Outputs = append(res.Outputs, FlowValue{Type: &n.OutputPorts[errPort].Type, Skipped: true})
+		}
+
+		storeResult := cacheable && resultIsCacheable(res)
+		n.mu.Lock()
+		if storeResult {
+			n.cachedKey = key
+			n.cachedResult = &res
+		} else {
+			n.cachedResult = nil
+		}
+		n.mu.Unlock()
+
+		if storeResult {
+			if err := StoreCachedResult(key, res.Outputs); err != nil {
+				Events.Publish(NodeEvent{Kind: NodeCacheError, Node: n, Time: time.Now(), Err: fmt.Errorf("failed to store result in cache directory: %w", err)})
 			}
-			n.mu.Lock()
-			n.result = res
-			n.resultAvailable = true
-			n.mu.Unlock()
-		case <-ctx.Done():
-			n.mu.Lock()
-			n.result = NodeActionResult{Err: ctx.Err()}
-			n.resultAvailable = true
-			n.mu.Unlock()
 		}
+		finish(NodeFinished, res, false)
 	}()
 
 	return doneCh
@@ -471,6 +587,16 @@ func PortAnchorID(n *Node, output bool, index int) clay.ElementID {
 }
 
 func Toposort(nodes []*Node, wires []*Wire) ([]*Node, error) {
+	sorted, cyclic := toposort(nodes, wires)
+	if len(cyclic) > 0 {
+		return nil, fmt.Errorf("cycle detected")
+	}
+	return sorted, nil
+}
+
+// Sorts nodes topologically, also returning the nodes that could not be
+// sorted because they are part of, or downstream of, a cycle.
+func toposort(nodes []*Node, wires []*Wire) (sorted, cyclic []*Node) {
 	nodeMap := make(map[int]*Node)
 	inDegree := make(map[int]int)
 	adj := make(map[int][]int)
@@ -515,9 +641,10 @@ func Toposort(nodes []*Node, wires []*Wire) ([]*Node, error) {
 		}
 	}
 
-	if len(result) != len(nodes) {
-		return nil, fmt.Errorf("cycle detected")
+	for _, n := range nodes {
+		if inDegree[n.ID] > 0 {
+			cyclic = append(cyclic, n)
+		}
 	}
-
-	return result, nil
+	return result, cyclic
 }
\ No newline at end of file
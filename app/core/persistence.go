@@ -1,12 +1,19 @@
 ﻿package core
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+// The version written by SerializeGraph and used when copying nodes, so that
+// copies keep every field.
+const SerializeVersion = 12
+
 func SerializeGraph(g *Graph) ([]byte, error) {
-	s := NewEncoder(4)
+	s := NewEncoder(SerializeVersion)
 
 	// Nodes
 	nodeCount := len(g.Nodes)
@@ -42,7 +49,17 @@ func SerializeGraph(g *Graph) ([]byte, error) {
 	return s.Bytes(), nil
 }
 
+// DeserializeGraph reads a graph in either the binary or the text format.
+//
+// If a binary file from before version 10 has a node of an unknown type, the
+// error wraps an *UnknownActionError, and the graph is not nil but has only
+// the nodes before that one. Wires, groups and variables come after the nodes,
+// so they are lost.
 func DeserializeGraph(data []byte) (*Graph, error) {
+	if IsTextFormat(data) {
+		return DeserializeGraphText(data)
+	}
+
 	s := NewDecoder(data)
 
 	// Read into temporary variables first to avoid destroying state on failure
@@ -57,12 +74,20 @@ func DeserializeGraph(data []byte) (*Graph, error) {
 	nodeMap := make(map[int]*Node)
 	maxID := 0
 
-	for range nodeCount {
+	for i := range nodeCount {
 		n := &Node{}
 		if !SThing(s, n) {
+			var unknown *UnknownActionError
+			if errors.As(errors.Join(s.Errs...), &unknown) {
+				g.NextNodeID = maxID
+				g.LoadProblems = append(g.LoadProblems, fmt.Sprintf("read only %d of %d nodes, and no wires, groups or variables", i, nodeCount))
+				return g, fmt.Errorf("failed to read node: %w", s.Errs[0])
+			}
 			return nil, fmt.Errorf("failed to read node: %v", s.Errs)
 		}
-		g.AddNode(n)
+		// Keep the saved IDs; the wires below refer to them.
+		n.Graph = g
+		g.Nodes = append(g.Nodes, n)
 		nodeMap[n.ID] = n
 		if n.ID > maxID {
 			maxID = n.ID
@@ -98,7 +123,7 @@ func DeserializeGraph(data []byte) (*Graph, error) {
 				EndPort:   endPort,
 			})
 		} else {
-			fmt.Printf("Warning: skipping wire with missing nodes (%d -> %d)\n", startNodeID, endNodeID)
+			g.LoadProblems = append(g.LoadProblems, fmt.Sprintf("skipped wire with missing nodes (%d -> %d)", startNodeID, endNodeID))
 		}
 	}
 
@@ -135,14 +160,27 @@ func DeserializeGraph(data []byte) (*Graph, error) {
 	return g, nil
 }
 
+// SaveGraph writes g in the text format if path ends in .json, and in the
+// binary format otherwise.
 func SaveGraph(path string, g *Graph) error {
-	data, err := SerializeGraph(g)
+	serialize := SerializeGraph
+	if IsTextFormatPath(path) {
+		serialize = SerializeGraphText
+	}
+	data, err := serialize(g)
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(path, data, 0644)
 }
 
+// IsTextFormatPath reports whether SaveGraph writes path in the text format.
+func IsTextFormatPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// LoadGraph reads the graph at path. See DeserializeGraph for the partial
+// graph returned for some old files.
 func LoadGraph(path string) (*Graph, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -151,6 +189,21 @@ func LoadGraph(path string) (*Graph, error) {
 	return DeserializeGraph(data)
 }
 
+// CloneGraph returns a deep copy of g, made by saving and loading it. The copy
+// shares no nodes or actions with g, so both can run at the same time.
+func CloneGraph(g *Graph) (*Graph, error) {
+	data, err := SerializeGraph(g)
+	if err != nil {
+		return nil, err
+	}
+	clone, err := DeserializeGraph(data)
+	if err != nil {
+		return nil, err
+	}
+	clone.Parent = g.Parent
+	return clone, nil
+}
+
 func MergeGraph(target *Graph, source *Graph) {
 	// Map old IDs to new IDs
 	idMap := make(map[int]int)
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Profiler records how long each node in a run spent waiting on its inputs
+// and executing its action, and exports the result as a Chrome trace, which
+// can be opened in chrome://tracing or https://ui.perfetto.dev.
+type Profiler struct {
+	mu          sync.Mutex
+	graph       *Graph
+	start       time.Time
+	events      []NodeEvent
+	unsubscribe func()
+}
+
+// StartProfiler begins recording the node events of g. If g is nil, events
+// from every graph are recorded.
+func StartProfiler(g *Graph) *Profiler {
+	p := &Profiler{graph: g, start: time.Now()}
+	p.unsubscribe = Events.Subscribe(func(ev NodeEvent) {
+		if !ev.Kind.Terminal() || (p.graph != nil && ev.Node.Graph != p.graph) {
+			return
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.events = append(p.events, ev)
+	})
+	return p
+}
+
+// Stop ends recording. It is safe to call more than once.
+func (p *Profiler) Stop() {
+	p.mu.Lock()
+	unsubscribe := p.unsubscribe
+	p.unsubscribe = nil
+	p.mu.Unlock()
+
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
+
+// The subset of the Chrome "Trace Event Format" that we emit.
+type chromeTrace struct {
+	TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+	DisplayTimeUnit string             `json:"displayTimeUnit"`
+}
+
+type chromeTraceEvent struct {
+	Name     string         `json:"name"`
+	Category string         `json:"cat,omitempty"`
+	Phase    string         `json:"ph"`
+	TS       int64          `json:"ts"` // microseconds
+	Dur      int64          `json:"dur,omitempty"`
+	PID      int            `json:"pid"`
+	TID      int            `json:"tid"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the recorded run in the Chrome trace event format.
+// Each node gets its own track, with a "wait" slice for the time spent on
+// inputs followed by a "run" slice for the action itself.
+func (p *Profiler) WriteChromeTrace(w io.Writer) error {
+	p.mu.Lock()
+	events := append([]NodeEvent(nil), p.events...)
+	p.mu.Unlock()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Node.ID < events[j].Node.ID
+	})
+
+	micros := func(t time.Time) int64 { return t.Sub(p.start).Microseconds() }
+
+	trace := chromeTrace{
+		TraceEvents:     []chromeTraceEvent{},
+		DisplayTimeUnit: "ms",
+	}
+	named := make(map[int]bool)
+	for _, ev := range events {
+		tid := ev.Node.ID
+		if !named[tid] {
+			named[tid] = true
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name:  "thread_name",
+				Phase: "M",
+				PID:   1,
+				TID:   tid,
+				Args:  map[string]any{"name": ev.Node.String()},
+			})
+		}
+
+		queuedAt := ev.Time.Add(-ev.Running - ev.Waiting)
+		startedAt := queuedAt.Add(ev.Waiting)
+
+		trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+			Name:     "wait: " + ev.Node.Name,
+			Category: "wait",
+			Phase:    "X",
+			TS:       micros(queuedAt),
+			Dur:      ev.Waiting.Microseconds(),
+			PID:      1,
+			TID:      tid,
+		})
+
+		args := map[string]any{"result": ev.Kind.String()}
+		if ev.Err != nil {
+			args["error"] = ev.Err.Error()
+		}
+		if ev.Cached {
+			args["cached"] = true
+		}
+		if ev.Running > 0 || ev.Kind == NodeFinished {
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name:     ev.Node.Name,
+				Category: "run",
+				Phase:    "X",
+				TS:       micros(startedAt),
+				Dur:      ev.Running.Microseconds(),
+				PID:      1,
+				TID:      tid,
+				Args:     args,
+			})
+		} else {
+			// The action never ran; mark where the node ended up.
+			trace.TraceEvents = append(trace.TraceEvents, chromeTraceEvent{
+				Name:     ev.Kind.String() + ": " + ev.Node.Name,
+				Category: "run",
+				Phase:    "i",
+				TS:       micros(ev.Time),
+				PID:      1,
+				TID:      tid,
+				Args:     args,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(trace)
+}
+
+// SaveChromeTrace writes the recorded run to a file. See WriteChromeTrace.
+func (p *Profiler) SaveChromeTrace(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.WriteChromeTrace(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -13,13 +13,11 @@ func RegisterNodeAction(tag string, alloc func() NodeAction) {
 	registry[tag] = NodeActionMeta{Tag: tag, Alloc: alloc}
 }
 
-// GetNodeActionMeta retrieves the metadata for a node action tag.
-// It replaces the previous hardcoded lookup.
-func GetNodeActionMeta(tag string) NodeActionMeta {
+// LookupNodeActionMeta retrieves the metadata for a node action tag, if the
+// tag is known.
+func LookupNodeActionMeta(tag string) (NodeActionMeta, bool) {
 	registryMu.RLock()
 	defer registryMu.RUnlock()
-	if meta, ok := registry[tag]; ok {
-		return meta
-	}
-	panic("unknown node action type: " + tag)
+	meta, ok := registry[tag]
+	return meta, ok
 }
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Node.Run re-executes a failing action. The zero
+// value never retries.
+type RetryPolicy struct {
+	// Total number of attempts, including the first. Zero or one means the
+	// action is never retried.
+	MaxAttempts int
+	// Delay before the first retry. It doubles after every retry.
+	Backoff time.Duration
+	// If set, only errors whose message matches this regexp are retried.
+	// Otherwise every error is.
+	ErrorPattern string
+	// Comma-separated result codes that also trigger a retry even though the
+	// action succeeded, e.g. "1,2" for exit codes or "502,503" for HTTP
+	// statuses. Only used for actions that implement NodeActionWithResultCode.
+	RetryCodes string
+}
+
+// NodeActionWithResultCode can be implemented by actions whose successful
+// results still carry a status, like a process exit code or an HTTP status,
+// so that RetryPolicy.RetryCodes can apply to them.
+type NodeActionWithResultCode interface {
+	ResultCode(res NodeActionResult) (code int, ok bool)
+}
+
+func (p *RetryPolicy) Serialize(s *Serializer) bool {
+	SInt(s, &p.MaxAttempts)
+	SInt(s, &p.Backoff)
+	SStr(s, &p.ErrorPattern)
+	SStr(s, &p.RetryCodes)
+	return s.Ok()
+}
+
+// Validate reports whether ErrorPattern and RetryCodes can be parsed.
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("max attempts cannot be negative")
+	}
+	if p.Backoff < 0 {
+		return fmt.Errorf("backoff cannot be negative")
+	}
+	if _, err := regexp.Compile(p.ErrorPattern); err != nil {
+		return fmt.Errorf("bad error pattern: %w", err)
+	}
+	if _, err := ParseRetryCodes(p.RetryCodes); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParseRetryCodes parses a comma-separated list of integer codes.
+func ParseRetryCodes(str string) ([]int, error) {
+	var codes []int
+	for _, field := range strings.Split(str, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		code, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("bad retry code %q", field)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (p *RetryPolicy) shouldRetry(action NodeAction, res NodeActionResult) bool {
+	if res.Err != nil {
+		if p.ErrorPattern == "" {
+			return true
+		}
+		re, err := regexp.Compile(p.ErrorPattern)
+		return err == nil && re.MatchString(res.Err.Error())
+	}
+
+	codes, err := ParseRetryCodes(p.RetryCodes)
+	if err != nil || len(codes) == 0 {
+		return false
+	}
+	withCode, ok := action.(NodeActionWithResultCode)
+	if !ok {
+		return false
+	}
+	code, ok := withCode.ResultCode(res)
+	return ok && slices.Contains(codes, code)
+}
+
+// Runs the node's action, retrying according to n.Retry. The last attempt's
+// result is returned as-is.
+//
+// The caller holds a scheduler slot, which *release gives back. The slot is
+// given back during each backoff, so that a waiting retry does not hold up
+// other nodes, and taken again before the next attempt; *release is updated
+// to match.
+func (n *Node) runActionWithRetry(ctx context.Context, release *func()) NodeActionResult {
+	delay := n.Retry.Backoff
+	for attempt := 1; ; attempt++ {
+		res := n.runActionOnce(ctx)
+		if ctx.Err() != nil || attempt >= n.Retry.MaxAttempts || !n.Retry.shouldRetry(n.Action, res) {
+			return res
+		}
+
+		Events.Publish(NodeEvent{Kind: NodeRetrying, Node: n, Time: time.Now(), Err: res.Err, Attempt: attempt})
+		(*release)()
+		*release = func() {}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return NodeActionResult{Err: ctx.Err()}
+		}
+		delay *= 2
+
+		r, err := NodeScheduler.Acquire(ctx, schedCategory(n.Action))
+		if err != nil {
+			return NodeActionResult{Err: err}
+		}
+		*release = r
+	}
+}
+
+// Runs the node's action once, bounded by n.Timeout.
+func (n *Node) runActionOnce(ctx context.Context) (res NodeActionResult) {
+	actionCtx := ctx
+	if n.Timeout > 0 {
+		var cancel context.CancelFunc
+		actionCtx, cancel = context.WithTimeout(ctx, n.Timeout)
+		defer func() {
+			// Streams may still depend on the context (e.g. the output of a
+			// running process), so the timeout keeps bounding them instead
+			// of cutting them off right away.
+			if !slices.ContainsFunc(res.Outputs, ContainsStream) {
+				cancel()
+			}
+		}()
+	}
+
+	timedOut := func() bool {
+		return ctx.Err() == nil && errors.Is(actionCtx.Err(), context.DeadlineExceeded)
+	}
+
+	var resCh <-chan NodeActionResult
+	if withCtx, ok := n.Action.(NodeActionWithContext); ok {
+		resCh = withCtx.RunContext(actionCtx, n)
+	} else {
+		resCh = n.Action.Run(n)
+	}
+
+	select {
+	case res = <-resCh:
+		if res.Err != nil && timedOut() {
+			res.Err = fmt.Errorf("timed out after %v: %w", n.Timeout, res.Err)
+		}
+		return res
+	case <-actionCtx.Done():
+		if timedOut() {
+			return NodeActionResult{Err: fmt.Errorf("timed out after %v: %w", n.Timeout, actionCtx.Err())}
+		}
+		return NodeActionResult{Err: ctx.Err()}
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+)
+
+// Scheduler categories for actions that use scarce resources.
+const (
+	SchedCategoryHTTP    = "http"
+	SchedCategoryProcess = "process"
+
+	// Actions that only coordinate other nodes, like Map running a subflow,
+	// never occupy a slot. Otherwise the nested nodes could wait forever for
+	// a slot held by their parent.
+	SchedCategoryCoordinator = "coordinator"
+)
+
+// NodeActionWithSchedCategory can be implemented by actions that should be
+// subject to a per-category concurrency limit.
+type NodeActionWithSchedCategory interface {
+	SchedCategory() string
+}
+
+// A Scheduler bounds how many node actions execute at once, both overall and
+// per category. A node only takes a slot once its inputs are ready, so nodes
+// waiting on upstream work never block others.
+type Scheduler struct {
+	global     chan struct{}
+	categories map[string]chan struct{}
+}
+
+// NewScheduler creates a scheduler allowing at most maxParallel actions to run
+// at once, and at most limits[category] actions of each category. Zero or
+// negative limits mean unlimited.
+func NewScheduler(maxParallel int, limits map[string]int) *Scheduler {
+	s := &Scheduler{categories: make(map[string]chan struct{})}
+	if maxParallel > 0 {
+		s.global = make(chan struct{}, maxParallel)
+	}
+	for category, limit := range limits {
+		if limit > 0 {
+			s.categories[category] = make(chan struct{}, limit)
+		}
+	}
+	return s
+}
+
+// NodeScheduler is used by Node.Run for every action. It is unlimited by
+// default; the app and headless runner configure it from settings and flags.
+var NodeScheduler = NewScheduler(0, nil)
+
+// Acquire blocks until an action of the given category may run, or ctx is
+// done. The returned function must be called when the action completes.
+func (s *Scheduler) Acquire(ctx context.Context, category string) (release func(), err error) {
+	if category == SchedCategoryCoordinator {
+		return func() {}, nil
+	}
+
+	// Always take the category slot before the global one, so that every
+	// caller acquires in the same order.
+	var held []chan struct{}
+	releaseAll := func() {
+		for _, sem := range held {
+			<-sem
+		}
+	}
+	for _, sem := range []chan struct{}{s.categories[category], s.global} {
+		if sem == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+		case <-ctx.Done():
+			releaseAll()
+			return nil, ctx.Err()
+		}
+	}
+	return releaseAll, nil
+}
+
+func schedCategory(action NodeAction) string {
+	if c, ok := action.(NodeActionWithSchedCategory); ok {
+		return c.SchedCategory()
+	}
+	return ""
+}
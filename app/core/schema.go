@@ -0,0 +1,90 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// NodeActionWithSchemaVersion can be implemented by actions whose settings
+// have changed layout since they were first saved. The version is saved next
+// to the action's tag, and settings saved with an older version are read by
+// the upgrade registered for that version (see RegisterNodeActionUpgrade).
+// Actions that do not implement it have schema version 0.
+type NodeActionWithSchemaVersion interface {
+	SchemaVersion() int
+}
+
+// ActionSchemaVersion returns the schema version that action's Serialize
+// reads and writes.
+func ActionSchemaVersion(action NodeAction) int {
+	if v, ok := action.(NodeActionWithSchemaVersion); ok {
+		return v.SchemaVersion()
+	}
+	return 0
+}
+
+// A NodeActionUpgrade reads settings saved with an older schema version into
+// a freshly allocated action. Each upgrade reads one old layout directly into
+// the current action, so upgrades never need to be chained. s.Version is the
+// version of the file being read.
+type NodeActionUpgrade func(s *Serializer, action NodeAction) bool
+
+var upgrades = make(map[string]map[int]NodeActionUpgrade)
+
+// RegisterNodeActionUpgrade registers the upgrade that reads settings of the
+// action with the given tag that were saved with schema version fromVersion.
+func RegisterNodeActionUpgrade(tag string, fromVersion int, upgrade NodeActionUpgrade) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if upgrades[tag] == nil {
+		upgrades[tag] = make(map[int]NodeActionUpgrade)
+	}
+	upgrades[tag][fromVersion] = upgrade
+}
+
+func lookupNodeActionUpgrade(tag string, fromVersion int) (NodeActionUpgrade, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	upgrade, ok := upgrades[tag][fromVersion]
+	return upgrade, ok
+}
+
+// serializeActionSettings reads or writes the settings of action. Settings
+// are always written with the current schema version; when reading, schema is
+// the version they were saved with.
+func serializeActionSettings(s *Serializer, action NodeAction, schema int) bool {
+	current := ActionSchemaVersion(action)
+	if s.Encode || schema == current {
+		return action.Serialize(s)
+	}
+	if schema > current {
+		return s.Error(fmt.Errorf("%s settings have schema version %d, but this version of flowshell only reads up to version %d", action.Tag(), schema, current))
+	}
+	upgrade, ok := lookupNodeActionUpgrade(action.Tag(), schema)
+	if !ok {
+		return s.Error(fmt.Errorf("%s settings have schema version %d, which can no longer be read", action.Tag(), schema))
+	}
+	return upgrade(s, action)
+}
+
+// Since version 10, action settings are written with a length prefix, so
+// that nodes of unknown types can be loaded and saved without knowing how to
+// read their settings.
+func serializeLengthPrefixedSettings(s *Serializer, action NodeAction, schema int) bool {
+	settings := &Serializer{Buf: &bytes.Buffer{}, Encode: s.Encode, Version: s.Version}
+	if s.Encode {
+		serializeActionSettings(settings, action, schema)
+		s.Errs = append(s.Errs, settings.Errs...)
+		raw := settings.Buf.String()
+		return SStr(s, &raw)
+	}
+
+	var raw string
+	if !SStr(s, &raw) {
+		return false
+	}
+	settings.Buf.WriteString(raw)
+	serializeActionSettings(settings, action, schema)
+	s.Errs = append(s.Errs, settings.Errs...)
+	return s.Ok()
+}
@@ -0,0 +1,298 @@
+package core
+
+// A Table holds the rows of a table value by column: one vector of cells per
+// column, typed by the column's field, so that a cell of a large table costs
+// little more than its data. A FlowValue holds one in Table, in place of
+// TableValue; code that wants rows can get them from FlowValue.RowFields and
+// FlowValue.TableRows either way.
+type Table struct {
+	Fields  []FlowField
+	Columns []*TableColumn
+
+	n int
+}
+
+// A TableColumn keeps the cells of a Bytes, Int64, Float64 or Bool field in
+// a vector of that kind, and the cells of other fields, or cells that do not
+// fit the field's type, as FlowValues.
+type TableColumn struct {
+	cellType *FlowType    // the field's type, not nullable
+	nullType *FlowType    // the field's type, nullable
+	storage  FlowTypeKind // the kind of vector that holds the cells, or Any for values
+	n        int
+
+	nulls    []bool // nil until the first null
+	int64s   []int64
+	float64s []float64
+	bools    []bool
+	bytes    []byte
+	ends     []int // where each cell of bytes ends
+	values   []FlowValue
+}
+
+func NewTable(fields []FlowField) *Table {
+	t := &Table{Fields: fields}
+	for _, f := range fields {
+		t.Columns = append(t.Columns, newTableColumn(*f.Type))
+	}
+	return t
+}
+
+func newTableColumn(t FlowType) *TableColumn {
+	cellType := t
+	cellType.Nullable = false
+	nullType := NullableType(t)
+	c := &TableColumn{cellType: &cellType, nullType: &nullType, storage: FSKindAny}
+	switch t.Kind {
+	case FSKindBytes, FSKindInt64, FSKindFloat64, FSKindBool:
+		c.storage = t.Kind
+	}
+	return c
+}
+
+// Len returns the number of rows.
+func (t *Table) Len() int {
+	return t.n
+}
+
+// AppendRow adds a row whose fields are those of the table, preferably in
+// the same order.
+func (t *Table) AppendRow(row []FlowValueField) {
+	for i, c := range t.Columns {
+		var val FlowValue
+		if i < len(row) && row[i].Name == t.Fields[i].Name {
+			val = row[i].Value
+		} else {
+			val, _ = RowField(row, t.Fields[i].Name)
+		}
+		c.Append(val)
+	}
+	t.n++
+}
+
+// EndRow finishes a row whose cells were appended to each column directly.
+func (t *Table) EndRow() {
+	t.n++
+}
+
+// Row returns row i with a field per column.
+func (t *Table) Row(i int) []FlowValueField {
+	row := make([]FlowValueField, len(t.Columns))
+	for j, c := range t.Columns {
+		row[j] = FlowValueField{Name: t.Fields[j].Name, Value: c.Value(i)}
+	}
+	return row
+}
+
+// Rows returns every row, as a table value's TableValue would hold them.
+func (t *Table) Rows() [][]FlowValueField {
+	rows := make([][]FlowValueField, t.n)
+	for i := range rows {
+		rows[i] = t.Row(i)
+	}
+	return rows
+}
+
+// Select returns a table of the given columns of t, which shares their cells.
+func (t *Table) Select(cols []int) *Table {
+	res := &Table{n: t.n}
+	for _, col := range cols {
+		res.Fields = append(res.Fields, t.Fields[col])
+		res.Columns = append(res.Columns, t.Columns[col])
+	}
+	return res
+}
+
+// Take returns a table of the given rows of t, in the given order.
+func (t *Table) Take(rows []int) *Table {
+	res := &Table{Fields: t.Fields, n: len(rows)}
+	for _, c := range t.Columns {
+		res.Columns = append(res.Columns, c.take(rows))
+	}
+	return res
+}
+
+func (c *TableColumn) Len() int {
+	return c.n
+}
+
+func (c *TableColumn) IsNull(i int) bool {
+	return c.nulls != nil && c.nulls[i]
+}
+
+// Nulls returns which cells are null, or nil if none are.
+func (c *TableColumn) Nulls() []bool {
+	return c.nulls
+}
+
+// Int64s returns the vector of an Int64 column. Null cells hold zero.
+func (c *TableColumn) Int64s() ([]int64, bool) {
+	return c.int64s, c.storage == FSKindInt64
+}
+
+// Float64s returns the vector of a Float64 column. Null cells hold zero.
+func (c *TableColumn) Float64s() ([]float64, bool) {
+	return c.float64s, c.storage == FSKindFloat64
+}
+
+// Value returns cell i.
+func (c *TableColumn) Value(i int) FlowValue {
+	if c.IsNull(i) {
+		return FlowValue{Type: c.nullType, Null: true}
+	}
+	switch c.storage {
+	case FSKindInt64:
+		return FlowValue{Type: c.cellType, Int64Value: c.int64s[i]}
+	case FSKindFloat64:
+		return FlowValue{Type: c.cellType, Float64Value: c.float64s[i]}
+	case FSKindBool:
+		return FlowValue{Type: c.cellType, BoolValue: c.bools[i]}
+	case FSKindBytes:
+		start, end := c.bytesRange(i)
+		return FlowValue{Type: c.cellType, BytesValue: c.bytes[start:end:end]}
+	default:
+		return c.values[i]
+	}
+}
+
+func (c *TableColumn) bytesRange(i int) (int, int) {
+	start := 0
+	if i > 0 {
+		start = c.ends[i-1]
+	}
+	return start, c.ends[i]
+}
+
+// Append adds a cell. Cells without a type are null.
+func (c *TableColumn) Append(v FlowValue) {
+	if v.Null || v.Type == nil {
+		c.AppendNull()
+		return
+	}
+	if c.storage != FSKindAny && !c.fits(*v.Type) {
+		c.toValues()
+	}
+	switch c.storage {
+	case FSKindInt64:
+		c.AppendInt64(v.Int64Value)
+	case FSKindFloat64:
+		c.AppendFloat64(v.Float64Value)
+	case FSKindBool:
+		c.AppendBool(v.BoolValue)
+	case FSKindBytes:
+		c.bytes = append(c.bytes, v.BytesValue...)
+		c.ends = append(c.ends, len(c.bytes))
+		c.added(false)
+	default:
+		c.values = append(c.values, v)
+		c.added(false)
+	}
+}
+
+func (c *TableColumn) AppendNull() {
+	switch c.storage {
+	case FSKindInt64:
+		c.int64s = append(c.int64s, 0)
+	case FSKindFloat64:
+		c.float64s = append(c.float64s, 0)
+	case FSKindBool:
+		c.bools = append(c.bools, false)
+	case FSKindBytes:
+		c.ends = append(c.ends, len(c.bytes))
+	default:
+		c.values = append(c.values, FlowValue{})
+	}
+	c.added(true)
+}
+
+// The typed appends must only be used on a column of that kind.
+
+func (c *TableColumn) AppendInt64(v int64) {
+	c.int64s = append(c.int64s, v)
+	c.added(false)
+}
+
+func (c *TableColumn) AppendFloat64(v float64) {
+	c.float64s = append(c.float64s, v)
+	c.added(false)
+}
+
+func (c *TableColumn) AppendBool(v bool) {
+	c.bools = append(c.bools, v)
+	c.added(false)
+}
+
+func (c *TableColumn) AppendString(v string) {
+	c.bytes = append(c.bytes, v...)
+	c.ends = append(c.ends, len(c.bytes))
+	c.added(false)
+}
+
+func (c *TableColumn) added(null bool) {
+	if null && c.nulls == nil {
+		c.nulls = make([]bool, c.n, c.n+1)
+	}
+	if c.nulls != nil {
+		c.nulls = append(c.nulls, null)
+	}
+	c.n++
+}
+
+// fits reports whether a cell of type t can go in the column's vector.
+func (c *TableColumn) fits(t FlowType) bool {
+	return t.Kind == c.storage && t.Unit == c.cellType.Unit && t.WellKnownType == c.cellType.WellKnownType
+}
+
+// toValues moves the cells out of the column's vector, into values.
+func (c *TableColumn) toValues() {
+	values := make([]FlowValue, c.n)
+	for i := range values {
+		if !c.IsNull(i) {
+			values[i] = c.Value(i)
+		}
+	}
+	*c = TableColumn{
+		cellType: c.cellType,
+		nullType: c.nullType,
+		storage:  FSKindAny,
+		n:        c.n,
+		nulls:    c.nulls,
+		values:   values,
+	}
+}
+
+func (c *TableColumn) take(rows []int) *TableColumn {
+	res := &TableColumn{cellType: c.cellType, nullType: c.nullType, storage: c.storage, n: len(rows)}
+	if c.nulls != nil {
+		res.nulls = make([]bool, len(rows))
+		for i, row := range rows {
+			res.nulls[i] = c.nulls[row]
+		}
+	}
+	switch c.storage {
+	case FSKindInt64:
+		res.int64s = takeRows(c.int64s, rows)
+	case FSKindFloat64:
+		res.float64s = takeRows(c.float64s, rows)
+	case FSKindBool:
+		res.bools = takeRows(c.bools, rows)
+	case FSKindBytes:
+		res.ends = make([]int, len(rows))
+		for i, row := range rows {
+			start, end := c.bytesRange(row)
+			res.bytes = append(res.bytes, c.bytes[start:end]...)
+			res.ends[i] = len(res.bytes)
+		}
+	default:
+		res.values = takeRows(c.values, rows)
+	}
+	return res
+}
+
+func takeRows[T any](vals []T, rows []int) []T {
+	res := make([]T, len(rows))
+	for i, row := range rows {
+		res[i] = vals[row]
+	}
+	return res
+}
@@ -0,0 +1,517 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The text format is an indented JSON encoding of a graph, meant to be read by
+// people and diffed in version control. It holds exactly what the binary
+// format holds, so files can be converted back and forth without loss.
+//
+// Node settings are written as the JSON of the node's action whenever that
+// JSON reads back to the same settings. Otherwise they are written as
+// "settings_binary", a base64 blob in the binary format.
+
+const textFormatName = "flowshell"
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+type graphText struct {
+	Format    string            `json:"format"`
+	Version   int               `json:"version"`
+	Nodes     []nodeText        `json:"nodes"`
+	Wires     []wireText        `json:"wires"`
+	Groups    []groupText       `json:"groups,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+type nodeText struct {
+	ID        int             `json:"id"`
+	Name      string          `json:"name"`
+	Pos       [2]float32      `json:"pos"`
+	Pinned    bool            `json:"pinned,omitempty"`
+	NoCache   bool            `json:"no_cache,omitempty"`
+	ErrorPort bool            `json:"error_port,omitempty"`
+	Retry     *retryText      `json:"retry,omitempty"`
+	Timeout   string          `json:"timeout,omitempty"`
+	Inputs    []portText      `json:"inputs,omitempty"`
+	Outputs   []portText      `json:"outputs,omitempty"`
+	Action    string          `json:"action"`
+	Schema    int             `json:"schema,omitempty"`
+	Settings  json.RawMessage `json:"settings,omitempty"`
+	// Settings in the binary format, with a leading version, for actions
+	// whose settings do not survive a trip through JSON.
+	SettingsBinary []byte `json:"settings_binary,omitempty"`
+}
+
+type retryText struct {
+	MaxAttempts  int    `json:"max_attempts,omitempty"`
+	Backoff      string `json:"backoff,omitempty"`
+	ErrorPattern string `json:"error_pattern,omitempty"`
+	RetryCodes   string `json:"retry_codes,omitempty"`
+}
+
+type portText struct {
+	Name string   `json:"name"`
+	Type FlowType `json:"type"`
+}
+
+// Wire ends are [node ID, port].
+type wireText struct {
+	From [2]int `json:"from"`
+	To   [2]int `json:"to"`
+}
+
+type groupText struct {
+	ID    int        `json:"id"`
+	Title string     `json:"title"`
+	Rect  [4]float32 `json:"rect"`  // x, y, width, height
+	Color [4]float32 `json:"color"` // r, g, b, a
+}
+
+// IsTextFormat reports whether data looks like a graph in the text format
+// rather than the binary format.
+func IsTextFormat(data []byte) bool {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '{'
+}
+
+func SerializeGraphText(g *Graph) ([]byte, error) {
+	gt := graphText{
+		Format:    textFormatName,
+		Version:   SerializeVersion,
+		Nodes:     []nodeText{},
+		Wires:     []wireText{},
+		Variables: g.Variables,
+	}
+
+	for _, n := range g.Nodes {
+		nt := nodeText{
+			ID:        n.ID,
+			Name:      n.Name,
+			Pos:       [2]float32{n.Pos.X, n.Pos.Y},
+			Pinned:    n.Pinned,
+			NoCache:   n.NoCache,
+			ErrorPort: n.ErrorPort,
+			Inputs:    portsToText(n.InputPorts),
+			Outputs:   portsToText(n.OutputPorts),
+			Action:    n.Action.Tag(),
+			Schema:    ActionSchemaVersion(n.Action),
+		}
+		if n.Retry != (RetryPolicy{}) {
+			nt.Retry = &retryText{
+				MaxAttempts:  n.Retry.MaxAttempts,
+				ErrorPattern: n.Retry.ErrorPattern,
+				RetryCodes:   n.Retry.RetryCodes,
+			}
+			if n.Retry.Backoff != 0 {
+				nt.Retry.Backoff = n.Retry.Backoff.String()
+			}
+		}
+		if n.Timeout != 0 {
+			nt.Timeout = n.Timeout.String()
+		}
+
+		var err error
+		nt.Settings, nt.SettingsBinary, err = actionSettingsToText(n.Action)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", n, err)
+		}
+		gt.Nodes = append(gt.Nodes, nt)
+	}
+
+	for _, w := range g.Wires {
+		gt.Wires = append(gt.Wires, wireText{
+			From: [2]int{w.StartNode.ID, w.StartPort},
+			To:   [2]int{w.EndNode.ID, w.EndPort},
+		})
+	}
+
+	for _, grp := range g.Groups {
+		gt.Groups = append(gt.Groups, groupText{
+			ID:    grp.ID,
+			Title: grp.Title,
+			Rect:  [4]float32{grp.Rect.X, grp.Rect.Y, grp.Rect.Width, grp.Rect.Height},
+			Color: [4]float32{grp.Color.R, grp.Color.G, grp.Color.B, grp.Color.A},
+		})
+	}
+
+	data, err := json.MarshalIndent(gt, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func DeserializeGraphText(data []byte) (*Graph, error) {
+	var gt graphText
+	if err := json.Unmarshal(bytes.TrimPrefix(data, utf8BOM), &gt); err != nil {
+		return nil, fmt.Errorf("failed to parse graph: %v", err)
+	}
+	if gt.Format != textFormatName {
+		return nil, fmt.Errorf("not a flowshell graph (format is %q)", gt.Format)
+	}
+
+	g := NewGraph()
+
+	nodeMap := make(map[int]*Node)
+	for _, nt := range gt.Nodes {
+		n := &Node{
+			ID:          nt.ID,
+			Name:        nt.Name,
+			Pos:         V2{X: nt.Pos[0], Y: nt.Pos[1]},
+			Pinned:      nt.Pinned,
+			NoCache:     nt.NoCache,
+			ErrorPort:   nt.ErrorPort,
+			InputPorts:  portsFromText(nt.Inputs),
+			OutputPorts: portsFromText(nt.Outputs),
+			Graph:       g,
+		}
+		if nt.Retry != nil {
+			n.Retry = RetryPolicy{
+				MaxAttempts:  nt.Retry.MaxAttempts,
+				ErrorPattern: nt.Retry.ErrorPattern,
+				RetryCodes:   nt.Retry.RetryCodes,
+			}
+			if nt.Retry.Backoff != "" {
+				backoff, err := time.ParseDuration(nt.Retry.Backoff)
+				if err != nil {
+					return nil, fmt.Errorf("%s has a bad retry backoff: %v", n, err)
+				}
+				n.Retry.Backoff = backoff
+			}
+		}
+		if nt.Timeout != "" {
+			timeout, err := time.ParseDuration(nt.Timeout)
+			if err != nil {
+				return nil, fmt.Errorf("%s has a bad timeout: %v", n, err)
+			}
+			n.Timeout = timeout
+		}
+
+		if meta, ok := LookupNodeActionMeta(nt.Action); ok {
+			n.Action = meta.Alloc()
+		} else {
+			n.Action = &UnknownAction{OriginalTag: nt.Action, Schema: nt.Schema}
+		}
+		if err := actionSettingsFromText(n.Action, nt.Schema, nt.Settings, nt.SettingsBinary); err != nil {
+			return nil, fmt.Errorf("%s has bad settings: %v", n, err)
+		}
+
+		g.Nodes = append(g.Nodes, n)
+		nodeMap[n.ID] = n
+		g.NextNodeID = max(g.NextNodeID, n.ID)
+	}
+
+	for _, wt := range gt.Wires {
+		startNode, ok1 := nodeMap[wt.From[0]]
+		endNode, ok2 := nodeMap[wt.To[0]]
+		if ok1 && ok2 {
+			g.Wires = append(g.Wires, &Wire{
+				StartNode: startNode,
+				StartPort: wt.From[1],
+				EndNode:   endNode,
+				EndPort:   wt.To[1],
+			})
+		} else {
+			g.LoadProblems = append(g.LoadProblems, fmt.Sprintf("skipped wire with missing nodes (%d -> %d)", wt.From[0], wt.To[0]))
+		}
+	}
+
+	for _, grpText := range gt.Groups {
+		grp := &Group{ID: grpText.ID, Title: grpText.Title}
+		grp.Rect.X, grp.Rect.Y, grp.Rect.Width, grp.Rect.Height = grpText.Rect[0], grpText.Rect[1], grpText.Rect[2], grpText.Rect[3]
+		grp.Color.R, grp.Color.G, grp.Color.B, grp.Color.A = grpText.Color[0], grpText.Color[1], grpText.Color[2], grpText.Color[3]
+		g.Groups = append(g.Groups, grp)
+		g.NextGroupID = max(g.NextGroupID, grp.ID)
+	}
+
+	for k, v := range gt.Variables {
+		g.Variables[k] = v
+	}
+
+	return g, nil
+}
+
+func portsToText(ports []NodePort) []portText {
+	var res []portText
+	for _, p := range ports {
+		res = append(res, portText{Name: p.Name, Type: p.Type})
+	}
+	return res
+}
+
+func portsFromText(ports []portText) []NodePort {
+	var res []NodePort
+	for _, p := range ports {
+		res = append(res, NodePort{Name: p.Name, Type: p.Type})
+	}
+	return res
+}
+
+// actionSettingsToText returns either JSON settings or binary settings for
+// the action. The JSON is built from a fresh action decoded from the binary
+// settings, so that state which is not saved does not leak into the file, and
+// is only used if it decodes back to identical binary settings.
+func actionSettingsToText(action NodeAction) (json.RawMessage, []byte, error) {
+	if unknown, ok := action.(*UnknownAction); ok {
+		if unknown.Settings == nil {
+			return unknown.SettingsJSON, nil, nil
+		}
+		enc := NewEncoder(unknown.Version)
+		enc.Buf.Write(unknown.Settings)
+		return nil, enc.Bytes(), nil
+	}
+
+	enc := NewEncoder(SerializeVersion)
+	action.Serialize(enc)
+	if !enc.Ok() {
+		return nil, nil, fmt.Errorf("failed to serialize settings: %v", enc.Errs)
+	}
+	settings := enc.Bytes()
+
+	meta, ok := LookupNodeActionMeta(action.Tag())
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown action type %q", action.Tag())
+	}
+	raw, ok := settingsJSON(meta, settings)
+	if !ok {
+		return nil, settings, nil
+	}
+	if string(raw) == "{}" {
+		raw = nil
+	}
+	return raw, nil, nil
+}
+
+func settingsJSON(meta NodeActionMeta, settings []byte) (raw json.RawMessage, ok bool) {
+	// Actions are not written with arbitrary JSON in mind, so a panic just
+	// means the binary settings must be used.
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+
+	fresh := meta.Alloc()
+	if dec := NewDecoder(settings); !fresh.Serialize(dec) {
+		return nil, false
+	}
+	raw, err := json.Marshal(fresh)
+	if err != nil {
+		return nil, false
+	}
+	raw, err = pruneZeroJSON(raw)
+	if err != nil {
+		return nil, false
+	}
+
+	check := meta.Alloc()
+	if err := json.Unmarshal(raw, check); err != nil {
+		return nil, false
+	}
+	checkEnc := NewEncoder(SerializeVersion)
+	if !check.Serialize(checkEnc) || !bytes.Equal(checkEnc.Bytes(), settings) {
+		return nil, false
+	}
+	return raw, true
+}
+
+// JSON settings are matched to fields by name, so they are read the same way
+// whatever their schema version.
+func actionSettingsFromText(action NodeAction, schema int, settings json.RawMessage, binary []byte) error {
+	if unknown, ok := action.(*UnknownAction); ok && binary == nil {
+		unknown.SettingsJSON = settings
+		return nil
+	}
+	if binary != nil {
+		dec := NewDecoder(binary)
+		if !serializeActionSettings(dec, action, schema) {
+			return fmt.Errorf("%v", dec.Errs)
+		}
+		return nil
+	}
+	if settings != nil {
+		return json.Unmarshal(settings, action)
+	}
+	return nil
+}
+
+// pruneZeroJSON removes object keys whose values are null, false, zero, or
+// empty, since decoding into a fresh action leaves those fields zero anyway.
+func pruneZeroJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // keep large integers exact
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(pruneZero(v))
+}
+
+func pruneZero(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			field = pruneZero(field)
+			if isZeroJSON(field) {
+				delete(v, k)
+			} else {
+				v[k] = field
+			}
+		}
+		return v
+	case []any:
+		// Elements are kept so that positions do not change.
+		for i, elem := range v {
+			v[i] = pruneZero(elem)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isZeroJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// ---------------------------
+// Values
+
+// MarshalJSON writes a table held by column as rows, as the binary format
+// does.
+func (v FlowValue) MarshalJSON() ([]byte, error) {
+	type plainValue FlowValue
+	p := plainValue(v)
+	if p.Table != nil {
+		p.TableValue = v.TableRows()
+		p.Table = nil
+	}
+	return json.Marshal(p)
+}
+
+// ---------------------------
+// Readable JSON for types
+
+var kindNames = map[FlowTypeKind]string{
+	FSKindAny:     "Any",
+	FSKindBytes:   "Bytes",
+	FSKindStream:  "Stream",
+	FSKindInt64:   "Int64",
+	FSKindFloat64: "Float64",
+	FSKindList:    "List",
+	FSKindRecord:  "Record",
+	FSKindTable:   "Table",
+	FSKindBool:    "Bool",
+	FSKindMap:     "Map",
+}
+
+var unitNames = map[FlowUnit]string{
+	FSUnitBytes:   "bytes",
+	FSUnitSeconds: "seconds",
+}
+
+var wellKnownTypeNames = map[FlowWellKnownType]string{
+	FSWKTFile:      "file",
+	FSWKTTimestamp: "timestamp",
+}
+
+type flowTypeText struct {
+	Kind          string          `json:"kind"`
+	ContainedType *FlowType       `json:"contained,omitempty"`
+	Fields        []flowFieldText `json:"fields,omitempty"`
+	Unit          string          `json:"unit,omitempty"`
+	WellKnownType string          `json:"well_known,omitempty"`
+	Nullable      bool            `json:"nullable,omitempty"`
+}
+
+type flowFieldText struct {
+	Name string    `json:"name"`
+	Type *FlowType `json:"type,omitempty"`
+}
+
+func (t FlowType) MarshalJSON() ([]byte, error) {
+	tt := flowTypeText{ContainedType: t.ContainedType, Nullable: t.Nullable}
+	var err error
+	if tt.Kind, err = nameOf(kindNames, t.Kind, true); err != nil {
+		return nil, err
+	}
+	if tt.Unit, err = nameOf(unitNames, t.Unit, false); err != nil {
+		return nil, err
+	}
+	if tt.WellKnownType, err = nameOf(wellKnownTypeNames, t.WellKnownType, false); err != nil {
+		return nil, err
+	}
+	for _, f := range t.Fields {
+		tt.Fields = append(tt.Fields, flowFieldText{Name: f.Name, Type: f.Type})
+	}
+	return json.Marshal(tt)
+}
+
+func (t *FlowType) UnmarshalJSON(data []byte) error {
+	var tt flowTypeText
+	if err := json.Unmarshal(data, &tt); err != nil {
+		return err
+	}
+	*t = FlowType{ContainedType: tt.ContainedType, Nullable: tt.Nullable}
+	var err error
+	if t.Kind, err = valueOf(kindNames, tt.Kind, "kind"); err != nil {
+		return err
+	}
+	if t.Unit, err = valueOf(unitNames, tt.Unit, "unit"); err != nil {
+		return err
+	}
+	if t.WellKnownType, err = valueOf(wellKnownTypeNames, tt.WellKnownType, "well-known type"); err != nil {
+		return err
+	}
+	for _, f := range tt.Fields {
+		t.Fields = append(t.Fields, FlowField{Name: f.Name, Type: f.Type})
+	}
+	return nil
+}
+
+// nameOf looks up the name of v. The zero value has no name unless required.
+func nameOf[T comparable](names map[T]string, v T, required bool) (string, error) {
+	var zero T
+	if v == zero && !required {
+		return "", nil
+	}
+	name, ok := names[v]
+	if !ok {
+		return "", fmt.Errorf("unknown value %v", v)
+	}
+	return name, nil
+}
+
+func valueOf[T comparable](names map[T]string, name string, what string) (T, error) {
+	var zero T
+	if name == "" {
+		return zero, nil
+	}
+	for v, n := range names {
+		if n == name {
+			return v, nil
+		}
+	}
+	return zero, fmt.Errorf("unknown %s %q", what, name)
+}
@@ -20,6 +20,16 @@ func PushHistory() {
 	}
 }
 
+// OpenSubgraphFunc should be set by app. It opens the embedded graph of a
+// subgraph node for editing.
+var OpenSubgraphFunc func(n *Node, g *Graph)
+
+func OpenSubgraph(n *Node, g *Graph) {
+	if OpenSubgraphFunc != nil {
+		OpenSubgraphFunc(n, g)
+	}
+}
+
 // Focus tracking
 var UIFocus *clay.ElementID
 var LastUIFocus clay.ElementID
@@ -435,6 +445,11 @@ func UIFlowValue(seed clay.ElementID, v FlowValue) {
 			}
 		}, nil)
 
+		if v.Null {
+			clay.TEXT("null", clay.TextElementConfig{FontID: JetBrainsMono, TextColor: LightGray})
+			return
+		}
+
 		switch v.Type.Kind {
 		case FSKindBytes:
 			if len(v.BytesValue) == 0 {
@@ -456,6 +471,8 @@ func UIFlowValue(seed clay.ElementID, v FlowValue) {
 			var str string
 			str = fmt.Sprintf("%v", v.Float64Value)
 			clay.TEXT(str, clay.TextElementConfig{TextColor: White})
+		case FSKindBool:
+			clay.TEXT(fmt.Sprintf("%t", v.BoolValue), clay.TextElementConfig{TextColor: White})
 		case FSKindList:
 			clay.CLAY(clay.ID(fmt.Sprintf("%d-ListGen", seed.ID)), clay.EL{ // list items
 				Layout: clay.LAY{LayoutDirection: clay.TopToBottom, ChildGap: S1},
@@ -512,6 +529,20 @@ func UIFlowValue(seed clay.ElementID, v FlowValue) {
 					})
 				}
 			})
+		case FSKindMap:
+			clay.CLAY(clay.ID(fmt.Sprintf("%d-MapGen", seed.ID)), clay.EL{ // map entries
+				Layout: clay.LAY{LayoutDirection: clay.TopToBottom, ChildGap: S1},
+			}, func() {
+				for i, entry := range v.MapValue {
+					entrySeed := clay.ID(fmt.Sprintf("%d-Entry-%d", seed.ID, i))
+					clay.CLAY(entrySeed, clay.EL{ // map entry
+						Layout: clay.LAY{ChildGap: S2},
+					}, func() {
+						clay.TEXT(fmt.Sprintf("%q", entry.Name), clay.TextElementConfig{FontID: InterSemibold, TextColor: White})
+						UIFlowValue(clay.ID(fmt.Sprintf("%d-Val", entrySeed.ID)), entry.Value)
+					})
+				}
+			})
 		case FSKindStream:
 			clay.TEXT("<stream>", clay.TextElementConfig{FontID: JetBrainsMono, TextColor: LightGray})
 		default:
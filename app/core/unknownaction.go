@@ -0,0 +1,99 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bvisness/flowshell/clay"
+)
+
+// UnknownAction stands in for an action whose tag is not registered, such as a
+// node type that has been removed or that comes from a plugin that is not
+// installed. It keeps the original tag and settings, so saving the graph
+// writes them back unchanged, and the node keeps its ports and wires. It
+// cannot run.
+type UnknownAction struct {
+	OriginalTag string
+
+	// Settings as read from a binary file, the version of that file, and the
+	// schema version of the settings.
+	Settings []byte
+	Version  int
+	Schema   int
+
+	// Settings as read from a text file, if that is where they came from.
+	SettingsJSON json.RawMessage
+}
+
+var _ NodeAction = &UnknownAction{}
+
+// UnknownActionError is the error for a node whose tag is not registered, in
+// a binary file from before version 10. Those files do not record the length
+// of each node's settings, so the node cannot be kept as an UnknownAction, and
+// nothing after it can be read.
+type UnknownActionError struct {
+	Tag     string
+	Version int
+}
+
+func (e *UnknownActionError) Error() string {
+	return fmt.Sprintf("unknown node type %q: the file is version %d, from before unknown nodes could be skipped (version 10), so nothing after this node can be read", e.Tag, e.Version)
+}
+
+func (a *UnknownAction) Tag() string {
+	return a.OriginalTag
+}
+
+func (a *UnknownAction) SchemaVersion() int {
+	return a.Schema
+}
+
+func (a *UnknownAction) UpdateAndValidate(n *Node) {
+	n.Valid = false
+}
+
+func (a *UnknownAction) UI(n *Node) {
+	clay.CLAY(clay.IDI("UnknownNode", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: GROWH, ChildGap: S2},
+	}, func() {
+		clay.TEXT(fmt.Sprintf("Unknown node type %q", a.OriginalTag), clay.TextElementConfig{TextColor: Yellow})
+		clay.TEXT("Its settings will be kept, but it cannot run.", clay.TextElementConfig{TextColor: LightGray})
+		for i := range n.InputPorts {
+			UIInputPort(n, i)
+		}
+		for i := range n.ActionOutputPorts() {
+			UIOutputPort(n, i)
+		}
+	})
+}
+
+func (a *UnknownAction) Run(n *Node) <-chan NodeActionResult {
+	done := make(chan NodeActionResult, 1)
+	done <- NodeActionResult{Err: fmt.Errorf("unknown node type %q; is a plugin missing?", a.OriginalTag)}
+	return done
+}
+
+// Serialize reads all remaining bytes as the settings, and writes them back
+// byte for byte. Settings from a file of another version may depend on that
+// version, so they are only written back to a file of the same version; the
+// text format records the version with them.
+func (a *UnknownAction) Serialize(s *Serializer) bool {
+	if !s.Ok() {
+		return false
+	}
+	if s.Encode {
+		if a.Settings == nil && a.SettingsJSON != nil {
+			return s.Error(fmt.Errorf("settings of unknown node type %q can only be saved in the text format", a.OriginalTag))
+		}
+		if len(a.Settings) > 0 && a.Version != s.Version {
+			return s.Error(fmt.Errorf("settings of unknown node type %q are from a version %d file, so they can only be saved in the text format", a.OriginalTag, a.Version))
+		}
+		if _, err := s.Buf.Write(a.Settings); err != nil {
+			return s.Error(err)
+		}
+	} else {
+		a.Settings = append([]byte(nil), s.Buf.Next(s.Buf.Len())...)
+		a.Version = s.Version
+	}
+	return true
+}
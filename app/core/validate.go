@@ -0,0 +1,116 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpdateAndValidate runs every node's UpdateAndValidate in dependency order,
+// so that inferred port types flow downstream. If the graph has a cycle, the
+// nodes are visited in their stored order and the error is returned.
+func (g *Graph) UpdateAndValidate() error {
+	sortedNodes, topoErr := Toposort(g.Nodes, g.Wires)
+	if topoErr != nil {
+		// If there is a cycle, we can't toposort. Just use the default order.
+		sortedNodes = g.Nodes
+	}
+	for _, node := range sortedNodes {
+		node.Action.UpdateAndValidate(node)
+	}
+	return topoErr
+}
+
+// A ValidationIssue is a problem found by ValidateGraph. Node is nil for
+// problems that do not belong to a single node.
+type ValidationIssue struct {
+	Node    *Node
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	if i.Node == nil {
+		return i.Message
+	}
+	return fmt.Sprintf("%s: %s", i.Node, i.Message)
+}
+
+// ValidateGraph statically checks g without running any node. It infers port
+// types with UpdateAndValidate, then reports invalid or unknown nodes, cycles,
+// wires that do not connect to existing ports, and wires whose types do not
+// match.
+func ValidateGraph(g *Graph) []ValidationIssue {
+	var issues []ValidationIssue
+	report := func(n *Node, format string, args ...any) {
+		issues = append(issues, ValidationIssue{Node: n, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, problem := range g.LoadProblems {
+		report(nil, "%s", problem)
+	}
+
+	seen := make(map[int]*Node)
+	for _, n := range g.Nodes {
+		if other, ok := seen[n.ID]; ok {
+			report(n, "duplicate node ID, also used by %s", other)
+		}
+		seen[n.ID] = n
+	}
+
+	// Wires must be checked before running UpdateAndValidate, which may
+	// index ports by wire.
+	wiresOK := true
+	for _, w := range g.Wires {
+		if w.StartNode.Graph != g || w.EndNode.Graph != g {
+			report(nil, "wire from %s to %s refers to a node outside the graph", w.StartNode, w.EndNode)
+			wiresOK = false
+		}
+		if w.StartPort < 0 || w.StartPort >= len(w.StartNode.OutputPorts) {
+			report(w.StartNode, "wire starts at missing output port %d", w.StartPort)
+			wiresOK = false
+		}
+		if w.EndPort < 0 || w.EndPort >= len(w.EndNode.InputPorts) {
+			report(w.EndNode, "wire ends at missing input port %d", w.EndPort)
+			wiresOK = false
+		}
+	}
+	if !wiresOK {
+		// The remaining checks cannot run safely.
+		return issues
+	}
+
+	inputWires := make(map[[2]int]int)
+	for _, w := range g.Wires {
+		key := [2]int{w.EndNode.ID, w.EndPort}
+		inputWires[key]++
+		if inputWires[key] == 2 {
+			report(w.EndNode, "input port %d (%s) has more than one wire", w.EndPort, w.EndNode.InputPorts[w.EndPort].Name)
+		}
+	}
+
+	if _, cyclic := toposort(g.Nodes, g.Wires); len(cyclic) > 0 {
+		var names []string
+		for _, n := range cyclic {
+			names = append(names, n.String())
+		}
+		report(nil, "cycle detected among %s", strings.Join(names, ", "))
+	}
+
+	_ = g.UpdateAndValidate()
+	for _, n := range g.Nodes {
+		if unknown, ok := n.Action.(*UnknownAction); ok {
+			report(n, "unknown action type %q", unknown.OriginalTag)
+		} else if !n.Valid {
+			report(n, "node is not valid")
+		}
+	}
+
+	for _, w := range g.Wires {
+		from := w.StartNode.OutputPorts[w.StartPort]
+		to := w.EndNode.InputPorts[w.EndPort]
+		if err := Typecheck(from.Type, to.Type); err != nil {
+			report(w.EndNode, "input port %d (%s) is wired to %s output %d (%s): %v", w.EndPort, to.Name, w.StartNode, w.StartPort, from.Name, err)
+		}
+	}
+
+	return issues
+}
@@ -2,25 +2,208 @@
 
 import (
 	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
-func HeadlessRun(path string) {
-	fmt.Printf("Starting Flowshell HEADLESS mode...\n")
-	fmt.Printf("Loading graph: %s\n", path)
+type HeadlessOptions struct {
+	// Overrides the cache directory from settings.json.
+	CacheDir string
+	// Disables the on-disk result cache entirely.
+	NoCache bool
+	// If set, a Chrome trace of the run is written here on exit.
+	ProfilePath string
+	// Override the concurrency limits from settings.json when positive.
+	MaxParallelism    int
+	ConcurrencyLimits map[string]int
+
+	// Set in Graph.Variables before running, overriding saved values.
+	Variables map[string]string
+	// Values for named graph inputs, parsed with ParseInputValue.
+	Inputs map[string]string
+
+	// If set, the values of the graph outputs are written to Output in this
+	// format when the run ends. See WriteGraphOutputs.
+	OutputFormat string
+
+	// Where graph outputs and everything else (progress, errors) are written.
+	// They default to stdout and stderr, so that runs can be piped into other
+	// tools.
+	Output io.Writer
+	Log    io.Writer
+}
+
+// ParseInputValue parses a value given on the command line. JSON is converted
+// with NativeToFlowValue; anything else is taken as a literal string, so that
+// `--input name=Alice` works without quoting.
+func ParseInputValue(str string) (core.FlowValue, error) {
+	var native any
+	if err := json.Unmarshal([]byte(str), &native); err != nil {
+		return core.NewStringValue(str), nil
+	}
+	return core.NativeToFlowValue(native)
+}
+
+// Applies variables and inputs from the command line to a loaded graph.
+func applyHeadlessParams(g *core.Graph, opts HeadlessOptions) error {
+	g.VarMutex.Lock()
+	if g.Variables == nil {
+		g.Variables = make(map[string]string)
+	}
+	for k, v := range opts.Variables {
+		g.Variables[k] = v
+	}
+	g.VarMutex.Unlock()
+
+	for name, str := range opts.Inputs {
+		value, err := ParseInputValue(str)
+		if err != nil {
+			return fmt.Errorf("bad value for input %q: %w", name, err)
+		}
+		if err := nodes.SetGraphInput(g, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolves the result cache directory the same way the GUI does, so that
+// headless runs and the app share cached results.
+func resolveCacheDir(override string, disabled bool) string {
+	if disabled {
+		return ""
+	}
+	if override != "" {
+		return override
+	}
+	return LoadSettings().CacheDir
+}
+
+// Exit codes of HeadlessRun, for use in scripts and CI.
+const (
+	ExitOK          = 0
+	ExitNodeFailed  = 1   // at least one node failed
+	ExitLoadFailed  = 2   // the graph could not be loaded
+	ExitInterrupted = 130 // the run was stopped by a signal, like a shell's Ctrl+C
+)
+
+// HeadlessSummary is the outcome of a headless run.
+type HeadlessSummary struct {
+	Succeeded   int
+	Failed      []*core.Node
+	Interrupted bool
+}
+
+func (s HeadlessSummary) ExitCode() int {
+	switch {
+	case s.Interrupted:
+		return ExitInterrupted
+	case len(s.Failed) > 0:
+		return ExitNodeFailed
+	default:
+		return ExitOK
+	}
+}
+
+// RunGraphToCompletion starts every node of g and waits until all of them
+// have finished, failed, been skipped, or ctx is done.
+//
+// We start all nodes because in a flow-based system, we don't necessarily know
+// which ones are "entry points" (some might be listeners, some might be timers).
+// Nodes that need inputs will block until inputs are available.
+func RunGraphToCompletion(ctx context.Context, g *core.Graph) HeadlessSummary {
+	var runs []<-chan struct{}
+	var nodes []*core.Node
+	for _, n := range g.Nodes {
+		if n.Action != nil {
+			// Results are reported through the event bus.
+			runs = append(runs, n.Run(ctx, false))
+			nodes = append(nodes, n)
+		}
+	}
+	for _, run := range runs {
+		// Nodes notice cancellation themselves, so this cannot hang.
+		<-run
+	}
+
+	summary := HeadlessSummary{Interrupted: ctx.Err() != nil}
+	for _, n := range nodes {
+		// Failures routed to an error port count as handled.
+		if res, ok := n.GetResult(); ok && res.Err == nil {
+			summary.Succeeded++
+		} else {
+			summary.Failed = append(summary.Failed, n)
+		}
+	}
+	return summary
+}
+
+// HeadlessRun runs the graph at path without the GUI and returns the process
+// exit code.
+func HeadlessRun(path string, opts HeadlessOptions) int {
+	out, log := opts.Output, opts.Log
+	if out == nil {
+		out = os.Stdout
+	}
+	if log == nil {
+		log = os.Stderr
+	}
+
+	if !validOutputFormat(opts.OutputFormat) {
+		fmt.Fprintf(log, "Error: unknown output format %q\n", opts.OutputFormat)
+		return ExitLoadFailed
+	}
+
+	fmt.Fprintf(log, "Starting Flowshell HEADLESS mode...\n")
+	fmt.Fprintf(log, "Loading graph: %s\n", path)
+
+	core.ResultCacheDir = resolveCacheDir(opts.CacheDir, opts.NoCache)
+	if core.ResultCacheDir != "" {
+		fmt.Fprintf(log, "Using result cache: %s\n", core.ResultCacheDir)
+	}
+
+	settings := LoadSettings()
+	if opts.MaxParallelism > 0 {
+		settings.MaxParallelism = opts.MaxParallelism
+	}
+	if settings.ConcurrencyLimits == nil {
+		settings.ConcurrencyLimits = make(map[string]int)
+	}
+	for category, limit := range opts.ConcurrencyLimits {
+		settings.ConcurrencyLimits[category] = limit
+	}
+	core.NodeScheduler = settings.Scheduler()
 
 	g, err := core.LoadGraph(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading graph: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(log, "Error loading graph: %v\n", err)
+		return ExitLoadFailed
 	}
 
-	fmt.Printf("Graph loaded. %d nodes, %d wires.\n", len(g.Nodes), len(g.Wires))
+	fmt.Fprintf(log, "Graph loaded. %d nodes, %d wires.\n", len(g.Nodes), len(g.Wires))
+
+	if err := applyHeadlessParams(g, opts); err != nil {
+		fmt.Fprintf(log, "Error: %v\n", err)
+		return ExitLoadFailed
+	}
+	// Nodes would wait on each other forever.
+	if err := g.UpdateAndValidate(); err != nil {
+		fmt.Fprintf(log, "Error: %v\n", err)
+		return ExitLoadFailed
+	}
+	// Check the outputs can be written before running anything.
+	if opts.OutputFormat != "" {
+		if _, err := graphOutputs(g); err != nil {
+			fmt.Fprintf(log, "Error: %v\n", err)
+			return ExitLoadFailed
+		}
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -28,44 +211,117 @@ func HeadlessRun(path string) {
 	// Handle interrupt signal
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 	go func() {
-		<-sigChan
-		fmt.Println("\nReceived interrupt signal, shutting down...")
-		cancel()
+		select {
+		case <-sigChan:
+			fmt.Fprintln(log, "\nReceived interrupt signal, shutting down...")
+			cancel()
+		case <-ctx.Done():
+		}
 	}()
 
-	// Start all nodes
-	// We start all nodes because in a flow-based system, we don't necessarily know
-	// which ones are "entry points" (some might be listeners, some might be timers).
-	// Nodes that need inputs will block until inputs are available.
+	// Report node progress.
+	unsubscribe := core.Events.Subscribe(func(ev core.NodeEvent) {
+		if ev.Node.Graph != g {
+			return
+		}
+		switch ev.Kind {
+		case core.NodeFailed, core.NodeCancelled:
+			fmt.Fprintf(log, "[Node %d %s] Error: %v\n", ev.Node.ID, ev.Node.Name, ev.Err)
+		default:
+			fmt.Fprintln(log, ev)
+		}
+	})
+	defer unsubscribe()
 
-	fmt.Println("Starting nodes...")
-	activeNodes := 0
-	for _, n := range g.Nodes {
-		if n.Action != nil {
-			activeNodes++
-			// We discard the result channel for now in headless mode,
-			// unless we want to log errors from it.
-			// Ideally specific nodes should log to stdout/stderr or a file.
-			// For now, let's just let them run.
-			go func(node *core.Node) {
-				// We call n.Run which internally handles context and calls Run/RunContext
-				done := node.Run(ctx, false)
-				<-done
-				if res, ok := node.GetResult(); ok && res.Err != nil {
-					fmt.Fprintf(os.Stderr, "[core.Node %d %s] Error: %v\n", node.ID, node.Name, res.Err)
-				}
-			}(n)
+	if opts.ProfilePath != "" {
+		profiler := core.StartProfiler(g)
+		defer func() {
+			profiler.Stop()
+			if err := profiler.SaveChromeTrace(opts.ProfilePath); err != nil {
+				fmt.Fprintf(log, "Error writing profile: %v\n", err)
+			} else {
+				fmt.Fprintf(log, "Wrote profile to %s\n", opts.ProfilePath)
+			}
+		}()
+	}
+
+	fmt.Fprintln(log, "Running nodes (Ctrl+C to stop)...")
+	summary := RunGraphToCompletion(ctx, g)
+
+	if opts.OutputFormat != "" {
+		if err := WriteGraphOutputs(out, g, opts.OutputFormat); err != nil {
+			fmt.Fprintf(log, "Error writing outputs: %v\n", err)
+			return ExitNodeFailed
 		}
 	}
 
-	fmt.Printf("Started %d nodes. Running (Ctrl+C to stop)...\n", activeNodes)
+	fmt.Fprintf(log, "Done: %d succeeded, %d failed.\n", summary.Succeeded, len(summary.Failed))
+	if len(summary.Failed) > 0 {
+		fmt.Fprintln(log, "Failed nodes:")
+		for _, n := range summary.Failed {
+			res, _ := n.GetResult()
+			fmt.Fprintf(log, "  [Node %d %s] %v\n", n.ID, n.Name, res.Err)
+		}
+	}
+	return summary.ExitCode()
+}
 
-	// Wait for context cancellation (interrupt)
-	<-ctx.Done()
+// ValidateFlow loads the graph at path and reports every problem found by
+// core.ValidateGraph, without running anything. It returns the process exit
+// code: ExitOK if the graph is valid, ExitNodeFailed if it has problems, or
+// ExitLoadFailed if it could not be loaded.
+func ValidateFlow(path string) int {
+	g, err := core.LoadGraph(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		if g != nil {
+			// Part of an old file was read; say how much.
+			for _, problem := range g.LoadProblems {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", path, problem)
+			}
+		}
+		return ExitLoadFailed
+	}
 
-	// Give a small grace period for cleanup if needed?
-	time.Sleep(100 * time.Millisecond)
-	fmt.Println("Shutdown complete.")
+	issues := core.ValidateGraph(g)
+	for _, issue := range issues {
+		fmt.Printf("%s: %s\n", path, issue)
+	}
+	if len(issues) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d problem(s) found\n", path, len(issues))
+		return ExitNodeFailed
+	}
+	fmt.Fprintf(os.Stderr, "%s: OK (%d nodes, %d wires)\n", path, len(g.Nodes), len(g.Wires))
+	return ExitOK
 }
 
+// ConvertFlow loads the flow at inPath, which may be in either format, and
+// saves it to outPath. The output is in the text format if outPath ends in
+// .json, and in the binary format otherwise.
+func ConvertFlow(inPath, outPath string) int {
+	g, err := core.LoadGraph(inPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", inPath, err)
+		return ExitLoadFailed
+	}
+	for _, problem := range g.LoadProblems {
+		fmt.Fprintf(os.Stderr, "%s: warning: %s\n", inPath, problem)
+	}
+	if err := core.SaveGraph(outPath, g); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", outPath, err)
+		return ExitNodeFailed
+	}
+	return ExitOK
+}
+
+// ClearResultCache deletes all persisted node results. If cacheDir is empty,
+// the directory from settings.json is used.
+func ClearResultCache(cacheDir string) error {
+	core.ResultCacheDir = resolveCacheDir(cacheDir, false)
+	if core.ResultCacheDir == "" {
+		return fmt.Errorf("no cache directory configured")
+	}
+	return core.ClearResultCache()
+}
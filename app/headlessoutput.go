@@ -0,0 +1,171 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+)
+
+// Formats for the results of graph outputs in headless runs.
+const (
+	OutputFormatJSON   = "json"   // one object mapping output names to values
+	OutputFormatNDJSON = "ndjson" // one {"name", "value"} object per line
+	OutputFormatCSV    = "csv"    // each output must be a table; tables are separated by a blank line
+)
+
+func validOutputFormat(format string) bool {
+	switch format {
+	case "", OutputFormatJSON, OutputFormatNDJSON, OutputFormatCSV:
+		return true
+	}
+	return false
+}
+
+// A graph output and its value. Value is nil if the output produced no value,
+// e.g. because it was skipped or failed.
+type graphOutput struct {
+	Name  string
+	Value *core.FlowValue
+}
+
+// graphOutputs returns the outputs of g in order. Outputs are written under
+// their names, so two outputs with the same name are an error.
+func graphOutputs(g *core.Graph) ([]graphOutput, error) {
+	var outputs []graphOutput
+	seen := make(map[string]*core.Node)
+	for _, n := range g.Nodes {
+		action, ok := n.Action.(*nodes.GraphOutputAction)
+		if !ok {
+			continue
+		}
+		out := graphOutput{Name: action.Name}
+		if out.Name == "" {
+			out.Name = fmt.Sprintf("output%d", n.ID)
+		}
+		if other, ok := seen[out.Name]; ok {
+			return nil, fmt.Errorf("%s and %s are both named %q", other, n, out.Name)
+		}
+		seen[out.Name] = n
+		if v, ok, err := n.GetOutputValue(0); ok && err == nil && !v.Skipped {
+			out.Value = &v
+		}
+		outputs = append(outputs, out)
+	}
+	return outputs, nil
+}
+
+func outputToNative(out graphOutput) any {
+	if out.Value == nil {
+		return nil
+	}
+	return core.FlowValueToNative(*out.Value)
+}
+
+// WriteGraphOutputs writes the results of every GraphOutputAction in g in the
+// given format. Outputs without a value are written as null, or left out of
+// CSV. Outputs must have distinct names.
+func WriteGraphOutputs(w io.Writer, g *core.Graph, format string) error {
+	outputs, err := graphOutputs(g)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case OutputFormatJSON:
+		obj := make(map[string]any)
+		for _, out := range outputs {
+			obj[out.Name] = outputToNative(out)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(obj)
+	case OutputFormatNDJSON:
+		enc := json.NewEncoder(w)
+		for _, out := range outputs {
+			line := struct {
+				Name  string `json:"name"`
+				Value any    `json:"value"`
+			}{out.Name, outputToNative(out)}
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OutputFormatCSV:
+		first := true
+		for _, out := range outputs {
+			if out.Value == nil {
+				continue
+			}
+			if out.Value.Type.Kind != core.FSKindTable {
+				return fmt.Errorf("output %q is a %s, but CSV output requires tables", out.Name, out.Value.Type)
+			}
+			if !first {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+			first = false
+			if err := writeTableCSV(w, *out.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+func writeTableCSV(w io.Writer, table core.FlowValue) error {
+	cw := csv.NewWriter(w)
+
+	var header []string
+	if table.Type.ContainedType != nil {
+		for _, field := range table.Type.ContainedType.Fields {
+			header = append(header, field.Name)
+		}
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i := range table.NumRows() {
+		row := table.RowFields(i)
+		var record []string
+		for _, field := range row {
+			record = append(record, csvCell(field.Value))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Scalars are written as-is; anything nested is written as JSON.
+func csvCell(v core.FlowValue) string {
+	if v.Null {
+		return ""
+	}
+	switch v.Type.Kind {
+	case core.FSKindBytes:
+		return string(v.BytesValue)
+	case core.FSKindInt64:
+		return strconv.FormatInt(v.Int64Value, 10)
+	case core.FSKindFloat64:
+		return strconv.FormatFloat(v.Float64Value, 'f', -1, 64)
+	case core.FSKindBool:
+		return strconv.FormatBool(v.BoolValue)
+	}
+	b, err := json.Marshal(core.FlowValueToNative(v))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
 	"github.com/bvisness/flowshell/clay"
 	"github.com/bvisness/flowshell/util"
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -51,70 +52,52 @@ func UIMenuBar() {
 			UIMenuItem("File", func() {
 				UIMenuDropdownItem("New", func() {
 					ActiveMenu = ""
-					// Confirm discard?
-					core.PushHistory()
-					CurrentGraph = core.NewGraph()
-					CurrentFilename = ""
+					ConfirmDiscardChanges("Discard changes and start a new flow?", "New Flow", NewGraph)
 				})
 				UIMenuDropdownItem("Open... (Ctrl+L)", func() {
 					ActiveMenu = ""
-					cwd, _ := os.Getwd()
-					filename, ok, err := core.OpenFileDialog("Open Flow", cwd, map[string]string{"flow": "Flow Files"})
-					if err != nil {
-						fmt.Printf("Load error: %v\n", err)
-						core.ShowInfoDialog("Error", fmt.Sprintf("Failed to open file dialog: %v", err))
-					} else if ok {
-						core.PushHistory()
-						if g, err := core.LoadGraph(filename); err == nil {
-							CurrentGraph = g
-							CurrentFilename = filename
-						}
-					}
+					ConfirmDiscardChanges("Discard changes and open another flow?", "Open", OpenGraph)
 				})
 				UIMenuDropdownItem("Save (Ctrl+S)", func() {
 					ActiveMenu = ""
-					if CurrentFilename != "" {
-						err := core.SaveGraph(CurrentFilename, CurrentGraph)
-						if err != nil {
-							fmt.Printf("Save error: %v\n", err)
-						}
-					} else {
-						cwd, _ := os.Getwd()
-						filename, ok, err := core.SaveFileDialog("Save Flow", cwd, map[string]string{"flow": "Flow Files"})
-						if err != nil {
-							fmt.Printf("Save error: %v\n", err)
-						} else if ok {
-							if filepath.Ext(filename) != ".flow" {
-								filename += ".flow"
-							}
-							if err := core.SaveGraph(filename, CurrentGraph); err == nil {
-								CurrentFilename = filename
-							}
-						}
-					}
+					SaveCurrentGraph(CurrentFilename)
 				})
 				UIMenuDropdownItem("Save As... (Ctrl+Shift+S)", func() {
 					ActiveMenu = ""
-					initialDir, _ := os.Getwd()
-					if CurrentFilename != "" {
-						initialDir = filepath.Dir(CurrentFilename)
+					SaveCurrentGraphAs()
+				})
+				UIMenuSeparator()
+				UIMenuDropdownItem("Export Run Profile...", func() {
+					ActiveMenu = ""
+					profile := LastRunProfile()
+					if profile == nil {
+						core.ShowInfoDialog("Export Run Profile", "Run the graph first to record a profile.")
+						return
 					}
-					filename, ok, err := core.SaveFileDialog("Save Flow", initialDir, map[string]string{"flow": "Flow Files"})
+					cwd, _ := os.Getwd()
+					filename, ok, err := core.SaveFileDialog("Export Run Profile", cwd, map[string]string{"json": "Chrome Trace Files"})
 					if err != nil {
-						fmt.Printf("Save error: %v\n", err)
+						fmt.Printf("Profile export error: %v\n", err)
 					} else if ok {
-						if filepath.Ext(filename) != ".flow" {
-							filename += ".flow"
+						if filepath.Ext(filename) != ".json" {
+							filename += ".json"
 						}
-						if err := core.SaveGraph(filename, CurrentGraph); err == nil {
-							CurrentFilename = filename
+						if err := profile.SaveChromeTrace(filename); err != nil {
+							core.ShowInfoDialog("Error", fmt.Sprintf("Failed to export profile: %v", err))
 						}
 					}
 				})
+				UIMenuDropdownItem("Clear Result Cache", func() {
+					ActiveMenu = ""
+					nodes.ClearCachedResults(RootGraph())
+					if err := core.ClearResultCache(); err != nil {
+						core.ShowInfoDialog("Error", fmt.Sprintf("Failed to clear result cache: %v", err))
+					}
+				})
 				UIMenuSeparator()
 				UIMenuDropdownItem("Quit", func() {
 					ActiveMenu = ""
-					ShouldQuit = true
+					ConfirmDiscardChanges("Discard changes and quit?", "Quit", func() { ShouldQuit = true })
 				})
 			})
 
@@ -8,12 +8,14 @@ func init() {
 	core.RegisterNodeAction("AddColumnAction", func() core.NodeAction { return &AddColumnAction{} })
 	core.RegisterNodeAction("AggregateAction", func() core.NodeAction { return &AggregateAction{} })
 	core.RegisterNodeAction("BarChartAction", func() core.NodeAction { return &BarChartAction{} })
+	core.RegisterNodeAction("CallFlowAction", func() core.NodeAction { return &CallFlowAction{} })
 	core.RegisterNodeAction("CaseConvertAction", func() core.NodeAction { return &CaseConvertAction{} })
 	core.RegisterNodeAction("ConcatTablesAction", func() core.NodeAction { return &ConcatTablesAction{} })
 	core.RegisterNodeAction("ConvertAction", func() core.NodeAction { return &ConvertAction{} })
 	core.RegisterNodeAction("CopyFileAction", func() core.NodeAction { return &CopyFileAction{} })
 	core.RegisterNodeAction("DeleteFileAction", func() core.NodeAction { return &DeleteFileAction{} })
 	core.RegisterNodeAction("ExtractColumnAction", func() core.NodeAction { return &ExtractColumnAction{} })
+	core.RegisterNodeAction("FilterAction", func() core.NodeAction { return &FilterAction{} })
 	core.RegisterNodeAction("FilterEmptyAction", func() core.NodeAction { return &FilterEmptyAction{} })
 	core.RegisterNodeAction("FormatStringAction", func() core.NodeAction { return &FormatStringAction{} })
 	core.RegisterNodeAction("FormulaAction", func() core.NodeAction { return &FormulaAction{} })
@@ -32,23 +34,30 @@ func init() {
 	core.RegisterNodeAction("LoadFileAction", func() core.NodeAction { return &LoadFileAction{} })
 	core.RegisterNodeAction("MakeDirAction", func() core.NodeAction { return &MakeDirAction{} })
 	core.RegisterNodeAction("MapAction", func() core.NodeAction { return &MapAction{} })
+	core.RegisterNodeAction("MapEntriesAction", func() core.NodeAction { return &MapEntriesAction{} })
+	core.RegisterNodeAction("MapKeysAction", func() core.NodeAction { return &MapKeysAction{} })
+	core.RegisterNodeAction("MapValuesAction", func() core.NodeAction { return &MapValuesAction{} })
 	core.RegisterNodeAction("MergeAction", func() core.NodeAction { return &MergeAction{} })
 	core.RegisterNodeAction("MinifyHTMLAction", func() core.NodeAction { return &MinifyHTMLAction{} })
 	core.RegisterNodeAction("MoveFileAction", func() core.NodeAction { return &MoveFileAction{} })
 	core.RegisterNodeAction("ParseTimeAction", func() core.NodeAction { return &ParseTimeAction{} })
 	core.RegisterNodeAction("PromptUserAction", func() core.NodeAction { return &PromptUserAction{} })
+	core.RegisterNodeAction("ReduceAction", func() core.NodeAction { return &ReduceAction{} })
 	core.RegisterNodeAction("RegexFindAllAction", func() core.NodeAction { return &RegexFindAllAction{} })
 	core.RegisterNodeAction("RegexMatchAction", func() core.NodeAction { return &RegexMatchAction{} })
 	core.RegisterNodeAction("RegexReplaceAction", func() core.NodeAction { return &RegexReplaceAction{} })
 	core.RegisterNodeAction("RegexSplitAction", func() core.NodeAction { return &RegexSplitAction{} })
+	core.RegisterNodeAction("RepeatAction", func() core.NodeAction { return &RepeatAction{} })
 	core.RegisterNodeAction("RunProcessAction", func() core.NodeAction { return &RunProcessAction{} })
 	core.RegisterNodeAction("SaveFileAction", func() core.NodeAction { return &SaveFileAction{} })
 	core.RegisterNodeAction("ScatterPlotAction", func() core.NodeAction { return &ScatterPlotAction{} })
 	core.RegisterNodeAction("SelectColumnsAction", func() core.NodeAction { return &SelectColumnsAction{} })
 	core.RegisterNodeAction("SortAction", func() core.NodeAction { return &SortAction{} })
 	core.RegisterNodeAction("SplitTextAction", func() core.NodeAction { return &SplitTextAction{} })
+	core.RegisterNodeAction("SubgraphAction", func() core.NodeAction { return &SubgraphAction{} })
 	core.RegisterNodeAction("TransposeAction", func() core.NodeAction { return &TransposeAction{} })
 	core.RegisterNodeAction("TrimSpacesAction", func() core.NodeAction { return &TrimSpacesAction{} })
+	core.RegisterNodeAction("TryAction", func() core.NodeAction { return &TryAction{} })
 	core.RegisterNodeAction("ValueAction", func() core.NodeAction { return &ValueAction{} })
 	core.RegisterNodeAction("WaitForClickAction", func() core.NodeAction { return &WaitForClickAction{} })
 	core.RegisterNodeAction("XmlQueryAction", func() core.NodeAction { return &XmlQueryAction{} })
@@ -63,6 +72,9 @@ func (a *AggregateAction) Tag() string {
 func (a *BarChartAction) Tag() string {
 	return "BarChartAction"
 }
+func (a *CallFlowAction) Tag() string {
+	return "CallFlowAction"
+}
 func (a *CaseConvertAction) Tag() string {
 	return "CaseConvertAction"
 }
@@ -81,6 +93,9 @@ func (a *DeleteFileAction) Tag() string {
 func (a *ExtractColumnAction) Tag() string {
 	return "ExtractColumnAction"
 }
+func (a *FilterAction) Tag() string {
+	return "FilterAction"
+}
 func (a *FilterEmptyAction) Tag() string {
 	return "FilterEmptyAction"
 }
@@ -135,6 +150,15 @@ func (a *MakeDirAction) Tag() string {
 func (a *MapAction) Tag() string {
 	return "MapAction"
 }
+func (a *MapEntriesAction) Tag() string {
+	return "MapEntriesAction"
+}
+func (a *MapKeysAction) Tag() string {
+	return "MapKeysAction"
+}
+func (a *MapValuesAction) Tag() string {
+	return "MapValuesAction"
+}
 func (a *MergeAction) Tag() string {
 	return "MergeAction"
 }
@@ -150,6 +174,9 @@ func (a *ParseTimeAction) Tag() string {
 func (a *PromptUserAction) Tag() string {
 	return "PromptUserAction"
 }
+func (a *ReduceAction) Tag() string {
+	return "ReduceAction"
+}
 func (a *RegexFindAllAction) Tag() string {
 	return "RegexFindAllAction"
 }
@@ -162,6 +189,9 @@ func (a *RegexReplaceAction) Tag() string {
 func (a *RegexSplitAction) Tag() string {
 	return "RegexSplitAction"
 }
+func (a *RepeatAction) Tag() string {
+	return "RepeatAction"
+}
 func (a *RunProcessAction) Tag() string {
 	return "RunProcessAction"
 }
@@ -180,12 +210,18 @@ func (a *SortAction) Tag() string {
 func (a *SplitTextAction) Tag() string {
 	return "SplitTextAction"
 }
+func (a *SubgraphAction) Tag() string {
+	return "SubgraphAction"
+}
 func (a *TransposeAction) Tag() string {
 	return "TransposeAction"
 }
 func (a *TrimSpacesAction) Tag() string {
 	return "TrimSpacesAction"
 }
+func (a *TryAction) Tag() string {
+	return "TryAction"
+}
 func (a *ValueAction) Tag() string {
 	return "ValueAction"
 }
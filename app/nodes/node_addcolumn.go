@@ -147,13 +147,14 @@ func (c *AddColumnAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 			return
 		}
 
-		if len(tableInput.TableValue) != len(valuesInput.ListValue) {
-			res.Err = fmt.Errorf("row count mismatch: table has %d, values list has %d", len(tableInput.TableValue), len(valuesInput.ListValue))
+		if tableInput.NumRows() != len(valuesInput.ListValue) {
+			res.Err = fmt.Errorf("row count mismatch: table has %d, values list has %d", tableInput.NumRows(), len(valuesInput.ListValue))
 			return
 		}
 
 		var newRows [][]core.FlowValueField
-		for i, row := range tableInput.TableValue {
+		for i := range tableInput.NumRows() {
+			row := tableInput.RowFields(i)
 			select {
 			case <-ctx.Done():
 				res.Err = ctx.Err()
@@ -136,7 +136,7 @@ func (a *AggregateAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 		op := a.ops.GetSelectedOption().Value.(AggOp)
 		switch input.Type.Kind {
 		case core.FSKindList:
-			agged, err := op(input.ListValue, *input.Type.ContainedType)
+			agged, err := op(withoutNulls(input.ListValue), *input.Type.ContainedType)
 			if err != nil {
 				res.Err = err
 				return
@@ -154,7 +154,10 @@ func (a *AggregateAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 				default:
 				}
 
-				agged, err := op(input.ColumnValues(col), *field.Type)
+				agged, ok := aggColumn(a.ops.GetSelectedOption().Name, &input, col, *field.Type)
+				if !ok {
+					agged, err = op(withoutNulls(input.ColumnValues(col)), *field.Type)
+				}
 				if err != nil {
 					res.Err = fmt.Errorf("for column %s: %v", field.Name, err)
 					return
@@ -196,6 +199,68 @@ func (n *AggregateAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+// Missing values are left out of aggregates.
+func withoutNulls(vals []core.FlowValue) []core.FlowValue {
+	var res []core.FlowValue
+	for _, v := range vals {
+		if !v.Null {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// aggColumn aggregates a numeric column of a table held by column straight
+// from its vector, without making a value per cell. It reports false for
+// other columns, which go through the AggOp instead.
+func aggColumn(opName string, input *core.FlowValue, col int, t core.FlowType) (core.FlowValue, bool) {
+	if input.Table == nil {
+		return core.FlowValue{}, false
+	}
+	column := input.Table.Columns[col]
+	if ints, ok := column.Int64s(); ok && t.Kind == core.FSKindInt64 {
+		res, ok := aggVector(opName, ints, column.Nulls())
+		return core.FlowValue{Type: &t, Int64Value: res}, ok
+	}
+	if floats, ok := column.Float64s(); ok && t.Kind == core.FSKindFloat64 {
+		res, ok := aggVector(opName, floats, column.Nulls())
+		return core.FlowValue{Type: &t, Float64Value: res}, ok
+	}
+	return core.FlowValue{}, false
+}
+
+// aggVector does what AggOpMin, AggOpMax and AggOpMean do, on the cells of a
+// vector that are not null. Like them, it gives zero if there are none.
+func aggVector[T int64 | float64](opName string, vals []T, nulls []bool) (T, bool) {
+	if opName != "Min" && opName != "Max" && opName != "Mean" {
+		return 0, false
+	}
+
+	var res, sum T
+	var count int
+	for i, v := range vals {
+		if nulls != nil && nulls[i] {
+			continue
+		}
+		if count == 0 {
+			res = v
+		}
+		switch opName {
+		case "Min":
+			res = min(res, v)
+		case "Max":
+			res = max(res, v)
+		case "Mean":
+			sum += v
+		}
+		count++
+	}
+	if opName == "Mean" && count > 0 {
+		res = sum / T(count)
+	}
+	return res, true
+}
+
 type AggOp = func(vals []core.FlowValue, t core.FlowType) (core.FlowValue, error)
 
 var _ AggOp = AggOpMin
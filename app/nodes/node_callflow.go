@@ -0,0 +1,205 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+)
+
+// Call Flow runs another flow like a function. Each named graph input and
+// output of the flow becomes a port of the node, typed as inferred in the
+// flow.
+//
+// GEN:NodeAction
+type CallFlowAction struct {
+	Path string
+
+	mu         sync.Mutex
+	graph      *core.Graph
+	loadedPath string
+	modTime    time.Time
+	loadErr    error
+}
+
+func NewCallFlowNode() *core.Node {
+	return &core.Node{
+		Name:   "Call Flow",
+		Action: &CallFlowAction{},
+	}
+}
+
+var _ core.NodeAction = &CallFlowAction{}
+
+// loadGraph returns the flow at Path, reading it again if the path has
+// changed. If checkModTime is set, it is also read again if the file has
+// been modified since.
+func (a *CallFlowAction) loadGraph(checkModTime bool) (*core.Graph, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.loadedPath == a.Path && !checkModTime {
+		return a.graph, a.loadErr
+	}
+
+	info, err := os.Stat(a.Path)
+	if err == nil && a.graph != nil && a.loadedPath == a.Path && info.ModTime().Equal(a.modTime) {
+		return a.graph, nil
+	}
+
+	a.graph, a.loadedPath, a.loadErr = nil, a.Path, err
+	if err != nil {
+		return nil, err
+	}
+	g, err := core.LoadGraph(a.Path)
+	if err != nil {
+		a.loadErr = err
+		return nil, err
+	}
+	g.UpdateAndValidate() // infer the types of the graph inputs and outputs
+	a.graph, a.modTime = g, info.ModTime()
+	return g, nil
+}
+
+func (a *CallFlowAction) UpdateAndValidate(n *core.Node) {
+	n.Valid = false
+	if a.Path == "" {
+		return
+	}
+
+	// If the flow cannot be read, the ports are kept so that wires survive
+	// until it can.
+	g, err := a.loadGraph(false)
+	if err != nil {
+		return
+	}
+	inputs, outputs := NewGraphSignature(g, true).Ports()
+	setFunctionPorts(n, inputs, outputs)
+
+	n.Valid = true
+	for i := range n.InputPorts {
+		if !n.InputIsWired(i) {
+			n.Valid = false
+		}
+	}
+}
+
+func (a *CallFlowAction) UI(n *core.Node) {
+	clay.CLAY(clay.IDI("CallFlowUI", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		clay.CLAY(clay.IDI("CallFlowRow", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+		}, func() {
+			core.UITextBox(clay.IDI("CallFlowPath", n.ID), &a.Path, core.UITextBoxConfig{
+				El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+			})
+			core.UISpacer(clay.IDI("CallFlowSpacer", n.ID), core.W2)
+			core.UIButton(clay.IDI("CallFlowBrowse", n.ID), core.UIButtonConfig{
+				OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+					cwd, _ := os.Getwd()
+					path, ok, err := core.OpenFileDialog("Call Flow", cwd, map[string]string{"flow": "Flow Files"})
+					if err == nil && ok {
+						core.PushHistory()
+						a.Path = path
+					}
+				},
+				ZIndex: core.Z_NODE_BUTTON,
+			}, func() {
+				clay.TEXT("Browse...", clay.TextElementConfig{TextColor: core.White})
+			})
+		})
+
+		a.mu.Lock()
+		loadErr := a.loadErr
+		a.mu.Unlock()
+		if loadErr != nil {
+			clay.TEXT(fmt.Sprintf("Cannot read flow: %v", loadErr), clay.TextElementConfig{TextColor: core.Yellow})
+		}
+
+		for i := range n.InputPorts {
+			core.UIInputPort(n, i)
+		}
+		for i := range n.ActionOutputPorts() {
+			core.UIOutputPort(n, i)
+		}
+	})
+}
+
+func (a *CallFlowAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *CallFlowAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	go func() {
+		defer close(done)
+
+		g, err := a.loadGraph(true)
+		if err != nil {
+			done <- core.NodeActionResult{Err: fmt.Errorf("failed to load flow: %v", err)}
+			return
+		}
+
+		// The flow may have changed since the ports were last updated, so
+		// parameters are matched to ports by name.
+		sig := NewGraphSignature(g, true)
+		args := make([]core.FlowValue, len(sig.Inputs))
+		for i, param := range sig.Inputs {
+			port := slices.IndexFunc(n.InputPorts, func(p core.NodePort) bool { return p.Name == param.Port.Name })
+			if port < 0 {
+				done <- core.NodeActionResult{Err: fmt.Errorf("%s has a new input %q", a.Path, param.Port.Name)}
+				return
+			}
+			val, ok, err := n.GetInputValue(port)
+			if err != nil {
+				done <- core.NodeActionResult{Err: err}
+				return
+			}
+			if !ok {
+				done <- core.NodeActionResult{Err: fmt.Errorf("missing input %q", param.Port.Name)}
+				return
+			}
+			args[i] = val
+		}
+
+		results, err := sig.Call(ctx, args)
+		if err != nil {
+			done <- core.NodeActionResult{Err: fmt.Errorf("%s: %w", a.Path, err)}
+			return
+		}
+
+		ports := n.ActionOutputPorts()
+		outputs := make([]core.FlowValue, len(ports))
+		for i, port := range ports {
+			j := slices.IndexFunc(sig.Outputs, func(param GraphParam) bool { return param.Port.Name == port.Name })
+			if j < 0 {
+				done <- core.NodeActionResult{Err: fmt.Errorf("%s no longer has an output %q", a.Path, port.Name)}
+				return
+			}
+			outputs[i] = results[j]
+		}
+		done <- core.NodeActionResult{Outputs: outputs}
+	}()
+	return done
+}
+
+func (a *CallFlowAction) Serialize(s *core.Serializer) bool {
+	core.SStr(s, &a.Path)
+	return s.Ok()
+}
+
+// The flow is loaded from disk and may have changed.
+func (a *CallFlowAction) HasSideEffects() bool {
+	return true
+}
+
+// Call Flow only waits on the flow, whose nodes are scheduled themselves.
+func (a *CallFlowAction) SchedCategory() string {
+	return core.SchedCategoryCoordinator
+}
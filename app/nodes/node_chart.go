@@ -1,6 +1,7 @@
 ﻿package nodes
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math"
 	"strconv"
@@ -29,6 +30,62 @@ type ChartRenderData struct {
 	Error  string
 }
 
+func init() {
+	core.RegisterNodeActionUpgrade("LineChartAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		c := a.(*LineChartAction)
+		return readChartSettingsV0(s, &c.XColumn, &c.YColumn, &c.Width, &c.Height)
+	})
+	core.RegisterNodeActionUpgrade("BarChartAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		c := a.(*BarChartAction)
+		return readChartSettingsV0(s, &c.XColumn, &c.YColumn, &c.Width, &c.Height)
+	})
+	core.RegisterNodeActionUpgrade("ScatterPlotAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		c := a.(*ScatterPlotAction)
+		return readChartSettingsV0(s, &c.XColumn, &c.YColumn, &c.Width, &c.Height)
+	})
+}
+
+// Charts saved before schema version 1 may or may not have a size. If there
+// is no size, use the default.
+func readChartSettingsV0(s *core.Serializer, xColumn, yColumn *string, width, height *float32) bool {
+	core.SStr(s, xColumn)
+	core.SStr(s, yColumn)
+	if hasChartSizeV0(s) {
+		core.SFloat(s, width)
+		core.SFloat(s, height)
+	} else {
+		*width = 400
+		*height = 200
+	}
+	return s.Ok()
+}
+
+// hasChartSizeV0 reports whether chart settings go on to a size.
+//
+// The size was added while files were at version 4, without a new version, so
+// a version 4 file may or may not have one; the saves kept in
+// examples/history/v4 do not. Every later version does. For version 4 and
+// before this is a known limitation: the next eight bytes are taken as a size
+// if they are two floats in a plausible range, and otherwise as the next node
+// or the wires. A file without a size whose next bytes happen to look like one
+// is read wrong.
+func hasChartSizeV0(s *core.Serializer) bool {
+	if s.Version > 4 {
+		return true
+	}
+	rest := s.Buf.Bytes()
+	if len(rest) < 8 { // two float32s
+		return false
+	}
+	for i := 0; i < 8; i += 4 {
+		v := math.Float32frombits(binary.LittleEndian.Uint32(rest[i:]))
+		if !(v >= 1 && v <= 100000) {
+			return false
+		}
+	}
+	return true
+}
+
 // Helper to extract data
 func ExtractChartData(n *core.Node, xCol, yCol string, chartType ChartType) *ChartRenderData {
 	renderData := &ChartRenderData{
@@ -86,12 +143,12 @@ func ExtractChartData(n *core.Node, xCol, yCol string, chartType ChartType) *Cha
 
 	maxPoints := 1000
 	step := 1
-	if len(val.TableValue) > maxPoints {
-		step = len(val.TableValue) / maxPoints
+	if val.NumRows() > maxPoints {
+		step = val.NumRows() / maxPoints
 	}
 
-	for i := 0; i < len(val.TableValue); i += step {
-		row := val.TableValue[i]
+	for i := 0; i < val.NumRows(); i += step {
+		row := val.RowFields(i)
 		var x, y float64
 		var err error
 
@@ -203,25 +260,16 @@ func NewLineChartNode() *core.Node {
 
 var _ core.NodeAction = &LineChartAction{}
 
+// Since schema version 1, the chart size is always saved.
+func (c *LineChartAction) SchemaVersion() int {
+	return 1
+}
+
 func (c *LineChartAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &c.XColumn)
 	core.SStr(s, &c.YColumn)
-
-	if s.Encode {
-		core.SFloat(s, &c.Width)
-		core.SFloat(s, &c.Height)
-	} else {
-		// safeCheck: Need 8 bytes for 2 float32s.
-		// Actually SFloat uses binary.Read which might be Fixed size. float32 is 4 bytes.
-		// If we don't have enough bytes, assume old save.
-		if s.Buf.Len() >= 8 {
-			core.SFloat(s, &c.Width)
-			core.SFloat(s, &c.Height)
-		} else {
-			c.Width = 400
-			c.Height = 200
-		}
-	}
+	core.SFloat(s, &c.Width)
+	core.SFloat(s, &c.Height)
 	return s.Ok()
 }
 
@@ -340,22 +388,15 @@ func NewBarChartNode() *core.Node {
 
 var _ core.NodeAction = &BarChartAction{}
 
+func (c *BarChartAction) SchemaVersion() int {
+	return 1
+}
+
 func (c *BarChartAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &c.XColumn)
 	core.SStr(s, &c.YColumn)
-
-	if s.Encode {
-		core.SFloat(s, &c.Width)
-		core.SFloat(s, &c.Height)
-	} else {
-		if s.Buf.Len() >= 8 {
-			core.SFloat(s, &c.Width)
-			core.SFloat(s, &c.Height)
-		} else {
-			c.Width = 400
-			c.Height = 200
-		}
-	}
+	core.SFloat(s, &c.Width)
+	core.SFloat(s, &c.Height)
 	return s.Ok()
 }
 
@@ -474,22 +515,15 @@ func NewScatterPlotNode() *core.Node {
 
 var _ core.NodeAction = &ScatterPlotAction{}
 
+func (c *ScatterPlotAction) SchemaVersion() int {
+	return 1
+}
+
 func (c *ScatterPlotAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &c.XColumn)
 	core.SStr(s, &c.YColumn)
-
-	if s.Encode {
-		core.SFloat(s, &c.Width)
-		core.SFloat(s, &c.Height)
-	} else {
-		if s.Buf.Len() >= 8 {
-			core.SFloat(s, &c.Width)
-			core.SFloat(s, &c.Height)
-		} else {
-			c.Width = 400
-			c.Height = 200
-		}
-	}
+	core.SFloat(s, &c.Width)
+	core.SFloat(s, &c.Height)
 	return s.Ok()
 }
 
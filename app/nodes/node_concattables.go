@@ -43,6 +43,8 @@ func (a *ConcatTablesAction) UpdateAndValidate(n *core.Node) {
 			n.OutputPorts[0].Type = core.NewAnyTableType()
 		} else if i == 0 {
 			n.OutputPorts[0].Type = wire.Type()
+		} else {
+			n.OutputPorts[0].Type = mergeNullableColumns(n.OutputPorts[0].Type, wire.Type())
 		}
 	}
 
@@ -138,7 +140,7 @@ func (a *ConcatTablesAction) RunContext(ctx context.Context, n *core.Node) <-cha
 			res.Err = err
 			return
 		}
-		expectedType := firstInput.Type
+		expectedType := *firstInput.Type
 
 		var finalTableRows [][]core.FlowValueField
 		for i := range n.InputPorts {
@@ -160,16 +162,33 @@ func (a *ConcatTablesAction) RunContext(ctx context.Context, n *core.Node) <-cha
 				return
 			}
 
-			if err := core.Typecheck(*input.Type, *expectedType); err != nil {
+			// Tables loaded from files with and without empty cells differ
+			// only in which columns may be null, and such columns are
+			// nullable in the result.
+			if err := core.Typecheck(*input.Type, withNullableColumns(expectedType)); err != nil {
 				res.Err = fmt.Errorf("all tables should have the same type: expected %s but got %s", expectedType, input.Type)
 				return
 			}
-			finalTableRows = append(finalTableRows, input.TableValue...)
+			expectedType = mergeNullableColumns(expectedType, *input.Type)
+			// Later tables may have the columns in another order, or more of
+			// them, so take the columns of the first table by name.
+			for j, row := range input.TableRows() {
+				if expectedType.ContainedType != nil && expectedType.ContainedType.Kind == core.FSKindRecord {
+					columns := expectedType.ContainedType.Fields
+					projected, ok := core.ProjectFields(row, columns)
+					if !ok {
+						res.Err = fmt.Errorf("%s: row %d is missing column %q", n.InputPorts[i].Name, j, missingColumn(row, columns))
+						return
+					}
+					row = projected
+				}
+				finalTableRows = append(finalTableRows, row)
+			}
 		}
 
 		res = core.NodeActionResult{
 			Outputs: []core.FlowValue{{
-				Type:       expectedType,
+				Type:       &expectedType,
 				TableValue: finalTableRows,
 			}},
 		}
@@ -177,6 +196,50 @@ func (a *ConcatTablesAction) RunContext(ctx context.Context, n *core.Node) <-cha
 	return done
 }
 
+// missingColumn returns the name of the first of columns that row lacks.
+func missingColumn(row []core.FlowValueField, columns []core.FlowField) string {
+	for _, c := range columns {
+		if _, ok := core.RowField(row, c.Name); !ok {
+			return c.Name
+		}
+	}
+	return ""
+}
+
+// withNullableColumns returns the table type t with every column nullable.
+func withNullableColumns(t core.FlowType) core.FlowType {
+	if t.ContainedType == nil || t.ContainedType.Kind != core.FSKindRecord {
+		return t
+	}
+	row := *t.ContainedType
+	row.Fields = slices.Clone(row.Fields)
+	for i, field := range row.Fields {
+		fieldType := core.NullableType(*field.Type)
+		row.Fields[i].Type = &fieldType
+	}
+	t.ContainedType = &row
+	return t
+}
+
+// mergeNullableColumns returns the table type t with each column nullable if
+// the column of the same name in other is.
+func mergeNullableColumns(t, other core.FlowType) core.FlowType {
+	if t.ContainedType == nil || t.ContainedType.Kind != core.FSKindRecord || other.ContainedType == nil {
+		return t
+	}
+	row := *t.ContainedType
+	row.Fields = slices.Clone(row.Fields)
+	for i, field := range row.Fields {
+		j := other.ContainedType.FieldIndex(field.Name)
+		if j >= 0 && other.ContainedType.Fields[j].Type.Nullable && !field.Type.Nullable {
+			fieldType := core.NullableType(*field.Type)
+			row.Fields[i].Type = &fieldType
+		}
+	}
+	t.ContainedType = &row
+	return t
+}
+
 func (a *ConcatTablesAction) Run(n *core.Node) <-chan core.NodeActionResult {
 	return a.RunContext(context.Background(), n)
 }
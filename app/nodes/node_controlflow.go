@@ -117,6 +117,7 @@ func NewMergeNode() *core.Node {
 var _ core.NodeAction = &MergeAction{}
 
 func (a *MergeAction) UpdateAndValidate(n *core.Node) {
+	n.HandleSkippedInputs = true // not serialized, so restore it after loading
 	n.Valid = true
 }
 
@@ -191,3 +192,95 @@ func (a *MergeAction) RunContext(ctx context.Context, n *core.Node) <-chan core.
 func (a *MergeAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
+
+// --- Try core.Node ---
+
+// GEN:NodeAction
+type TryAction struct{}
+
+func NewTryNode() *core.Node {
+	return &core.Node{
+		Name:               "Try",
+		HandleFailedInputs: true,
+		InputPorts: []core.NodePort{
+			{Name: "Value", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Value", Type: core.FlowType{Kind: core.FSKindAny}},
+			{Name: "Error", Type: *core.FSError},
+		},
+		Action: &TryAction{},
+	}
+}
+
+var _ core.NodeAction = &TryAction{}
+
+func (a *TryAction) UpdateAndValidate(n *core.Node) {
+	n.HandleFailedInputs = true // not serialized, so restore it after loading
+	n.Valid = n.InputIsWired(0)
+	if wire, ok := n.GetInputWire(0); ok {
+		n.OutputPorts[0].Type = wire.Type()
+	} else {
+		n.OutputPorts[0].Type = core.FlowType{Kind: core.FSKindAny}
+	}
+}
+
+func (a *TryAction) UI(n *core.Node) {
+	clay.CLAY(clay.IDI("TryNode", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		clay.TEXT("Passes 'Value', or 'Error' if it failed", clay.TextElementConfig{TextColor: core.LightGray})
+
+		core.UIInputPort(n, 0)
+		core.UIOutputPort(n, 0)
+		core.UIOutputPort(n, 1)
+	})
+}
+
+func (a *TryAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *TryAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+
+	go func() {
+		defer close(done)
+
+		// Exactly one of the two outputs is skipped, so downstream nodes can
+		// branch on success or failure like they do after Gate.
+		if err := n.GetInputError(0); err != nil {
+			upstream, _ := n.GetInputWire(0)
+			done <- core.NodeActionResult{
+				Outputs: []core.FlowValue{
+					{Type: &n.OutputPorts[0].Type, Skipped: true},
+					core.NewErrorValue(upstream.StartNode.String(), err, ""),
+				},
+			}
+			return
+		}
+
+		value, ok, err := n.GetInputValue(0)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+		if !ok {
+			done <- core.NodeActionResult{Err: fmt.Errorf("missing input")}
+			return
+		}
+
+		done <- core.NodeActionResult{
+			Outputs: []core.FlowValue{
+				value,
+				{Type: core.FSError, Skipped: true},
+			},
+		}
+	}()
+
+	return done
+}
+
+func (a *TryAction) Serialize(s *core.Serializer) bool {
+	return s.Ok()
+}
@@ -2,9 +2,11 @@
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/bvisness/flowshell/clay"
 	"github.com/bvisness/flowshell/util"
@@ -26,6 +28,8 @@ var convertOptions = []core.UIDropdownOption{
 	{Name: "Bytes (String)", Value: core.FSKindBytes},
 	{Name: "Int64", Value: core.FSKindInt64},
 	{Name: "Float64", Value: core.FSKindFloat64},
+	{Name: "Bool", Value: core.FSKindBool},
+	{Name: "Map", Value: core.FSKindMap},
 }
 
 func NewConvertNode() *core.Node {
@@ -105,7 +109,7 @@ func (c *ConvertAction) UpdateAndValidate(n *core.Node) {
 			copy(newFields, inputType.ContainedType.Fields)
 			for i, f := range newFields {
 				if f.Name == c.Column {
-					newFields[i].Type = &core.FlowType{Kind: c.TargetKind}
+					newFields[i].Type = &core.FlowType{Kind: c.TargetKind, Nullable: f.Type != nil && f.Type.Nullable}
 					break
 				}
 			}
@@ -118,7 +122,7 @@ func (c *ConvertAction) UpdateAndValidate(n *core.Node) {
 			}
 		} else {
 			// Scalar conversion
-			n.OutputPorts[0].Type = core.FlowType{Kind: c.TargetKind}
+			n.OutputPorts[0].Type = core.FlowType{Kind: c.TargetKind, Nullable: inputType.Nullable}
 		}
 	} else {
 		// Not wired, assume scalar
@@ -207,14 +211,8 @@ func (c *ConvertAction) RunContext(ctx context.Context, n *core.Node) <-chan cor
 			}
 
 			// Find column index
-			colIndex := -1
+			colIndex := input.Type.FieldIndex(c.Column)
 			fields := input.Type.ContainedType.Fields
-			for i, f := range fields {
-				if f.Name == c.Column {
-					colIndex = i
-					break
-				}
-			}
 			if colIndex == -1 {
 				res.Err = fmt.Errorf("column %s not found in input table", c.Column)
 				return
@@ -223,7 +221,7 @@ func (c *ConvertAction) RunContext(ctx context.Context, n *core.Node) <-chan cor
 			// Create new output type
 			newFields := make([]core.FlowField, len(fields))
 			copy(newFields, fields)
-			newFields[colIndex].Type = &core.FlowType{Kind: c.TargetKind}
+			newFields[colIndex].Type = &core.FlowType{Kind: c.TargetKind, Nullable: fields[colIndex].Type != nil && fields[colIndex].Type.Nullable}
 			outputType := &core.FlowType{
 				Kind: core.FSKindTable,
 				ContainedType: &core.FlowType{
@@ -234,8 +232,8 @@ func (c *ConvertAction) RunContext(ctx context.Context, n *core.Node) <-chan cor
 
 			// Iterate rows and convert specific column
 			var newRows [][]core.FlowValueField
-			rows := input.TableValue
-			for _, row := range rows {
+			rows := input.TableRows()
+			for i, row := range rows {
 				// Check context periodically
 				select {
 				case <-ctx.Done():
@@ -254,7 +252,7 @@ func (c *ConvertAction) RunContext(ctx context.Context, n *core.Node) <-chan cor
 						if c.IgnoreErrors {
 							convertedVal = ZeroValue(c.TargetKind)
 						} else {
-							res.Err = fmt.Errorf("conversion failed for value %v: %w", row[colIndex].Value, err)
+							res.Err = fmt.Errorf("conversion failed in row %d: %w", i, err)
 							return
 						}
 					}
@@ -303,12 +301,20 @@ func ZeroValue(kind core.FlowTypeKind) core.FlowValue {
 		return core.NewInt64Value(0, 0)
 	case core.FSKindFloat64:
 		return core.NewFloat64Value(0, 0)
+	case core.FSKindBool:
+		return core.NewBoolValue(false)
+	case core.FSKindMap:
+		return core.NewMapValue(core.FlowType{Kind: core.FSKindAny}, nil)
 	default:
 		return core.FlowValue{Type: &core.FlowType{Kind: kind}}
 	}
 }
 
+// ConvertValue converts v to the target kind. Null stays null.
 func ConvertValue(v core.FlowValue, target core.FlowTypeKind) (core.FlowValue, error) {
+	if v.Null {
+		return core.NewNullValue(core.FlowType{Kind: target}), nil
+	}
 	if v.Type.Kind == target {
 		return v, nil
 	}
@@ -332,6 +338,14 @@ func ConvertValue(v core.FlowValue, target core.FlowTypeKind) (core.FlowValue, e
 			str = strconv.FormatInt(v.Int64Value, 10)
 		case core.FSKindFloat64:
 			str = strconv.FormatFloat(v.Float64Value, 'f', -1, 64)
+		case core.FSKindBool:
+			str = strconv.FormatBool(v.BoolValue)
+		case core.FSKindMap:
+			b, err := json.Marshal(core.FlowValueToNative(v))
+			if err != nil {
+				return core.FlowValue{}, err
+			}
+			str = string(b)
 		default:
 			return core.FlowValue{}, fmt.Errorf("cannot convert %s to Bytes", v.Type)
 		}
@@ -349,7 +363,7 @@ func ConvertValue(v core.FlowValue, target core.FlowTypeKind) (core.FlowValue, e
 				if err2 == nil {
 					val = int64(f)
 				} else {
-					return core.FlowValue{}, err
+					return core.FlowValue{}, fmt.Errorf("cannot convert %s to Int64: %w", v, err)
 				}
 			}
 		case core.FSKindStream:
@@ -374,6 +388,10 @@ func ConvertValue(v core.FlowValue, target core.FlowTypeKind) (core.FlowValue, e
 			return v, nil
 		case core.FSKindFloat64:
 			val = int64(v.Float64Value)
+		case core.FSKindBool:
+			if v.BoolValue {
+				val = 1
+			}
 		default:
 			return core.FlowValue{}, fmt.Errorf("cannot convert %s to Int64", v.Type)
 		}
@@ -386,7 +404,7 @@ func ConvertValue(v core.FlowValue, target core.FlowTypeKind) (core.FlowValue, e
 		case core.FSKindBytes:
 			val, err = strconv.ParseFloat(string(v.BytesValue), 64)
 			if err != nil {
-				return core.FlowValue{}, err
+				return core.FlowValue{}, fmt.Errorf("cannot convert %s to Float64: %w", v, err)
 			}
 		case core.FSKindStream:
 			if v.StreamValue == nil {
@@ -405,10 +423,45 @@ func ConvertValue(v core.FlowValue, target core.FlowTypeKind) (core.FlowValue, e
 			val = float64(v.Int64Value)
 		case core.FSKindFloat64:
 			return v, nil
+		case core.FSKindBool:
+			if v.BoolValue {
+				val = 1
+			}
 		default:
 			return core.FlowValue{}, fmt.Errorf("cannot convert %s to Float64", v.Type)
 		}
 		return core.NewFloat64Value(val, 0), nil
+
+	case core.FSKindBool: // To Bool
+		switch v.Type.Kind {
+		case core.FSKindBytes:
+			val, err := strconv.ParseBool(strings.TrimSpace(string(v.BytesValue)))
+			if err != nil {
+				return core.FlowValue{}, fmt.Errorf("cannot convert %s to Bool: %w", v, err)
+			}
+			return core.NewBoolValue(val), nil
+		case core.FSKindInt64:
+			return core.NewBoolValue(v.Int64Value != 0), nil
+		case core.FSKindFloat64:
+			return core.NewBoolValue(v.Float64Value != 0), nil
+		default:
+			return core.FlowValue{}, fmt.Errorf("cannot convert %s to Bool", v.Type)
+		}
+
+	case core.FSKindMap: // To Map
+		switch v.Type.Kind {
+		case core.FSKindBytes:
+			// A JSON object, whose nested objects become maps too
+			var obj map[string]any
+			if err := json.Unmarshal(v.BytesValue, &obj); err != nil {
+				return core.FlowValue{}, fmt.Errorf("expected a JSON object: %v", err)
+			}
+			return core.NativeMapsToFlowValue(obj)
+		case core.FSKindRecord:
+			return core.MapFromEntries(v.RecordValue), nil
+		default:
+			return core.FlowValue{}, fmt.Errorf("cannot convert %s to Map", v.Type)
+		}
 	}
 
 	return core.FlowValue{}, fmt.Errorf("unsupported conversion target: %v", target)
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/bvisness/flowshell/util"
+
 	"github.com/antchfx/xmlquery"
 	"github.com/bvisness/flowshell/clay"
 	"github.com/tidwall/gjson"
@@ -13,9 +15,19 @@ import (
 
 // GEN:NodeAction
 type JsonQueryAction struct {
-	Query string
+	Query   string
+	Objects JsonObjectMode
 }
 
+// JsonObjectMode says what JSON Query makes of objects and arrays.
+type JsonObjectMode int
+
+const (
+	JsonObjectsAsText    JsonObjectMode = iota // the JSON text, as a string
+	JsonObjectsAsRecords                       // records, lists of records, etc.
+	JsonObjectsAsMaps                          // maps, for objects keyed by data, like IDs
+)
+
 func NewJsonQueryNode() *core.Node {
 	return &core.Node{
 		Name: "JSON Query",
@@ -58,6 +70,25 @@ func (a *JsonQueryAction) UI(n *core.Node) {
 				El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
 			})
 		})
+
+		clay.CLAY(clay.IDI("ObjectsRow", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildGap: core.S2, ChildAlignment: core.YCENTER},
+		}, func() {
+			clay.TEXT("Objects as:", clay.TextElementConfig{FontSize: 12, TextColor: core.LightGray})
+			for _, mode := range []struct {
+				L string
+				M JsonObjectMode
+			}{{"Text", JsonObjectsAsText}, {"Records", JsonObjectsAsRecords}, {"Maps", JsonObjectsAsMaps}} {
+				core.UIButton(clay.IDI("ObjectsBtn"+mode.L, n.ID), core.UIButtonConfig{
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						a.Objects = mode.M
+					},
+					El: clay.EL{BackgroundColor: util.Tern(a.Objects == mode.M, core.Blue, core.Charcoal)},
+				}, func() {
+					clay.TEXT(mode.L, clay.TextElementConfig{TextColor: core.White, FontSize: 14})
+				})
+			}
+		})
 	})
 }
 
@@ -85,13 +116,24 @@ func (a *JsonQueryAction) Run(n *core.Node) <-chan core.NodeActionResult {
 		case gjson.Number:
 			output = core.NewFloat64Value(res.Float(), 0)
 		case gjson.True:
-			output = core.NewInt64Value(1, 0)
+			output = core.NewBoolValue(true)
 		case gjson.False:
-			output = core.NewInt64Value(0, 0)
+			output = core.NewBoolValue(false)
 		case gjson.Null:
-			output = core.FlowValue{Type: &core.FlowType{Kind: core.FSKindAny}}
-		default: // JSON (Object/Array) -> treat as String
-			output = core.NewStringValue(res.String())
+			output = core.NewNullValue(core.FlowType{Kind: core.FSKindAny})
+		default: // JSON (Object/Array)
+			switch a.Objects {
+			case JsonObjectsAsRecords:
+				output, err = core.NativeToFlowValue(res.Value())
+			case JsonObjectsAsMaps:
+				output, err = core.NativeMapsToFlowValue(res.Value())
+			default:
+				output = core.NewStringValue(res.String())
+			}
+			if err != nil {
+				ch <- core.NodeActionResult{Err: err}
+				return
+			}
 		}
 
 		ch <- core.NodeActionResult{Outputs: []core.FlowValue{output}}
@@ -103,11 +145,24 @@ func (a *JsonQueryAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 	return a.Run(n)
 }
 
+// Since schema version 1, the object mode is saved.
+func (a *JsonQueryAction) SchemaVersion() int {
+	return 1
+}
+
 func (a *JsonQueryAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &a.Query)
+	core.SInt(s, &a.Objects)
 	return s.Ok()
 }
 
+func init() {
+	core.RegisterNodeActionUpgrade("JsonQueryAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		core.SStr(s, &a.(*JsonQueryAction).Query)
+		return s.Ok()
+	})
+}
+
 // GEN:NodeAction
 type XmlQueryAction struct {
 	XPath string
@@ -158,33 +158,14 @@ func (c *ExtractColumnAction) RunContext(ctx context.Context, n *core.Node) <-ch
 			return
 		}
 
-		colIdx := -1
-		var colType *core.FlowType
-		for i, field := range input.Type.ContainedType.Fields {
-			if field.Name == c.Column {
-				colIdx = i
-				colType = field.Type
-				break
-			}
-		}
-
+		colIdx := input.Type.FieldIndex(c.Column)
 		if colIdx == -1 {
 			res.Err = fmt.Errorf("column %q not found", c.Column)
 			return
 		}
+		colType := input.Type.ContainedType.Fields[colIdx].Type
 
-		var list []core.FlowValue
-		for _, row := range input.TableValue {
-			// Check context
-			select {
-			case <-ctx.Done():
-				res.Err = ctx.Err()
-				return
-			default:
-			}
-
-			list = append(list, row[colIdx].Value)
-		}
+		list := input.ColumnValues(colIdx)
 
 		res = core.NodeActionResult{
 			Outputs: []core.FlowValue{{
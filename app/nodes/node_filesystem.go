@@ -125,6 +125,10 @@ func (a *CopyFileAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+func (a *CopyFileAction) HasSideEffects() bool {
+	return true
+}
+
 // GEN:NodeAction
 type MoveFileAction struct{}
 
@@ -218,6 +222,10 @@ func (a *MoveFileAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+func (a *MoveFileAction) HasSideEffects() bool {
+	return true
+}
+
 // GEN:NodeAction
 type DeleteFileAction struct{}
 
@@ -292,6 +300,10 @@ func (a *DeleteFileAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+func (a *DeleteFileAction) HasSideEffects() bool {
+	return true
+}
+
 // GEN:NodeAction
 type MakeDirAction struct{}
 
@@ -365,3 +377,7 @@ func (a *MakeDirAction) RunContext(ctx context.Context, n *core.Node) <-chan cor
 func (a *MakeDirAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
+
+func (a *MakeDirAction) HasSideEffects() bool {
+	return true
+}
@@ -0,0 +1,144 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+	"github.com/bvisness/flowshell/util"
+)
+
+// Filter keeps the items of a list, or the rows of a table, for which a
+// predicate is true (see IsTruthy). The predicate is an expression that sees
+// the item as Input (see formulaEnv), or a subflow that gets the item on its
+// graph input and returns the predicate on its first output. The result has
+// the same type as the input.
+//
+// GEN:NodeAction
+type FilterAction struct {
+	Expression  string
+	SubflowPath string
+	UseSubflow  bool
+
+	subflow subflowFile
+}
+
+func NewFilterNode() *core.Node {
+	return &core.Node{
+		Name: "Filter",
+		InputPorts: []core.NodePort{
+			{Name: "Items", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Kept", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		Action: &FilterAction{Expression: "Input != \"\""},
+	}
+}
+
+var _ core.NodeAction = &FilterAction{}
+
+func (a *FilterAction) UpdateAndValidate(n *core.Node) {
+	n.Valid = n.InputIsWired(0) && itemsWireOk(n) && util.Tern(a.UseSubflow, a.SubflowPath, a.Expression) != ""
+	if wire, ok := n.GetInputWire(0); ok {
+		n.OutputPorts[0].Type = wire.Type()
+	} else {
+		n.OutputPorts[0].Type = core.FlowType{Kind: core.FSKindAny}
+	}
+}
+
+func (a *FilterAction) UI(n *core.Node) {
+	clay.CLAY(clay.IDI("FilterUI", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		clay.CLAY(clay.IDI("FilterRow1", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+		}, func() {
+			core.UIInputPort(n, 0)
+			core.UISpacer(clay.IDI("FilterSpacer1", n.ID), core.GROWH)
+			core.UIOutputPort(n, 0)
+		})
+		uiItemFunc(n, "Filter", &a.UseSubflow, &a.Expression, &a.SubflowPath)
+	})
+}
+
+func (a *FilterAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *FilterAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	go func() {
+		defer close(done)
+
+		val, ok, err := n.GetInputValue(0)
+		if err != nil || !ok {
+			done <- core.NodeActionResult{Err: fmt.Errorf("failed to get input: %v", err)}
+			return
+		}
+		items, err := mapItems(val)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+
+		f, err := newItemFunc(a.UseSubflow, a.Expression, &a.subflow, a.SubflowPath)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+		result := core.FlowValue{Type: val.Type}
+		var kept []int
+		for i, item := range items {
+			if ctx.Err() != nil {
+				done <- core.NodeActionResult{Err: ctx.Err()}
+				return
+			}
+			keep, err := f(ctx, map[string]core.FlowValue{"Input": item})
+			if err != nil {
+				done <- core.NodeActionResult{Err: fmt.Errorf("item %d: %v", i, err)}
+				return
+			}
+			if !IsTruthy(keep) {
+				continue
+			}
+			if val.Type.Kind == core.FSKindTable {
+				kept = append(kept, i)
+			} else {
+				result.ListValue = append(result.ListValue, item)
+			}
+		}
+		if val.Table != nil {
+			result.Table = val.Table.Take(kept)
+		} else {
+			for _, i := range kept {
+				result.TableValue = append(result.TableValue, val.TableValue[i])
+			}
+		}
+
+		done <- core.NodeActionResult{Outputs: []core.FlowValue{result}}
+	}()
+	return done
+}
+
+func (a *FilterAction) Serialize(s *core.Serializer) bool {
+	core.SStr(s, &a.Expression)
+	core.SStr(s, &a.SubflowPath)
+	core.SBool(s, &a.UseSubflow)
+	return s.Ok()
+}
+
+// A subflow is loaded from disk and may have changed.
+func (a *FilterAction) HasSideEffects() bool {
+	return a.UseSubflow
+}
+
+// With a subflow, Filter only waits on it, and its nodes are scheduled
+// themselves. An expression is evaluated by Filter itself, so it takes a slot.
+func (a *FilterAction) SchedCategory() string {
+	if a.UseSubflow {
+		return core.SchedCategoryCoordinator
+	}
+	return ""
+}
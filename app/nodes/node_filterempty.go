@@ -146,14 +146,7 @@ func (c *FilterEmptyAction) RunContext(ctx context.Context, n *core.Node) <-chan
 			return
 		}
 
-		colIdx := -1
-		for i, field := range input.Type.ContainedType.Fields {
-			if field.Name == c.Column {
-				colIdx = i
-				break
-			}
-		}
-
+		colIdx := input.Type.FieldIndex(c.Column)
 		if colIdx == -1 {
 			// If column not found, we can't filter.
 			// Return error.
@@ -161,8 +154,8 @@ func (c *FilterEmptyAction) RunContext(ctx context.Context, n *core.Node) <-chan
 			return
 		}
 
-		var newRows [][]core.FlowValueField
-		for _, row := range input.TableValue {
+		var kept []int
+		for i := range input.NumRows() {
 			select {
 			case <-ctx.Done():
 				res.Err = ctx.Err()
@@ -170,8 +163,8 @@ func (c *FilterEmptyAction) RunContext(ctx context.Context, n *core.Node) <-chan
 			default:
 			}
 
-			val := row[colIdx].Value
-			keep := true
+			val := input.Cell(i, colIdx)
+			keep := !val.Null
 
 			switch val.Type.Kind {
 			case core.FSKindBytes:
@@ -195,21 +188,26 @@ func (c *FilterEmptyAction) RunContext(ctx context.Context, n *core.Node) <-chan
 					keep = false
 				}
 			case core.FSKindTable:
-				if len(val.TableValue) == 0 {
+				if val.NumRows() == 0 {
 					keep = false
 				}
 			}
 
 			if keep {
-				newRows = append(newRows, row)
+				kept = append(kept, i)
 			}
 		}
 
+		output := core.FlowValue{Type: input.Type}
+		if input.Table != nil {
+			output.Table = input.Table.Take(kept)
+		} else {
+			for _, i := range kept {
+				output.TableValue = append(output.TableValue, input.TableValue[i])
+			}
+		}
 		res = core.NodeActionResult{
-			Outputs: []core.FlowValue{{
-				Type:       input.Type,
-				TableValue: newRows,
-			}},
+			Outputs: []core.FlowValue{output},
 		}
 	}()
 	return done
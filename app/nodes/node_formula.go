@@ -85,25 +85,7 @@ func (a *FormulaAction) Run(n *core.Node) <-chan core.NodeActionResult {
 
 		// Helper to evaluate single item
 		eval := func(item core.FlowValue) (core.FlowValue, error) {
-			env := make(map[string]any)
-
-			// Helper to access columns
-			env["col"] = func(name string) any {
-				// Access field from Record or Table Row
-				if item.Type.Kind == core.FSKindRecord {
-					// Scan fields
-					for i, field := range item.Type.Fields {
-						if field.Name == name {
-							return core.FlowValueToNative(item.RecordValue[i].Value)
-						}
-					}
-				}
-				return nil
-			}
-
-			env["Input"] = core.FlowValueToNative(item)
-
-			output, err := expr.Run(program, env)
+			output, err := expr.Run(program, formulaEnv(item))
 			if err != nil {
 				return core.FlowValue{}, err
 			}
@@ -120,22 +102,8 @@ func (a *FormulaAction) Run(n *core.Node) <-chan core.NodeActionResult {
 		case core.FSKindTable:
 			// Iterate rows
 			var resList []core.FlowValue
-			for _, row := range valInput.TableValue {
-				// Construct Record from Row for easier access?
-				// Or just pass the row and let helper handle it.
-				// A TableValue row is []core.FlowValueField.
-				// We need to wrap it into a fake core.FlowValue Record to reuse helper logic?
-				// Or just handle []core.FlowValueField directly.
-
-				recVal := core.FlowValue{
-					Type: &core.FlowType{
-						Kind:   core.FSKindRecord,
-						Fields: valInput.Type.ContainedType.Fields,
-					},
-					RecordValue: row,
-				}
-
-				outItem, err := eval(recVal)
+			for i := range valInput.NumRows() {
+				outItem, err := eval(valInput.Row(i))
 				if err != nil {
 					done <- core.NodeActionResult{Err: fmt.Errorf("eval error: %v", err)}
 					return
@@ -143,12 +111,7 @@ func (a *FormulaAction) Run(n *core.Node) <-chan core.NodeActionResult {
 				resList = append(resList, outItem)
 			}
 			// Result is List
-			// Determine contained type from first item? Or Any?
-			resType := core.FlowType{Kind: core.FSKindAny}
-			if len(resList) > 0 {
-				resType = *resList[0].Type
-			}
-			result = core.NewListValue(resType, resList)
+			result = core.NewListValue(core.CommonType(resList), resList)
 
 		case core.FSKindList:
 			// Map over list
@@ -161,11 +124,7 @@ func (a *FormulaAction) Run(n *core.Node) <-chan core.NodeActionResult {
 				}
 				resList = append(resList, outItem)
 			}
-			resType := core.FlowType{Kind: core.FSKindAny}
-			if len(resList) > 0 {
-				resType = *resList[0].Type
-			}
-			result = core.NewListValue(resType, resList)
+			result = core.NewListValue(core.CommonType(resList), resList)
 
 		default:
 			// Single Item
@@ -193,4 +152,37 @@ func (a *FormulaAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+// formulaEnv returns the variables of a formula evaluated on item: Input, the
+// item itself, col(name), which returns a field of the item if it is a
+// record (or a table row) or the entry for name if it is a map, and
+// asMap(object).
+func formulaEnv(item core.FlowValue) map[string]any {
+	env := make(map[string]any)
+
+	// Helper to access columns
+	env["col"] = func(name string) any {
+		// Access field from Record or Table Row
+		if item.Type.Kind == core.FSKindRecord {
+			if val, ok := item.Field(name); ok {
+				return core.FlowValueToNative(val)
+			}
+		}
+		if item.Type.Kind == core.FSKindMap {
+			if val, ok := item.MapEntry(name); ok {
+				return core.FlowValueToNative(val)
+			}
+		}
+		return nil
+	}
+
+	// Objects made in a formula become records, unless made into maps, for
+	// keys that are data rather than a schema. Maps that come in stay maps.
+	env["asMap"] = func(m map[string]any) core.NativeMap {
+		return core.NativeMap(m)
+	}
+
+	env["Input"] = core.FlowValueToNative(item)
+	return env
+}
+
 // core.FlowValueToNative moved to flowdata.go
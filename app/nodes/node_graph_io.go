@@ -3,6 +3,7 @@
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/bvisness/flowshell/clay"
 	"github.com/bvisness/flowshell/app/core"
@@ -10,6 +11,9 @@ import (
 
 // GEN:NodeAction
 type GraphInputAction struct {
+	// Identifies the input to callers, e.g. `flowshell run --input name=value`.
+	Name string
+
 	Value core.FlowValue
 }
 
@@ -25,13 +29,49 @@ func NewGraphInputNode() *core.Node {
 
 var _ core.NodeAction = &GraphInputAction{}
 
+// A graph input takes the type of the first input port it is wired to, so
+// that nodes calling the graph know what to pass.
 func (a *GraphInputAction) UpdateAndValidate(n *core.Node) {
 	n.Valid = true
+
+	n.OutputPorts[0].Type = core.FlowType{Kind: core.FSKindAny}
+	if n.Graph != nil {
+		for _, wire := range n.Graph.Wires {
+			if wire.StartNode == n {
+				n.OutputPorts[0].Type = wire.EndNode.InputPorts[wire.EndPort].Type
+				break
+			}
+		}
+	}
+}
+
+func init() {
+	// Names were added in file versions 8 and 9, before actions had schema
+	// versions.
+	core.RegisterNodeActionUpgrade("GraphInputAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		if s.Version >= 8 {
+			core.SStr(s, &a.(*GraphInputAction).Name)
+		}
+		return s.Ok()
+	})
+	core.RegisterNodeActionUpgrade("GraphOutputAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		if s.Version >= 9 {
+			core.SStr(s, &a.(*GraphOutputAction).Name)
+		}
+		return s.Ok()
+	})
 }
 
 func (a *GraphInputAction) UI(n *core.Node) {
-	clay.TEXT("core.Graph Input", clay.TextElementConfig{TextColor: core.White})
-	core.UIOutputPort(n, 0)
+	clay.CLAY(clay.IDI("GraphInputRow", n.ID), clay.EL{
+		Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER, ChildGap: core.S2},
+	}, func() {
+		clay.TEXT("Name:", clay.TextElementConfig{TextColor: core.White})
+		core.UITextBox(clay.IDI("GraphInputName", n.ID), &a.Name, core.UITextBoxConfig{
+			El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+		})
+		core.UIOutputPort(n, 0)
+	})
 }
 
 func (a *GraphInputAction) Run(n *core.Node) <-chan core.NodeActionResult {
@@ -40,19 +80,54 @@ func (a *GraphInputAction) Run(n *core.Node) <-chan core.NodeActionResult {
 
 func (a *GraphInputAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
 	done := make(chan core.NodeActionResult, 1)
-	done <- core.NodeActionResult{
-		Outputs: []core.FlowValue{a.Value},
+	if a.Value.Type == nil {
+		done <- core.NodeActionResult{Err: fmt.Errorf("no value provided for graph input %q", a.Name)}
+	} else {
+		done <- core.NodeActionResult{
+			Outputs: []core.FlowValue{a.Value},
+		}
 	}
 	close(done)
 	return done
 }
 
+// Since schema version 1, the name is always saved.
+func (a *GraphInputAction) SchemaVersion() int {
+	return 1
+}
+
 func (a *GraphInputAction) Serialize(s *core.Serializer) bool {
+	core.SStr(s, &a.Name)
 	return s.Ok()
 }
 
+// SetGraphInput sets the value of every graph input in g with the given name.
+// It fails if there is no such input.
+func SetGraphInput(g *core.Graph, name string, value core.FlowValue) error {
+	found := false
+	for _, n := range g.Nodes {
+		if input, ok := n.Action.(*GraphInputAction); ok && input.Name == name {
+			input.Value = value
+			n.ClearResult()
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("graph has no input named %q", name)
+	}
+	return nil
+}
+
+// Value is set from outside the graph (e.g. by Map) and is not serialized,
+// so it is invisible to the result key.
+func (a *GraphInputAction) HasSideEffects() bool {
+	return true
+}
+
 // GEN:NodeAction
 type GraphOutputAction struct {
+	// Identifies the output to callers, e.g. in `flowshell run --output-format json`.
+	Name string
 }
 
 func NewGraphOutputNode() *core.Node {
@@ -70,14 +145,27 @@ func NewGraphOutputNode() *core.Node {
 
 var _ core.NodeAction = &GraphOutputAction{}
 
+// A graph output takes the type of the value wired into it.
 func (a *GraphOutputAction) UpdateAndValidate(n *core.Node) {
 	n.Valid = n.InputIsWired(0)
+
+	n.OutputPorts[0].Type = core.FlowType{Kind: core.FSKindAny}
+	if wire, ok := n.GetInputWire(0); ok {
+		n.OutputPorts[0].Type = wire.Type()
+	}
 }
 
 func (a *GraphOutputAction) UI(n *core.Node) {
-	core.UIInputPort(n, 0)
-	clay.TEXT("core.Graph Output", clay.TextElementConfig{TextColor: core.White})
-	core.UIOutputPort(n, 0)
+	clay.CLAY(clay.IDI("GraphOutputRow", n.ID), clay.EL{
+		Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER, ChildGap: core.S2},
+	}, func() {
+		core.UIInputPort(n, 0)
+		clay.TEXT("Name:", clay.TextElementConfig{TextColor: core.White})
+		core.UITextBox(clay.IDI("GraphOutputName", n.ID), &a.Name, core.UITextBoxConfig{
+			El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+		})
+		core.UIOutputPort(n, 0)
+	})
 }
 
 func (a *GraphOutputAction) Run(n *core.Node) <-chan core.NodeActionResult {
@@ -100,6 +188,167 @@ func (a *GraphOutputAction) RunContext(ctx context.Context, n *core.Node) <-chan
 	return done
 }
 
+// Since schema version 1, the name is always saved.
+func (a *GraphOutputAction) SchemaVersion() int {
+	return 1
+}
+
 func (a *GraphOutputAction) Serialize(s *core.Serializer) bool {
+	core.SStr(s, &a.Name)
 	return s.Ok()
 }
+
+// A GraphSignature lists the inputs and outputs of a graph that is run like a
+// function, as by Subgraph, Call Flow, and Map nodes. Graph inputs with the
+// same name share a parameter, so they all get the same value.
+type GraphSignature struct {
+	Inputs  []GraphParam
+	Outputs []GraphParam
+}
+
+type GraphParam struct {
+	Port core.NodePort
+
+	// The Graph Input nodes set from this parameter, or the Graph Output node
+	// it is read from.
+	Nodes []*core.Node
+}
+
+// NewGraphSignature finds the graph inputs and outputs of g, in the order
+// they appear in the graph. If namedOnly is set, unnamed inputs and outputs
+// are left out; otherwise they get a parameter each, with a numbered name.
+// Types are those inferred by UpdateAndValidate.
+func NewGraphSignature(g *core.Graph, namedOnly bool) GraphSignature {
+	var sig GraphSignature
+	inputIndex := make(map[string]int)
+	outputNames := make(map[string]bool)
+	for _, n := range g.Nodes {
+		switch action := n.Action.(type) {
+		case *GraphInputAction:
+			name := action.Name
+			if name == "" {
+				if namedOnly {
+					continue
+				}
+				name = fmt.Sprintf("Input %d", len(sig.Inputs)+1)
+			} else if i, ok := inputIndex[name]; ok {
+				sig.Inputs[i].Nodes = append(sig.Inputs[i].Nodes, n)
+				continue
+			}
+			inputIndex[name] = len(sig.Inputs)
+			sig.Inputs = append(sig.Inputs, GraphParam{
+				Port:  core.NodePort{Name: name, Type: n.OutputPorts[0].Type},
+				Nodes: []*core.Node{n},
+			})
+		case *GraphOutputAction:
+			name := action.Name
+			if name == "" {
+				if namedOnly {
+					continue
+				}
+				name = fmt.Sprintf("Output %d", len(sig.Outputs)+1)
+			} else if outputNames[name] {
+				continue
+			}
+			outputNames[name] = true
+			sig.Outputs = append(sig.Outputs, GraphParam{
+				Port:  core.NodePort{Name: name, Type: n.OutputPorts[0].Type},
+				Nodes: []*core.Node{n},
+			})
+		}
+	}
+	return sig
+}
+
+// Ports returns the ports of a node that calls the graph, not counting an
+// error port.
+func (sig GraphSignature) Ports() (inputs, outputs []core.NodePort) {
+	for _, param := range sig.Inputs {
+		inputs = append(inputs, param.Port)
+	}
+	for _, param := range sig.Outputs {
+		outputs = append(outputs, param.Port)
+	}
+	return inputs, outputs
+}
+
+// Call sets the graph inputs to args, in the order of sig.Inputs, runs the
+// graph outputs, and returns their values in the order of sig.Outputs.
+func (sig GraphSignature) Call(ctx context.Context, args []core.FlowValue) ([]core.FlowValue, error) {
+	if len(args) != len(sig.Inputs) {
+		return nil, fmt.Errorf("expected %d arguments, got %d", len(sig.Inputs), len(args))
+	}
+	for i, param := range sig.Inputs {
+		for _, in := range param.Nodes {
+			in.Action.(*GraphInputAction).Value = args[i]
+			in.ClearResult()
+		}
+	}
+
+	// Outputs share upstream nodes, which run only once at a time.
+	runs := make([]<-chan struct{}, len(sig.Outputs))
+	for i, param := range sig.Outputs {
+		runs[i] = param.Nodes[0].Run(ctx, true)
+	}
+
+	results := make([]core.FlowValue, len(sig.Outputs))
+	for i, param := range sig.Outputs {
+		select {
+		case <-runs[i]:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		res, ok := param.Nodes[0].GetResult()
+		if !ok {
+			return nil, fmt.Errorf("output %q produced no result", param.Port.Name)
+		}
+		if res.Err != nil {
+			return nil, fmt.Errorf("output %q: %w", param.Port.Name, res.Err)
+		}
+		results[i] = res.Outputs[0]
+	}
+	return results, nil
+}
+
+// setFunctionPorts gives a node that calls a graph the ports of its
+// signature. Wires to ports that no longer exist are removed, and the error
+// port stays last.
+func setFunctionPorts(n *core.Node, inputs, outputs []core.NodePort) {
+	errPort := n.ErrorPortIndex()
+	if n.Graph != nil {
+		n.Graph.Wires = slices.DeleteFunc(n.Graph.Wires, func(w *core.Wire) bool {
+			return w.EndNode == n && w.EndPort >= len(inputs) ||
+				w.StartNode == n && w.StartPort != errPort && w.StartPort >= len(outputs)
+		})
+		for _, w := range n.Graph.Wires {
+			if w.StartNode == n && w.StartPort == errPort {
+				w.StartPort = len(outputs)
+			}
+		}
+	}
+	if n.ErrorPort {
+		outputs = append(outputs, n.OutputPorts[errPort])
+	}
+	if len(inputs) != len(n.InputPorts) || len(outputs) != len(n.OutputPorts) {
+		n.ClearResult()
+	}
+	n.InputPorts = inputs
+	n.OutputPorts = outputs
+}
+
+// inputValues returns the values on all of n's input ports, which must all
+// be wired.
+func inputValues(n *core.Node) ([]core.FlowValue, error) {
+	values := make([]core.FlowValue, len(n.InputPorts))
+	for i := range n.InputPorts {
+		val, ok, err := n.GetInputValue(i)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("missing input %q", n.InputPorts[i].Name)
+		}
+		values[i] = val
+	}
+	return values, nil
+}
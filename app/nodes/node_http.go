@@ -207,3 +207,27 @@ func (a *HTTPRequestAction) RunContext(ctx context.Context, n *core.Node) <-chan
 func (a *HTTPRequestAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
+
+// Only GET and HEAD requests are safe to serve from the cache. Endpoints whose
+// responses change between runs can opt out with the node's NoCache flag.
+func (a *HTTPRequestAction) CacheKey(n *core.Node) ([]byte, bool) {
+	method := "GET"
+	if valMethod, wired, err := n.GetInputValue(1); err != nil {
+		return nil, false
+	} else if wired && len(valMethod.BytesValue) > 0 {
+		method = strings.ToUpper(string(valMethod.BytesValue))
+	}
+	return nil, method == "GET" || method == "HEAD"
+}
+
+func (a *HTTPRequestAction) SchedCategory() string {
+	return core.SchedCategoryHTTP
+}
+
+// The HTTP status.
+func (a *HTTPRequestAction) ResultCode(res core.NodeActionResult) (int, bool) {
+	if len(res.Outputs) < 1 {
+		return 0, false
+	}
+	return int(res.Outputs[0].Int64Value), true
+}
@@ -130,4 +130,9 @@ func (c *ListFilesAction) Run(n *core.Node) <-chan core.NodeActionResult {
 func (n *ListFilesAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &n.Dir)
 	return s.Ok()
+}
+
+// Directory contents can change between runs.
+func (n *ListFilesAction) HasSideEffects() bool {
+	return true
 }
\ No newline at end of file
@@ -26,6 +26,11 @@ type LoadFileAction struct {
 	// as strings (Bytes). Users can then use the "Convert Type" node to
 	// manually convert specific columns if needed.
 	InferTypes bool
+
+	// NullEmptyCells makes empty cells in inferred columns null, and also
+	// infers Bool columns. Nodes saved before schema version 1 leave it off,
+	// so empty cells in their numeric columns still load as 0.
+	NullEmptyCells bool
 }
 
 const maxLoadFileBytes int64 = 256 << 20
@@ -83,9 +88,10 @@ func NewLoadFileNode(path string) *core.Node {
 		}},
 
 		Action: &LoadFileAction{
-			Path:       path,
-			Format:     formatDropdown,
-			InferTypes: true,
+			Path:           path,
+			Format:         formatDropdown,
+			InferTypes:     true,
+			NullEmptyCells: true,
 		},
 	}
 }
@@ -233,7 +239,6 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 		}
 
 		var paths []string
-		fmt.Printf("LoadFile: Starting execution\n")
 		wireVal, hasWire, err := n.GetInputValue(0)
 		if err != nil {
 			res.Err = err
@@ -267,7 +272,6 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 
 		switch format := c.Format.GetSelectedOption().Value; format {
 		case "raw":
-			fmt.Printf("LoadFile: Mode RAW\n")
 			var outputs []core.FlowValue
 			for _, path := range paths {
 				// Check context
@@ -338,12 +342,13 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 			}
 
 		case "csv":
-			fmt.Printf("LoadFile: Mode CSV, paths: %v\n", paths)
 			var allHeader []string
 			var allDataRows [][]string
 			var colIsInt []bool
 			var colIsFloat []bool
+			var colIsBool []bool
 			var colSeenNonEmpty []bool
+			var colHasEmpty []bool
 
 			for i, path := range paths {
 				// Check context
@@ -354,11 +359,9 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 
 				f, err := os.Open(path)
 				if err != nil {
-					fmt.Printf("LoadFile: Failed to open %s: %v\n", path, err)
 					res.Err = fmt.Errorf("failed to open %s: %w", path, err)
 					return
 				}
-				fmt.Printf("LoadFile: Opened %s\n", path)
 				r := csv.NewReader(f)
 				r.FieldsPerRecord = -1
 
@@ -381,10 +384,13 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 					allHeader = header
 					colIsInt = make([]bool, len(allHeader))
 					colIsFloat = make([]bool, len(allHeader))
+					colIsBool = make([]bool, len(allHeader))
 					colSeenNonEmpty = make([]bool, len(allHeader))
+					colHasEmpty = make([]bool, len(allHeader))
 					for j := range allHeader {
 						colIsInt[j] = true
 						colIsFloat[j] = true
+						colIsBool[j] = c.NullEmptyCells
 					}
 				} else {
 					if len(header) != len(allHeader) {
@@ -419,10 +425,12 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 					if c.InferTypes {
 						for col := 0; col < len(allHeader); col++ {
 							if col >= len(row) {
+								colHasEmpty[col] = true
 								continue
 							}
 							val := row[col]
 							if val == "" {
+								colHasEmpty[col] = true
 								continue
 							}
 							colSeenNonEmpty[col] = true
@@ -436,6 +444,11 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 									colIsFloat[col] = false
 								}
 							}
+							if colIsBool[col] {
+								if _, ok := parseCSVBool(val); !ok {
+									colIsBool[col] = false
+								}
+							}
 						}
 					}
 				}
@@ -475,21 +488,24 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 						colTypes[col] = core.FSKindInt64
 					} else if colIsFloat[col] {
 						colTypes[col] = core.FSKindFloat64
+					} else if colIsBool[col] {
+						colTypes[col] = core.FSKindBool
 					}
 				}
 			}
 
-			// Build schema
+			// Build schema. Empty cells in typed columns are null, or 0 without
+			// NullEmptyCells; text columns keep them as empty strings.
 			tableRecordType := core.FlowType{Kind: core.FSKindRecord}
 			for i, headerField := range allHeader {
 				tableRecordType.Fields = append(tableRecordType.Fields, core.FlowField{
 					Name: headerField,
-					Type: &core.FlowType{Kind: colTypes[i]},
+					Type: &core.FlowType{Kind: colTypes[i], Nullable: c.NullEmptyCells && colTypes[i] != core.FSKindBytes && colHasEmpty[i]},
 				})
 			}
 
-			// Build rows
-			var tableRows [][]core.FlowValueField
+			// Build columns
+			table := core.NewTable(tableRecordType.Fields)
 			for _, row := range allDataRows {
 				select {
 				case <-ctx.Done():
@@ -498,70 +514,33 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 				default:
 				}
 
-				var flowRow []core.FlowValueField
-				for col, value := range row {
-					if col >= numCols {
-						continue
-					}
-
-					var flowValue core.FlowValue
-					switch colTypes[col] {
-					case core.FSKindInt64:
-						if value == "" {
-							flowValue = core.NewInt64Value(0, 0)
-						} else {
-							val, _ := strconv.ParseInt(value, 10, 64)
-							flowValue = core.NewInt64Value(val, 0)
-						}
-					case core.FSKindFloat64:
-						if value == "" {
-							flowValue = core.NewFloat64Value(0, 0)
-						} else {
-							val, _ := strconv.ParseFloat(value, 64)
-							flowValue = core.NewFloat64Value(val, 0)
-						}
-					default:
-						flowValue = core.NewStringValue(value)
+				for col, column := range table.Columns {
+					value := ""
+					if col < len(row) {
+						value = row[col]
 					}
-
-					flowRow = append(flowRow, core.FlowValueField{
-						Name:  allHeader[col],
-						Value: flowValue,
-					})
-				}
-
-				// Fill missing
-				if len(row) < numCols {
-					for col := len(row); col < numCols; col++ {
-						var flowValue core.FlowValue
-						switch colTypes[col] {
-						case core.FSKindInt64:
-							flowValue = core.NewInt64Value(0, 0)
-						case core.FSKindFloat64:
-							flowValue = core.NewFloat64Value(0, 0)
-						default:
-							flowValue = core.NewStringValue("")
-						}
-						flowRow = append(flowRow, core.FlowValueField{
-							Name:  allHeader[col],
-							Value: flowValue,
-						})
+					switch {
+					case colTypes[col] == core.FSKindBytes:
+						column.AppendString(value)
+					case value == "" && c.NullEmptyCells:
+						column.AppendNull()
+					case colTypes[col] == core.FSKindInt64:
+						val, _ := strconv.ParseInt(value, 10, 64)
+						column.AppendInt64(val)
+					case colTypes[col] == core.FSKindFloat64:
+						val, _ := strconv.ParseFloat(value, 64)
+						column.AppendFloat64(val)
+					case colTypes[col] == core.FSKindBool:
+						val, _ := parseCSVBool(value)
+						column.AppendBool(val)
 					}
 				}
-
-				tableRows = append(tableRows, flowRow)
+				table.EndRow()
 			}
 
 			res = core.NodeActionResult{
-				Outputs: []core.FlowValue{{
-					Type: &core.FlowType{
-						Kind:          core.FSKindTable,
-						ContainedType: &tableRecordType,
-					},
-					TableValue: tableRows,
-				}},
+				Outputs: []core.FlowValue{core.NewTableValue(table)},
 			}
-			fmt.Printf("LoadFile: CSV done, rows: %d\n", len(tableRows))
 		case "json":
 			var outputs []core.FlowValue
 			for _, path := range paths {
@@ -613,11 +592,41 @@ func (c *LoadFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 	return done
 }
 
+// parseCSVBool parses the words true and false, in any case. Other spellings
+// that strconv.ParseBool accepts, like 1 and 0, are left to numeric columns.
+func parseCSVBool(s string) (bool, bool) {
+	switch {
+	case strings.EqualFold(s, "true"):
+		return true, true
+	case strings.EqualFold(s, "false"):
+		return false, true
+	}
+	return false, false
+}
+
 func (c *LoadFileAction) Run(n *core.Node) <-chan core.NodeActionResult {
 	return c.RunContext(context.Background(), n)
 }
 
+// Since schema version 1, NullEmptyCells is saved.
+func (c *LoadFileAction) SchemaVersion() int {
+	return 1
+}
+
 func (c *LoadFileAction) Serialize(s *core.Serializer) bool {
+	c.serializeV0(s)
+	core.SBool(s, &c.NullEmptyCells)
+	return s.Ok()
+}
+
+func init() {
+	core.RegisterNodeActionUpgrade("LoadFileAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		return a.(*LoadFileAction).serializeV0(s)
+	})
+}
+
+// serializeV0 reads or writes the settings as of schema version 0.
+func (c *LoadFileAction) serializeV0(s *core.Serializer) bool {
 	core.SStr(s, &c.Path)
 	core.SBool(s, &c.InferTypes)
 
@@ -639,3 +648,32 @@ func (c *LoadFileAction) Serialize(s *core.Serializer) bool {
 
 	return s.Ok()
 }
+
+// Fingerprint the files being loaded so that edits on disk invalidate cached
+// results.
+func (c *LoadFileAction) CacheKey(n *core.Node) ([]byte, bool) {
+	paths := []string{c.Path}
+	if wireVal, hasWire, err := n.GetInputValue(0); err != nil {
+		return nil, false
+	} else if hasWire {
+		paths = nil
+		switch wireVal.Type.Kind {
+		case core.FSKindBytes:
+			paths = append(paths, string(wireVal.BytesValue))
+		case core.FSKindList:
+			for _, v := range wireVal.ListValue {
+				paths = append(paths, string(v.BytesValue))
+			}
+		}
+	}
+
+	var key []byte
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, false
+		}
+		key = fmt.Appendf(key, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return key, true
+}
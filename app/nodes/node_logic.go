@@ -107,11 +107,13 @@ func (a *IfElseAction) Serialize(s *core.Serializer) bool {
 }
 
 func IsTruthy(v core.FlowValue) bool {
-	if v.Skipped {
+	if v.Skipped || v.Null {
 		return false
 	}
 
 	switch v.Type.Kind {
+	case core.FSKindBool:
+		return v.BoolValue
 	case core.FSKindInt64:
 		return v.Int64Value != 0
 	case core.FSKindFloat64:
@@ -121,7 +123,9 @@ func IsTruthy(v core.FlowValue) bool {
 	case core.FSKindList:
 		return len(v.ListValue) > 0
 	case core.FSKindTable:
-		return len(v.TableValue) > 0
+		return v.NumRows() > 0
+	case core.FSKindMap:
+		return len(v.MapValue) > 0
 	}
 	return true // Default true for other types?
 }
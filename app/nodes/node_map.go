@@ -4,22 +4,38 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/bvisness/flowshell/clay"
 	"github.com/bvisness/flowshell/app/core"
 )
 
+// Map runs a subflow once for each item of a list, or each row of a table,
+// and collects the results in order. Items run on up to Parallelism copies
+// of the subflow at once; 0 means one per CPU.
+//
 // GEN:NodeAction
 type MapAction struct {
 	SubflowPath string
+	Parallelism int
+
+	// CachedGraph is the subflow last loaded from SubflowPath. If it is set
+	// while SubflowPath is empty, it is used as the subflow as-is.
 	CachedGraph *core.Graph
+
+	subflow         subflowFile
+	parallelismText string
 }
 
 func NewMapNode() *core.Node {
 	return &core.Node{
 		Name: "Map",
 		InputPorts: []core.NodePort{
-			{Name: "List", Type: core.FlowType{Kind: core.FSKindList}},
+			{Name: "Items", Type: core.FlowType{Kind: core.FSKindAny}},
 		},
 		OutputPorts: []core.NodePort{
 			{Name: "Result", Type: core.FlowType{Kind: core.FSKindList}},
@@ -31,7 +47,17 @@ func NewMapNode() *core.Node {
 var _ core.NodeAction = &MapAction{}
 
 func (a *MapAction) UpdateAndValidate(n *core.Node) {
+	// Older flows have a List port here.
+	n.InputPorts[0] = core.NodePort{Name: "Items", Type: core.FlowType{Kind: core.FSKindAny}}
+
 	n.Valid = a.SubflowPath != "" && n.InputIsWired(0)
+	if wire, ok := n.GetInputWire(0); ok {
+		switch wire.Type().Kind {
+		case core.FSKindList, core.FSKindTable, core.FSKindAny:
+		default:
+			n.Valid = false
+		}
+	}
 }
 
 func (a *MapAction) UI(n *core.Node) {
@@ -41,9 +67,83 @@ func (a *MapAction) UI(n *core.Node) {
 	core.UITextBox(clay.IDI("SubflowPath", n.ID), &a.SubflowPath, core.UITextBoxConfig{
 		El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
 	})
+	clay.TEXT("Parallelism (0 = one per CPU):", clay.TextElementConfig{TextColor: core.Gray})
+	parallelismID := clay.IDI("MapParallelism", n.ID)
+	if !core.IsFocused(parallelismID) {
+		a.parallelismText = strconv.Itoa(a.Parallelism)
+	}
+	core.UITextBox(parallelismID, &a.parallelismText, core.UITextBoxConfig{
+		El: clay.EL{Layout: clay.LAY{Sizing: clay.Sizing{Width: clay.SizingFixed(80)}}},
+		OnSubmit: func(val string) {
+			if p, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && p >= 0 {
+				a.Parallelism = p
+			}
+		},
+	})
 	core.UIOutputPort(n, 0)
 }
 
+func (a *MapAction) loadGraph() (*core.Graph, error) {
+	if a.SubflowPath == "" && a.CachedGraph != nil {
+		return a.CachedGraph, nil
+	}
+	g, err := a.subflow.load(a.SubflowPath)
+	if err != nil {
+		return nil, err
+	}
+	a.CachedGraph = g
+	return g, nil
+}
+
+// A subflowFile is a flow used by a node as a function, which is read again
+// whenever its path changes or the file is modified.
+type subflowFile struct {
+	mu         sync.Mutex
+	graph      *core.Graph
+	loadedPath string
+	modTime    time.Time
+}
+
+func (f *subflowFile) load(path string) (*core.Graph, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if path == "" {
+		return nil, fmt.Errorf("no subflow")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subflow: %v", err)
+	}
+	if f.graph != nil && f.loadedPath == path && info.ModTime().Equal(f.modTime) {
+		return f.graph, nil
+	}
+
+	g, err := core.LoadGraph(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subflow: %v", err)
+	}
+	g.UpdateAndValidate() // infer the types of the graph inputs and outputs
+	f.graph, f.loadedPath, f.modTime = g, path, info.ModTime()
+	return g, nil
+}
+
+// mapItems returns the items of a list, or the rows of a table as records.
+func mapItems(val core.FlowValue) ([]core.FlowValue, error) {
+	switch val.Type.Kind {
+	case core.FSKindList:
+		return val.ListValue, nil
+	case core.FSKindTable:
+		items := make([]core.FlowValue, val.NumRows())
+		for i := range items {
+			items[i] = val.Row(i)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("input must be a list or a table, not %s", val.Type)
+	}
+}
+
 func (a *MapAction) Run(n *core.Node) <-chan core.NodeActionResult {
 	return a.RunContext(context.Background(), n)
 }
@@ -53,87 +153,98 @@ func (a *MapAction) RunContext(ctx context.Context, n *core.Node) <-chan core.No
 	go func() {
 		defer close(done)
 
-		// Get Input List
 		val, ok, err := n.GetInputValue(0)
 		if err != nil || !ok {
 			done <- core.NodeActionResult{Err: fmt.Errorf("failed to get input: %v", err)}
 			return
 		}
-		if val.Type.Kind != core.FSKindList {
-			done <- core.NodeActionResult{Err: fmt.Errorf("input must be a list")}
+		items, err := mapItems(val)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
 			return
 		}
-		list := val.ListValue
-
-		// Load core.Graph
-		if a.CachedGraph == nil {
-			data, err := os.ReadFile(a.SubflowPath)
-			if err != nil {
-				done <- core.NodeActionResult{Err: fmt.Errorf("failed to read subflow: %v", err)}
-				return
-			}
-			g, err := core.DeserializeGraph(data)
-			if err != nil {
-				done <- core.NodeActionResult{Err: fmt.Errorf("failed to parse subflow: %v", err)}
-				return
-			}
-			a.CachedGraph = g
-		}
 
-		// Find IO Nodes
-		var inputNode, outputNode *core.Node
-		for _, node := range a.CachedGraph.Nodes {
-			if _, ok := node.Action.(*GraphInputAction); ok {
-				inputNode = node
-			}
-			if _, ok := node.Action.(*GraphOutputAction); ok {
-				outputNode = node
-			}
+		g, err := a.loadGraph()
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
 		}
-
-		if inputNode == nil || outputNode == nil {
+		sig := NewGraphSignature(g, false)
+		if len(sig.Inputs) == 0 || len(sig.Outputs) == 0 {
 			done <- core.NodeActionResult{Err: fmt.Errorf("subflow missing GraphInput or GraphOutput node")}
 			return
 		}
 
-		// Process
-		results := make([]core.FlowValue, 0, len(list))
-		for _, item := range list {
-			// Check Context
-			if ctx.Err() != nil {
-				done <- core.NodeActionResult{Err: ctx.Err()}
+		workers := a.Parallelism
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		workers = max(1, min(workers, len(items)))
+
+		// Each worker runs its own copy of the subflow, since running an item
+		// sets the graph inputs and the results of every node in it.
+		sigs := make([]GraphSignature, workers)
+		for i := range sigs {
+			clone, err := core.CloneGraph(g)
+			if err != nil {
+				done <- core.NodeActionResult{Err: fmt.Errorf("failed to copy subflow: %v", err)}
 				return
 			}
+			sigs[i] = NewGraphSignature(clone, false)
+		}
 
-			// Set Input
-			inputAction := inputNode.Action.(*GraphInputAction)
-			inputAction.Value = item
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
 
-			// Run Subgraph
-			// We force rerun inputs to propagate the new value
-			outDoneCh := outputNode.Run(ctx, true)
-			
-			select {
-			case <-outDoneCh:
-				res, ok := outputNode.GetResult()
-				if !ok {
-					done <- core.NodeActionResult{Err: fmt.Errorf("subflow output node produced no result")}
-					return
-				}
-				if res.Err != nil {
-					done <- core.NodeActionResult{Err: fmt.Errorf("subflow error: %v", res.Err)}
-					return
-				}
-				if len(res.Outputs) > 0 {
-					results = append(results, res.Outputs[0])
-				} else {
-					results = append(results, core.FlowValue{}) // nil/void?
+		var firstErr error
+		var errOnce sync.Once
+		fail := func(err error) {
+			errOnce.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+
+		results := make([]core.FlowValue, len(items))
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for _, sig := range sigs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					args, err := mapArgs(sig, items[i])
+					if err != nil {
+						fail(fmt.Errorf("item %d: %v", i, err))
+						continue
+					}
+					outs, err := sig.Call(ctx, args)
+					if err != nil {
+						fail(fmt.Errorf("subflow error on item %d: %v", i, err))
+						continue
+					}
+					results[i] = mapResult(sig, outs)
 				}
+			}()
+		}
+	feed:
+		for i := range items {
+			select {
+			case next <- i:
 			case <-ctx.Done():
-				done <- core.NodeActionResult{Err: ctx.Err()}
-				return
+				break feed
 			}
 		}
+		close(next)
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			done <- core.NodeActionResult{Err: firstErr}
+			return
+		}
 
 		done <- core.NodeActionResult{
 			Outputs: []core.FlowValue{{Type: &core.FlowType{Kind: core.FSKindList}, ListValue: results}},
@@ -142,7 +253,66 @@ func (a *MapAction) RunContext(ctx context.Context, n *core.Node) <-chan core.No
 	return done
 }
 
+// A subflow with one input gets each item as-is. A subflow with several
+// inputs gets the fields of each item, which must be a record, by name.
+func mapArgs(sig GraphSignature, item core.FlowValue) ([]core.FlowValue, error) {
+	if len(sig.Inputs) == 1 {
+		return []core.FlowValue{item}, nil
+	}
+	if item.Type.Kind != core.FSKindRecord {
+		return nil, fmt.Errorf("subflow has %d inputs, so list items must be records", len(sig.Inputs))
+	}
+	args := make([]core.FlowValue, len(sig.Inputs))
+	for i, param := range sig.Inputs {
+		arg, ok := item.Field(param.Port.Name)
+		if !ok {
+			return nil, fmt.Errorf("list item has no field %q for subflow input %q", param.Port.Name, param.Port.Name)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+// A subflow with one output produces its value for each item. A subflow with
+// several outputs produces a record of them, with a field per output.
+func mapResult(sig GraphSignature, outs []core.FlowValue) core.FlowValue {
+	if len(outs) == 1 {
+		return outs[0]
+	}
+	fields := make([]core.FlowValueField, len(outs))
+	fieldTypes := make([]core.FlowField, len(outs))
+	for i, out := range outs {
+		fields[i] = core.FlowValueField{Name: sig.Outputs[i].Port.Name, Value: out}
+		fieldTypes[i] = core.FlowField{Name: sig.Outputs[i].Port.Name, Type: out.Type}
+	}
+	t := core.NewRecordType(fieldTypes)
+	return core.FlowValue{Type: &t, RecordValue: fields}
+}
+
+// Since schema version 1, the parallelism is saved.
+func (a *MapAction) SchemaVersion() int {
+	return 1
+}
+
 func (a *MapAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &a.SubflowPath)
+	core.SInt(s, &a.Parallelism)
 	return s.Ok()
+}
+
+func init() {
+	core.RegisterNodeActionUpgrade("MapAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		core.SStr(s, &a.(*MapAction).SubflowPath)
+		return s.Ok()
+	})
+}
+
+// The subflow is loaded from disk and may have changed.
+func (a *MapAction) HasSideEffects() bool {
+	return true
+}
+
+// Map only waits on the subflow, whose nodes are scheduled themselves.
+func (a *MapAction) SchedCategory() string {
+	return core.SchedCategoryCoordinator
 }
\ No newline at end of file
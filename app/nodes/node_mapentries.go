@@ -0,0 +1,213 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+)
+
+// Map Keys, Map Values and Map Entries take apart a map, in key order. Their
+// input is Any, so that maps from JSON Query, whose type is only known when
+// it runs, can be wired to them.
+
+// GEN:NodeAction
+type MapKeysAction struct{}
+
+func NewMapKeysNode() *core.Node {
+	return &core.Node{
+		Name: "Map Keys",
+		InputPorts: []core.NodePort{
+			{Name: "Map", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Keys", Type: core.NewListType(core.FlowType{Kind: core.FSKindBytes})},
+		},
+		Action: &MapKeysAction{},
+	}
+}
+
+var _ core.NodeAction = &MapKeysAction{}
+
+func (a *MapKeysAction) UpdateAndValidate(n *core.Node) {
+	n.Valid = n.InputIsWired(0) && mapWireOk(n)
+}
+
+func (a *MapKeysAction) UI(n *core.Node) {
+	uiMapEntries(n, "MapKeys")
+}
+
+func (a *MapKeysAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *MapKeysAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	return runMapEntries(n, func(m core.FlowValue) core.FlowValue {
+		keys := make([]core.FlowValue, len(m.MapValue))
+		for i, entry := range m.MapValue {
+			keys[i] = core.NewStringValue(entry.Name)
+		}
+		return core.NewListValue(core.FlowType{Kind: core.FSKindBytes}, keys)
+	})
+}
+
+func (a *MapKeysAction) Serialize(s *core.Serializer) bool {
+	return s.Ok()
+}
+
+// GEN:NodeAction
+type MapValuesAction struct{}
+
+func NewMapValuesNode() *core.Node {
+	return &core.Node{
+		Name: "Map Values",
+		InputPorts: []core.NodePort{
+			{Name: "Map", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Values", Type: core.NewListType(core.FlowType{Kind: core.FSKindAny})},
+		},
+		Action: &MapValuesAction{},
+	}
+}
+
+var _ core.NodeAction = &MapValuesAction{}
+
+func (a *MapValuesAction) UpdateAndValidate(n *core.Node) {
+	n.Valid = n.InputIsWired(0) && mapWireOk(n)
+	n.OutputPorts[0].Type = core.NewListType(mapValueType(n))
+}
+
+func (a *MapValuesAction) UI(n *core.Node) {
+	uiMapEntries(n, "MapValues")
+}
+
+func (a *MapValuesAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *MapValuesAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	return runMapEntries(n, func(m core.FlowValue) core.FlowValue {
+		vals := make([]core.FlowValue, len(m.MapValue))
+		for i, entry := range m.MapValue {
+			vals[i] = entry.Value
+		}
+		return core.NewListValue(*m.Type.ContainedType, vals)
+	})
+}
+
+func (a *MapValuesAction) Serialize(s *core.Serializer) bool {
+	return s.Ok()
+}
+
+// GEN:NodeAction
+type MapEntriesAction struct{}
+
+func NewMapEntriesNode() *core.Node {
+	return &core.Node{
+		Name: "Map Entries",
+		InputPorts: []core.NodePort{
+			{Name: "Map", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Entries", Type: mapEntriesType(core.FlowType{Kind: core.FSKindAny})},
+		},
+		Action: &MapEntriesAction{},
+	}
+}
+
+var _ core.NodeAction = &MapEntriesAction{}
+
+func (a *MapEntriesAction) UpdateAndValidate(n *core.Node) {
+	n.Valid = n.InputIsWired(0) && mapWireOk(n)
+	n.OutputPorts[0].Type = mapEntriesType(mapValueType(n))
+}
+
+func (a *MapEntriesAction) UI(n *core.Node) {
+	uiMapEntries(n, "MapEntries")
+}
+
+func (a *MapEntriesAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *MapEntriesAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	return runMapEntries(n, func(m core.FlowValue) core.FlowValue {
+		t := mapEntriesType(*m.Type.ContainedType)
+		rows := make([][]core.FlowValueField, len(m.MapValue))
+		for i, entry := range m.MapValue {
+			rows[i] = []core.FlowValueField{
+				{Name: "key", Value: core.NewStringValue(entry.Name)},
+				{Name: "value", Value: entry.Value},
+			}
+		}
+		return core.FlowValue{Type: &t, TableValue: rows}
+	})
+}
+
+func (a *MapEntriesAction) Serialize(s *core.Serializer) bool {
+	return s.Ok()
+}
+
+// mapWireOk reports whether the input of n is wired to something that could
+// be a map.
+func mapWireOk(n *core.Node) bool {
+	wire, ok := n.GetInputWire(0)
+	if !ok {
+		return true
+	}
+	kind := wire.Type().Kind
+	return kind == core.FSKindMap || kind == core.FSKindAny
+}
+
+// mapValueType returns the value type of the map wired to n, or Any.
+func mapValueType(n *core.Node) core.FlowType {
+	if wire, ok := n.GetInputWire(0); ok {
+		if t := wire.Type(); t.Kind == core.FSKindMap && t.ContainedType != nil {
+			return *t.ContainedType
+		}
+	}
+	return core.FlowType{Kind: core.FSKindAny}
+}
+
+// mapEntriesType is a table with a row per entry, of columns "key" and
+// "value".
+func mapEntriesType(value core.FlowType) core.FlowType {
+	return core.NewTableType([]core.FlowField{
+		{Name: "key", Type: &core.FlowType{Kind: core.FSKindBytes}},
+		{Name: "value", Type: &value},
+	})
+}
+
+func uiMapEntries(n *core.Node, prefix string) {
+	clay.CLAY(clay.IDI(prefix+"UI", n.ID), clay.EL{
+		Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+	}, func() {
+		core.UIInputPort(n, 0)
+		core.UISpacer(clay.IDI(prefix+"Spacer", n.ID), core.GROWH)
+		core.UIOutputPort(n, 0)
+	})
+}
+
+// runMapEntries gets the map on the input of n and gives f's result of it.
+func runMapEntries(n *core.Node, f func(m core.FlowValue) core.FlowValue) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	defer close(done)
+
+	m, ok, err := n.GetInputValue(0)
+	if err != nil || !ok {
+		done <- core.NodeActionResult{Err: fmt.Errorf("failed to get input: %v", err)}
+		return done
+	}
+	if m.Type.Kind != core.FSKindMap {
+		done <- core.NodeActionResult{Err: fmt.Errorf("input must be a map, not %s", m.Type)}
+		return done
+	}
+	if m.Type.ContainedType == nil {
+		anyMap := core.NewMapType(core.FlowType{Kind: core.FSKindAny})
+		m.Type = &anyMap
+	}
+	done <- core.NodeActionResult{Outputs: []core.FlowValue{f(m)}}
+	return done
+}
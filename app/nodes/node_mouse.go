@@ -63,6 +63,10 @@ func (a *GetMousePositionAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+func (a *GetMousePositionAction) HasSideEffects() bool {
+	return true
+}
+
 // GEN:NodeAction
 type WaitForClickAction struct{}
 
@@ -153,3 +157,7 @@ func (a *WaitForClickAction) RunContext(ctx context.Context, n *core.Node) <-cha
 func (a *WaitForClickAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
+
+func (a *WaitForClickAction) HasSideEffects() bool {
+	return true
+}
@@ -86,4 +86,8 @@ func (a *PromptUserAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &a.Message)
 	core.SStr(s, &a.DefaultValue)
 	return s.Ok()
+}
+
+func (a *PromptUserAction) HasSideEffects() bool {
+	return true
 }
\ No newline at end of file
@@ -0,0 +1,250 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+	"github.com/bvisness/flowshell/util"
+	"github.com/expr-lang/expr"
+)
+
+// Reduce combines the items of a list, or the rows of a table, into a single
+// value. The accumulator starts as the Initial input, or as the first item if
+// Initial is not wired, and is replaced for each item by the result of an
+// expression or a subflow. An expression sees the accumulator as Acc and the
+// item as Input (see formulaEnv). A subflow gets them on graph inputs named
+// "Acc" and "Input" and returns the new accumulator on its first output.
+//
+// GEN:NodeAction
+type ReduceAction struct {
+	Expression  string
+	SubflowPath string
+	UseSubflow  bool
+
+	subflow subflowFile
+}
+
+func NewReduceNode() *core.Node {
+	return &core.Node{
+		Name: "Reduce",
+		InputPorts: []core.NodePort{
+			{Name: "Items", Type: core.FlowType{Kind: core.FSKindAny}},
+			{Name: "Initial", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Result", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		Action: &ReduceAction{Expression: "Acc + Input"},
+	}
+}
+
+var _ core.NodeAction = &ReduceAction{}
+
+func (a *ReduceAction) UpdateAndValidate(n *core.Node) {
+	n.Valid = n.InputIsWired(0) && itemsWireOk(n) && util.Tern(a.UseSubflow, a.SubflowPath, a.Expression) != ""
+}
+
+func (a *ReduceAction) UI(n *core.Node) {
+	clay.CLAY(clay.IDI("ReduceUI", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		clay.CLAY(clay.IDI("ReduceRow1", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+		}, func() {
+			core.UIInputPort(n, 0)
+			core.UISpacer(clay.IDI("ReduceSpacer1", n.ID), core.GROWH)
+			core.UIOutputPort(n, 0)
+		})
+		core.UIInputPort(n, 1)
+		uiItemFunc(n, "Reduce", &a.UseSubflow, &a.Expression, &a.SubflowPath)
+	})
+}
+
+func (a *ReduceAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *ReduceAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	go func() {
+		defer close(done)
+
+		val, ok, err := n.GetInputValue(0)
+		if err != nil || !ok {
+			done <- core.NodeActionResult{Err: fmt.Errorf("failed to get input: %v", err)}
+			return
+		}
+		items, err := mapItems(val)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+
+		acc, hasInitial, err := n.GetInputValue(1)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+		if !hasInitial {
+			if len(items) == 0 {
+				done <- core.NodeActionResult{Err: fmt.Errorf("cannot reduce no items without an initial value")}
+				return
+			}
+			acc, items = items[0], items[1:]
+		}
+
+		f, err := newItemFunc(a.UseSubflow, a.Expression, &a.subflow, a.SubflowPath)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+		for i, item := range items {
+			if ctx.Err() != nil {
+				done <- core.NodeActionResult{Err: ctx.Err()}
+				return
+			}
+			acc, err = f(ctx, map[string]core.FlowValue{"Acc": acc, "Input": item})
+			if err != nil {
+				done <- core.NodeActionResult{Err: fmt.Errorf("item %d: %v", i, err)}
+				return
+			}
+		}
+
+		done <- core.NodeActionResult{Outputs: []core.FlowValue{acc}}
+	}()
+	return done
+}
+
+func (a *ReduceAction) Serialize(s *core.Serializer) bool {
+	core.SStr(s, &a.Expression)
+	core.SStr(s, &a.SubflowPath)
+	core.SBool(s, &a.UseSubflow)
+	return s.Ok()
+}
+
+// A subflow is loaded from disk and may have changed.
+func (a *ReduceAction) HasSideEffects() bool {
+	return a.UseSubflow
+}
+
+// With a subflow, Reduce only waits on it, and its nodes are scheduled
+// themselves. An expression is evaluated by Reduce itself, so it takes a slot.
+func (a *ReduceAction) SchedCategory() string {
+	if a.UseSubflow {
+		return core.SchedCategoryCoordinator
+	}
+	return ""
+}
+
+// itemsWireOk reports whether the first input of n, which takes a list or a
+// table of items, is wired to something that could be one.
+func itemsWireOk(n *core.Node) bool {
+	wire, ok := n.GetInputWire(0)
+	if !ok {
+		return true
+	}
+	switch wire.Type().Kind {
+	case core.FSKindList, core.FSKindTable, core.FSKindAny:
+		return true
+	}
+	return false
+}
+
+// An itemFunc is the expression or subflow that Reduce and Filter apply to
+// each item, called with named arguments.
+type itemFunc func(ctx context.Context, args map[string]core.FlowValue) (core.FlowValue, error)
+
+// newItemFunc prepares the expression, or the subflow at path. Expressions
+// see the arguments as variables, alongside those of formulaEnv for Input.
+// Subflow inputs are matched to arguments by name, except that a subflow
+// with a single input gets the only argument whatever the input's name.
+func newItemFunc(useSubflow bool, expression string, subflow *subflowFile, path string) (itemFunc, error) {
+	if !useSubflow {
+		program, err := expr.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("bad expression: %v", err)
+		}
+		return func(_ context.Context, args map[string]core.FlowValue) (core.FlowValue, error) {
+			env := formulaEnv(args["Input"])
+			for name, arg := range args {
+				env[name] = core.FlowValueToNative(arg)
+			}
+			output, err := expr.Run(program, env)
+			if err != nil {
+				return core.FlowValue{}, err
+			}
+			return core.NativeToFlowValue(output)
+		}, nil
+	}
+
+	g, err := subflow.load(path)
+	if err != nil {
+		return nil, err
+	}
+	return graphItemFunc(g)
+}
+
+// graphItemFunc calls g with the arguments on the graph inputs of the same
+// names, or on its only input, and returns its first output.
+func graphItemFunc(g *core.Graph) (itemFunc, error) {
+	sig := NewGraphSignature(g, false)
+	if len(sig.Outputs) == 0 {
+		return nil, fmt.Errorf("subflow has no Graph Output")
+	}
+	return func(ctx context.Context, args map[string]core.FlowValue) (core.FlowValue, error) {
+		callArgs := make([]core.FlowValue, len(sig.Inputs))
+		for i, param := range sig.Inputs {
+			arg, ok := args[param.Port.Name]
+			if !ok && len(sig.Inputs) == 1 && len(args) == 1 {
+				for _, only := range args {
+					arg, ok = only, true
+				}
+			}
+			if !ok {
+				names := slices.Sorted(maps.Keys(args))
+				return core.FlowValue{}, fmt.Errorf("subflow input %q should be named one of %q", param.Port.Name, names)
+			}
+			callArgs[i] = arg
+		}
+		outs, err := sig.Call(ctx, callArgs)
+		if err != nil {
+			return core.FlowValue{}, fmt.Errorf("subflow error: %v", err)
+		}
+		return outs[0], nil
+	}, nil
+}
+
+// uiItemFunc shows the choice between an expression and a subflow, and the
+// setting for the chosen one.
+func uiItemFunc(n *core.Node, prefix string, useSubflow *bool, expression, subflowPath *string) {
+	clay.CLAY(clay.IDI(prefix+"ModeRow", n.ID), clay.EL{
+		Layout: clay.LAY{Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		for _, mode := range []struct {
+			L string
+			B bool
+		}{{"Expression", false}, {"Subflow", true}} {
+			core.UIButton(clay.IDI(prefix+"ModeBtn"+mode.L, n.ID), core.UIButtonConfig{
+				OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+					*useSubflow = mode.B
+				},
+				El: clay.EL{BackgroundColor: util.Tern(*useSubflow == mode.B, core.Blue, core.Charcoal)},
+			}, func() {
+				clay.TEXT(mode.L, clay.TextElementConfig{TextColor: core.White, FontSize: 14})
+			})
+		}
+	})
+	if *useSubflow {
+		core.UITextBox(clay.IDI(prefix+"Subflow", n.ID), subflowPath, core.UITextBoxConfig{
+			El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+		})
+	} else {
+		core.UITextBox(clay.IDI(prefix+"Expression", n.ID), expression, core.UITextBoxConfig{
+			El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+		})
+	}
+}
@@ -7,10 +7,15 @@ import (
 
 	"github.com/bvisness/flowshell/app/core"
 	"github.com/bvisness/flowshell/clay"
+	"github.com/bvisness/flowshell/util"
 )
 
 // GEN:NodeAction
-type RegexMatchAction struct{}
+type RegexMatchAction struct {
+	// BoolMatch makes Match a Bool. Nodes saved before schema version 1
+	// leave it off, so Match is still 1 or 0 as an Int64.
+	BoolMatch bool
+}
 
 func NewRegexMatchNode() *core.Node {
 	return &core.Node{
@@ -20,9 +25,9 @@ func NewRegexMatchNode() *core.Node {
 			{Name: "Pattern", Type: core.FlowType{Kind: core.FSKindBytes}},
 		},
 		OutputPorts: []core.NodePort{
-			{Name: "Match", Type: core.FlowType{Kind: core.FSKindInt64}}, // 0 or 1 for bool
+			{Name: "Match", Type: core.FlowType{Kind: core.FSKindBool}},
 		},
-		Action: &RegexMatchAction{},
+		Action: &RegexMatchAction{BoolMatch: true},
 	}
 }
 
@@ -30,6 +35,7 @@ var _ core.NodeAction = &RegexMatchAction{}
 
 func (a *RegexMatchAction) UpdateAndValidate(n *core.Node) {
 	n.Valid = true // Validation could check if pattern compiles
+	n.OutputPorts[0].Type = core.FlowType{Kind: util.Tern(a.BoolMatch, core.FSKindBool, core.FSKindInt64)}
 }
 
 func (a *RegexMatchAction) UI(n *core.Node) {
@@ -72,16 +78,11 @@ func (a *RegexMatchAction) Run(n *core.Node) <-chan core.NodeActionResult {
 		}
 
 		match := re.MatchString(text)
-		val := int64(0)
-		if match {
-			val = 1
-		}
-
-		done <- core.NodeActionResult{
-			Outputs: []core.FlowValue{
-				core.NewInt64Value(val, 0),
-			},
+		output := core.NewBoolValue(match)
+		if !a.BoolMatch {
+			output = core.NewInt64Value(util.Tern[int64](match, 1, 0), 0)
 		}
+		done <- core.NodeActionResult{Outputs: []core.FlowValue{output}}
 	}()
 	return done
 }
@@ -90,10 +91,22 @@ func (a *RegexMatchAction) RunContext(ctx context.Context, n *core.Node) <-chan
 	return a.Run(n)
 }
 
+// Since schema version 1, BoolMatch is saved.
+func (a *RegexMatchAction) SchemaVersion() int {
+	return 1
+}
+
 func (a *RegexMatchAction) Serialize(s *core.Serializer) bool {
+	core.SBool(s, &a.BoolMatch)
 	return s.Ok()
 }
 
+func init() {
+	core.RegisterNodeActionUpgrade("RegexMatchAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		return s.Ok()
+	})
+}
+
 // GEN:NodeAction
 type RegexFindAllAction struct{}
 
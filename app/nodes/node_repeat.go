@@ -0,0 +1,396 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+	"github.com/bvisness/flowshell/util"
+)
+
+// Repeat runs a body flow over and over, feeding each of its results back in
+// as the next input, for as long as a condition on the current value holds.
+// The body is the flow at BodyPath, or the embedded Body if BodyPath is
+// empty, and is called like a Reduce subflow with the value as Input. The
+// condition is an expression or subflow as for Filter; without one, the body
+// repeats until a limit is reached.
+//
+// At least one of MaxIterations and TimeLimit must be set. If the condition
+// still holds when a limit is reached, Repeat fails.
+//
+// GEN:NodeAction
+type RepeatAction struct {
+	Body     *core.Graph
+	BodyPath string
+
+	Condition           string
+	ConditionPath       string
+	UseConditionSubflow bool
+
+	MaxIterations int
+	TimeLimit     time.Duration
+
+	body      subflowFile
+	condition subflowFile
+
+	mu             sync.Mutex
+	iterations     []core.FlowValue // the results of the last few iterations
+	iterationCount int
+
+	maxIterationsText string
+	timeLimitText     string
+}
+
+func NewRepeatNode() *core.Node {
+	return &core.Node{
+		Name: "Repeat",
+		InputPorts: []core.NodePort{
+			{Name: "Initial", Type: core.FlowType{Kind: core.FSKindAny}},
+		},
+		OutputPorts: []core.NodePort{
+			{Name: "Result", Type: core.FlowType{Kind: core.FSKindAny}},
+			{Name: "Iterations", Type: core.FlowType{Kind: core.FSKindInt64}},
+		},
+		Action: &RepeatAction{Body: core.NewGraph(), MaxIterations: 100},
+	}
+}
+
+var _ core.NodeAction = &RepeatAction{}
+
+// The number of most recent iterations shown on the node.
+const repeatShownIterations = 5
+
+func (a *RepeatAction) hasCondition() bool {
+	return a.UseConditionSubflow || a.Condition != ""
+}
+
+func (a *RepeatAction) UpdateAndValidate(n *core.Node) {
+	if a.Body == nil {
+		a.Body = core.NewGraph()
+	}
+	a.Body.Parent = n.Graph
+
+	n.Valid = a.MaxIterations > 0 || a.TimeLimit > 0
+	if a.UseConditionSubflow && a.ConditionPath == "" {
+		n.Valid = false
+	}
+	if a.BodyPath == "" {
+		if a.Body.UpdateAndValidate() != nil {
+			n.Valid = false
+		}
+		for _, inner := range a.Body.Nodes {
+			if !inner.Valid {
+				n.Valid = false
+			}
+		}
+		if len(NewGraphSignature(a.Body, false).Outputs) == 0 {
+			n.Valid = false
+		}
+	}
+}
+
+func (a *RepeatAction) UI(n *core.Node) {
+	clay.CLAY(clay.IDI("RepeatUI", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		clay.CLAY(clay.IDI("RepeatRow1", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+		}, func() {
+			core.UIInputPort(n, 0)
+			core.UISpacer(clay.IDI("RepeatSpacer1", n.ID), core.GROWH)
+			core.UIOutputPort(n, 0)
+		})
+		core.UIOutputPort(n, 1)
+
+		clay.TEXT("Body (empty path for embedded):", clay.TextElementConfig{TextColor: core.Gray})
+		clay.CLAY(clay.IDI("RepeatBodyRow", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+		}, func() {
+			core.UITextBox(clay.IDI("RepeatBodyPath", n.ID), &a.BodyPath, core.UITextBoxConfig{
+				El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+			})
+			if a.BodyPath == "" {
+				core.UISpacer(clay.IDI("RepeatBodySpacer", n.ID), core.W2)
+				core.UIButton(clay.IDI("RepeatBodyOpen", n.ID), core.UIButtonConfig{
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						core.OpenSubgraph(n, a.Body)
+					},
+					ZIndex: core.Z_NODE_BUTTON,
+				}, func() {
+					clay.TEXT("Open", clay.TextElementConfig{TextColor: core.White})
+				})
+			}
+		})
+
+		clay.TEXT("While (empty to repeat until a limit):", clay.TextElementConfig{TextColor: core.Gray})
+		uiItemFunc(n, "RepeatCondition", &a.UseConditionSubflow, &a.Condition, &a.ConditionPath)
+
+		clay.CLAY(clay.IDI("RepeatLimitsRow", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildGap: core.S2, ChildAlignment: core.YCENTER},
+		}, func() {
+			clay.TEXT("Max:", clay.TextElementConfig{TextColor: core.Gray})
+			maxID := clay.IDI("RepeatMaxIterations", n.ID)
+			if !core.IsFocused(maxID) {
+				a.maxIterationsText = strconv.Itoa(a.MaxIterations)
+			}
+			core.UITextBox(maxID, &a.maxIterationsText, core.UITextBoxConfig{
+				El: clay.EL{Layout: clay.LAY{Sizing: clay.Sizing{Width: clay.SizingFixed(60)}}},
+				OnSubmit: func(val string) {
+					if max, err := strconv.Atoi(strings.TrimSpace(val)); err == nil && max >= 0 {
+						a.MaxIterations = max
+					}
+				},
+			})
+			clay.TEXT("Time:", clay.TextElementConfig{TextColor: core.Gray})
+			timeID := clay.IDI("RepeatTimeLimit", n.ID)
+			if !core.IsFocused(timeID) {
+				a.timeLimitText = util.Tern(a.TimeLimit == 0, "", a.TimeLimit.String())
+			}
+			core.UITextBox(timeID, &a.timeLimitText, core.UITextBoxConfig{
+				El: clay.EL{Layout: clay.LAY{Sizing: clay.Sizing{Width: clay.SizingFixed(80)}}},
+				OnSubmit: func(val string) {
+					if val = strings.TrimSpace(val); val == "" {
+						a.TimeLimit = 0
+					} else if limit, err := time.ParseDuration(val); err == nil && limit >= 0 {
+						a.TimeLimit = limit
+					}
+				},
+			})
+		})
+
+		iterations, count := a.Iterations()
+		for i, value := range iterations {
+			clay.CLAY(clay.IDI(fmt.Sprintf("RepeatIteration%d", i), n.ID), clay.EL{
+				Layout: clay.LAY{ChildGap: core.S2},
+			}, func() {
+				clay.TEXT(fmt.Sprintf("#%d", count-len(iterations)+i+1), clay.TextElementConfig{FontID: core.InterSemibold, TextColor: core.LightGray})
+				core.UIFlowValue(clay.IDI(fmt.Sprintf("RepeatIterationValue%d", i), n.ID), value)
+			})
+		}
+	})
+}
+
+// Iterations returns the results of the last few iterations of the current or
+// last run, up to repeatShownIterations, and the number of iterations so far.
+func (a *RepeatAction) Iterations() ([]core.FlowValue, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.iterations), a.iterationCount
+}
+
+func (a *RepeatAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *RepeatAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	go func() {
+		defer close(done)
+
+		a.mu.Lock()
+		a.iterations, a.iterationCount = nil, 0
+		a.mu.Unlock()
+
+		if a.MaxIterations <= 0 && a.TimeLimit <= 0 {
+			done <- core.NodeActionResult{Err: fmt.Errorf("set a maximum number of iterations or a time limit")}
+			return
+		}
+
+		value, ok, err := n.GetInputValue(0)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+		if !ok {
+			value = core.NewStringValue("")
+		}
+
+		var body itemFunc
+		if a.BodyPath != "" {
+			body, err = newItemFunc(true, "", &a.body, a.BodyPath)
+		} else {
+			a.Body.Parent = n.Graph
+			body, err = graphItemFunc(a.Body)
+		}
+		if err != nil {
+			done <- core.NodeActionResult{Err: fmt.Errorf("body: %v", err)}
+			return
+		}
+		var condition itemFunc
+		if a.hasCondition() {
+			condition, err = newItemFunc(a.UseConditionSubflow, a.Condition, &a.condition, a.ConditionPath)
+			if err != nil {
+				done <- core.NodeActionResult{Err: fmt.Errorf("condition: %v", err)}
+				return
+			}
+		}
+
+		loopCtx := ctx
+		if a.TimeLimit > 0 {
+			var cancel context.CancelFunc
+			loopCtx, cancel = context.WithTimeout(ctx, a.TimeLimit)
+			defer cancel()
+		}
+
+		// Stops at a limit, which is an error only if the loop was still
+		// supposed to go on.
+		var limitErr error
+		i := 0
+		for {
+			if ctx.Err() != nil {
+				done <- core.NodeActionResult{Err: ctx.Err()}
+				return
+			}
+			if condition != nil && loopCtx.Err() == nil {
+				cond, err := condition(loopCtx, map[string]core.FlowValue{"Input": value})
+				if err != nil && loopCtx.Err() == nil {
+					done <- core.NodeActionResult{Err: fmt.Errorf("condition on iteration %d: %v", i+1, err)}
+					return
+				}
+				if err == nil && !IsTruthy(cond) {
+					break
+				}
+			}
+			if a.MaxIterations > 0 && i >= a.MaxIterations {
+				limitErr = fmt.Errorf("condition still true after %d iterations", i)
+				break
+			}
+			if loopCtx.Err() != nil {
+				if ctx.Err() != nil {
+					continue // cancelled
+				}
+				limitErr = fmt.Errorf("condition still true after %v", a.TimeLimit)
+				break
+			}
+
+			next, err := body(loopCtx, map[string]core.FlowValue{"Input": value})
+			if err != nil {
+				if loopCtx.Err() != nil {
+					continue // cancelled or out of time
+				}
+				done <- core.NodeActionResult{Err: fmt.Errorf("iteration %d: %v", i+1, err)}
+				return
+			}
+			value = next
+			i++
+
+			a.mu.Lock()
+			if len(a.iterations) == repeatShownIterations {
+				copy(a.iterations, a.iterations[1:])
+				a.iterations[len(a.iterations)-1] = value
+			} else {
+				a.iterations = append(a.iterations, value)
+			}
+			a.iterationCount++
+			a.mu.Unlock()
+		}
+		if limitErr != nil && condition != nil {
+			done <- core.NodeActionResult{Err: limitErr}
+			return
+		}
+
+		done <- core.NodeActionResult{Outputs: []core.FlowValue{value, core.NewInt64Value(int64(i), 0)}}
+	}()
+	return done
+}
+
+// The embedded body is saved as a complete graph, with its own version.
+func (a *RepeatAction) Serialize(s *core.Serializer) bool {
+	if a.Body == nil {
+		a.Body = core.NewGraph()
+	}
+
+	var body string
+	if s.Encode {
+		raw, err := core.SerializeGraph(a.Body)
+		if err != nil {
+			return s.Error(err)
+		}
+		body = string(raw)
+	}
+	if !core.SStr(s, &body) {
+		return false
+	}
+	if !s.Encode {
+		g, err := core.DeserializeGraph([]byte(body))
+		if err != nil {
+			return s.Error(fmt.Errorf("failed to read loop body: %w", err))
+		}
+		a.Body = g
+	}
+
+	core.SStr(s, &a.BodyPath)
+	core.SStr(s, &a.Condition)
+	core.SStr(s, &a.ConditionPath)
+	core.SBool(s, &a.UseConditionSubflow)
+	core.SInt(s, &a.MaxIterations)
+	core.SInt(s, &a.TimeLimit)
+	return s.Ok()
+}
+
+type repeatJSON struct {
+	Body                json.RawMessage
+	BodyPath            string
+	Condition           string
+	ConditionPath       string
+	UseConditionSubflow bool
+	MaxIterations       int
+	TimeLimit           time.Duration
+}
+
+// In the text format, the embedded body is written in the text format too.
+func (a *RepeatAction) MarshalJSON() ([]byte, error) {
+	body, err := core.SerializeGraphText(a.Body)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(repeatJSON{
+		Body:                body,
+		BodyPath:            a.BodyPath,
+		Condition:           a.Condition,
+		ConditionPath:       a.ConditionPath,
+		UseConditionSubflow: a.UseConditionSubflow,
+		MaxIterations:       a.MaxIterations,
+		TimeLimit:           a.TimeLimit,
+	})
+}
+
+func (a *RepeatAction) UnmarshalJSON(data []byte) error {
+	var settings repeatJSON
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	if settings.Body == nil {
+		return errors.New("missing loop body")
+	}
+	body, err := core.DeserializeGraphText(settings.Body)
+	if err != nil {
+		return err
+	}
+	a.Body = body
+	a.BodyPath = settings.BodyPath
+	a.Condition = settings.Condition
+	a.ConditionPath = settings.ConditionPath
+	a.UseConditionSubflow = settings.UseConditionSubflow
+	a.MaxIterations = settings.MaxIterations
+	a.TimeLimit = settings.TimeLimit
+	return nil
+}
+
+// Loops usually wait on something outside the flow to change.
+func (a *RepeatAction) HasSideEffects() bool {
+	return a.BodyPath != "" || a.UseConditionSubflow || graphHasSideEffects(a.Body)
+}
+
+// Repeat only waits on its body, whose nodes are scheduled themselves.
+func (a *RepeatAction) SchedCategory() string {
+	return core.SchedCategoryCoordinator
+}
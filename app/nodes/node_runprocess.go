@@ -339,27 +339,47 @@ func (c *RunProcessAction) Run(n *core.Node) <-chan core.NodeActionResult {
 	return c.RunContext(context.Background(), n)
 }
 
+// Since schema version 1, every option is always saved.
+func (c *RunProcessAction) SchemaVersion() int {
+	return 1
+}
+
 func (c *RunProcessAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &c.CmdString)
+	core.SBool(s, &c.UseShell)
+	core.SBool(s, &c.UseStdin)
+	core.SBool(s, &c.StreamOutput)
+	return s.Ok()
+}
 
-	if !s.Encode && s.Buf.Len() == 0 {
-		c.UseShell = false
-		c.UseStdin = false
-		c.StreamOutput = false
+func init() {
+	// Schema version 0 saved the same fields. Checking for the end of the
+	// buffer to stop early, as it also did, is wrong in files from before
+	// version 10, where the buffer holds the rest of the file.
+	core.RegisterNodeActionUpgrade("RunProcessAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		c := a.(*RunProcessAction)
+		core.SStr(s, &c.CmdString)
+		core.SBool(s, &c.UseShell)
+		core.SBool(s, &c.UseStdin)
+		core.SBool(s, &c.StreamOutput)
 		return s.Ok()
-	}
+	})
+}
 
-	core.SBool(s, &c.UseShell)
+func (c *RunProcessAction) HasSideEffects() bool {
+	return true
+}
 
-	if !s.Encode && s.Buf.Len() == 0 {
-		c.UseStdin = false
-		c.StreamOutput = false
-		return s.Ok()
-	}
+func (c *RunProcessAction) SchedCategory() string {
+	return core.SchedCategoryProcess
+}
 
-	core.SBool(s, &c.UseStdin)
-	core.SBool(s, &c.StreamOutput)
-	return s.Ok()
+// The exit code, which is unknown when streaming output.
+func (c *RunProcessAction) ResultCode(res core.NodeActionResult) (int, bool) {
+	if c.StreamOutput || len(res.Outputs) < 4 {
+		return 0, false
+	}
+	return int(res.Outputs[3].Int64Value), true
 }
 
 func parseCommand(cmd string) []string {
@@ -44,6 +44,10 @@ func (c *SaveFileAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+func (c *SaveFileAction) HasSideEffects() bool {
+	return true
+}
+
 func (c *SaveFileAction) UpdateAndValidate(n *core.Node) {
 	n.Valid = true
 	// Could validate path validity here
@@ -168,6 +172,11 @@ func (c *SaveFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 					data = []byte(strconv.FormatInt(input.Int64Value, 10))
 				case core.FSKindFloat64:
 					data = []byte(strconv.FormatFloat(input.Float64Value, 'f', -1, 64))
+				case core.FSKindBool:
+					data = []byte(strconv.FormatBool(input.BoolValue))
+				}
+				if input.Null {
+					data = nil
 				}
 			}
 			_, err = f.Write(data)
@@ -216,7 +225,8 @@ func (c *SaveFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 			}
 
 			// Write rows
-			for _, row := range input.TableValue {
+			for i := range input.NumRows() {
+				row := input.RowFields(i)
 				select {
 				case <-ctx.Done():
 					res.Err = ctx.Err()
@@ -226,9 +236,12 @@ func (c *SaveFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 				var record []string
 				for _, field := range row {
 					// Convert value to string
-					valStr := fmt.Sprintf("%v", field.Value) // Simplification
-					// Proper conversion:
+					var valStr string
 					v := field.Value
+					if v.Null {
+						record = append(record, "")
+						continue
+					}
 					switch v.Type.Kind {
 					case core.FSKindBytes:
 						valStr = string(v.BytesValue)
@@ -236,6 +249,10 @@ func (c *SaveFileAction) RunContext(ctx context.Context, n *core.Node) <-chan co
 						valStr = strconv.FormatInt(v.Int64Value, 10)
 					case core.FSKindFloat64:
 						valStr = strconv.FormatFloat(v.Float64Value, 'f', -1, 64)
+					case core.FSKindBool:
+						valStr = strconv.FormatBool(v.BoolValue)
+					default:
+						valStr = v.String()
 					}
 					record = append(record, valStr)
 				}
@@ -170,21 +170,27 @@ func (c *SelectColumnsAction) RunContext(ctx context.Context, n *core.Node) <-ch
 			return
 		}
 
-		// Map selected columns to indices
-		var colIndices []int
 		var newFields []core.FlowField
-
+		var cols []int
 		if input.Type.ContainedType != nil {
 			for i, field := range input.Type.ContainedType.Fields {
 				if slices.Contains(c.SelectedColumns, field.Name) {
-					colIndices = append(colIndices, i)
 					newFields = append(newFields, field)
+					cols = append(cols, i)
 				}
 			}
 		}
 
+		// A table held by column shares the selected columns.
+		if input.Table != nil {
+			done <- core.NodeActionResult{
+				Outputs: []core.FlowValue{core.NewTableValue(input.Table.Select(cols))},
+			}
+			return
+		}
+
 		var newRows [][]core.FlowValueField
-		for _, row := range input.TableValue {
+		for i, row := range input.TableValue {
 			// Check context
 			select {
 			case <-ctx.Done():
@@ -193,9 +199,10 @@ func (c *SelectColumnsAction) RunContext(ctx context.Context, n *core.Node) <-ch
 			default:
 			}
 
-			var newRow []core.FlowValueField
-			for _, idx := range colIndices {
-				newRow = append(newRow, row[idx])
+			newRow, ok := core.ProjectFields(row, newFields)
+			if !ok {
+				done <- core.NodeActionResult{Err: fmt.Errorf("row %d is missing a selected column", i)}
+				return
 			}
 			newRows = append(newRows, newRow)
 		}
@@ -12,9 +12,14 @@ import (
 	"github.com/bvisness/flowshell/util"
 )
 
+// Sort sorts a list, or the rows of a table by one column.
+//
 // GEN:NodeAction
 type SortAction struct {
 	Reverse bool
+	Column  string
+
+	cols core.UIDropdown
 }
 
 func NewSortNode() *core.Node {
@@ -36,11 +41,24 @@ func NewSortNode() *core.Node {
 
 var _ core.NodeAction = &SortAction{}
 
+// Since schema version 1, the column is saved.
+func (c *SortAction) SchemaVersion() int {
+	return 1
+}
+
 func (c *SortAction) Serialize(s *core.Serializer) bool {
 	core.SBool(s, &c.Reverse)
+	core.SStr(s, &c.Column)
 	return s.Ok()
 }
 
+func init() {
+	core.RegisterNodeActionUpgrade("SortAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		core.SBool(s, &a.(*SortAction).Reverse)
+		return s.Ok()
+	})
+}
+
 func (c *SortAction) UpdateAndValidate(n *core.Node) {
 	n.Valid = true
 
@@ -48,7 +66,22 @@ func (c *SortAction) UpdateAndValidate(n *core.Node) {
 		n.InputPorts[0].Type = wire.Type()
 		n.OutputPorts[0].Type = wire.Type()
 
-		if wire.Type().Kind != core.FSKindList {
+		switch t := wire.Type(); t.Kind {
+		case core.FSKindList:
+		case core.FSKindTable:
+			var options []core.UIDropdownOption
+			if t.ContainedType != nil {
+				for _, field := range t.ContainedType.Fields {
+					options = append(options, core.UIDropdownOption{Name: field.Name, Value: field.Name})
+				}
+			}
+			c.cols.Options = options
+			if !c.cols.SelectByValue(c.Column) && len(options) > 0 {
+				c.Column = options[0].Value.(string)
+				c.cols.Selected = 0
+			}
+			n.Valid = t.FieldIndex(c.Column) >= 0
+		default:
 			n.Valid = false
 		}
 	} else {
@@ -83,6 +116,18 @@ func (c *SortAction) UI(n *core.Node) {
 		core.UISpacer(clay.IDI("SortSpacerOutput", n.ID), core.GROWH)
 		core.UIOutputPort(n, 0)
 	})
+
+	if wire, ok := n.GetInputWire(0); ok && wire.Type().Kind == core.FSKindTable {
+		c.cols.Do(clay.IDI("SortColumn", n.ID), core.UIDropdownConfig{
+			El: clay.EL{
+				Layout: clay.LAY{Sizing: core.GROWH},
+			},
+			OnChange: func(before, after any) {
+				c.Column = after.(string)
+				n.ClearResult()
+			},
+		})
+	}
 }
 
 func (c *SortAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
@@ -115,15 +160,11 @@ func (c *SortAction) RunContext(ctx context.Context, n *core.Node) <-chan core.N
 			return
 		}
 
-		if input.Type.Kind != core.FSKindList {
-			res.Err = errors.New("input must be a list")
+		if input.Type.Kind != core.FSKindList && input.Type.Kind != core.FSKindTable {
+			res.Err = errors.New("input must be a list or a table")
 			return
 		}
 
-		// Copy list to avoid modifying input
-		sorted := make([]core.FlowValue, len(input.ListValue))
-		copy(sorted, input.ListValue)
-
 		// Recover from panic caused by cancellation
 		defer func() {
 			if r := recover(); r != nil {
@@ -136,26 +177,27 @@ func (c *SortAction) RunContext(ctx context.Context, n *core.Node) <-chan core.N
 			}
 		}()
 
+		if input.Type.Kind == core.FSKindTable {
+			sorted, err := c.sortTable(ctx, input)
+			if err != nil {
+				res.Err = err
+				return
+			}
+			res.Outputs = []core.FlowValue{sorted}
+			return
+		}
+
+		// Copy list to avoid modifying input
+		sorted := make([]core.FlowValue, len(input.ListValue))
+		copy(sorted, input.ListValue)
+
 		slices.SortFunc(sorted, func(a, b core.FlowValue) int {
 			// Check for cancellation
 			if ctx.Err() != nil {
 				panic(ctx.Err())
 			}
 
-			res := 0
-			switch a.Type.Kind {
-			case core.FSKindBytes:
-				res = cmp.Compare(string(a.BytesValue), string(b.BytesValue))
-			case core.FSKindInt64:
-				res = cmp.Compare(a.Int64Value, b.Int64Value)
-			case core.FSKindFloat64:
-				res = cmp.Compare(a.Float64Value, b.Float64Value)
-			}
-
-			if c.Reverse {
-				return -res
-			}
-			return res
+			return util.Tern(c.Reverse, -1, 1) * compareValues(a, b)
 		})
 
 		res.Outputs = []core.FlowValue{core.NewListValue(*input.Type.ContainedType, sorted)}
@@ -164,6 +206,99 @@ func (c *SortAction) RunContext(ctx context.Context, n *core.Node) <-chan core.N
 	return done
 }
 
+// sortTable sorts the rows of a table by c.Column, keeping the order of rows
+// that tie. It sorts row numbers, comparing a table held by column straight
+// from a numeric column's vector, and then takes the rows in that order.
+func (c *SortAction) sortTable(ctx context.Context, input core.FlowValue) (core.FlowValue, error) {
+	col := input.Type.FieldIndex(c.Column)
+	if col < 0 {
+		return core.FlowValue{}, fmt.Errorf("column %q not found", c.Column)
+	}
+
+	compare := func(i, j int) int {
+		return compareValues(input.Cell(i, col), input.Cell(j, col))
+	}
+	if input.Table != nil {
+		column := input.Table.Columns[col]
+		nulls := column.Nulls()
+		compareNulls := func(i, j int) (int, bool) {
+			if nulls == nil || (!nulls[i] && !nulls[j]) {
+				return 0, false
+			}
+			return compareBools(!nulls[i], !nulls[j]), true
+		}
+		if ints, ok := column.Int64s(); ok {
+			compare = func(i, j int) int {
+				if res, ok := compareNulls(i, j); ok {
+					return res
+				}
+				return cmp.Compare(ints[i], ints[j])
+			}
+		} else if floats, ok := column.Float64s(); ok {
+			compare = func(i, j int) int {
+				if res, ok := compareNulls(i, j); ok {
+					return res
+				}
+				return cmp.Compare(floats[i], floats[j])
+			}
+		}
+	}
+
+	rows := make([]int, input.NumRows())
+	for i := range rows {
+		rows[i] = i
+	}
+	slices.SortStableFunc(rows, func(i, j int) int {
+		// Check for cancellation
+		if ctx.Err() != nil {
+			panic(ctx.Err())
+		}
+
+		return util.Tern(c.Reverse, -1, 1) * compare(i, j)
+	})
+
+	sorted := core.FlowValue{Type: input.Type}
+	if input.Table != nil {
+		sorted.Table = input.Table.Take(rows)
+	} else {
+		sorted.TableValue = make([][]core.FlowValueField, len(rows))
+		for i, row := range rows {
+			sorted.TableValue[i] = input.TableValue[row]
+		}
+	}
+	return sorted, nil
+}
+
+// compareValues orders values of the same primitive kind, with nulls first.
+func compareValues(a, b core.FlowValue) int {
+	if a.Null || b.Null {
+		return compareBools(!a.Null, !b.Null)
+	}
+	switch a.Type.Kind {
+	case core.FSKindBytes:
+		return cmp.Compare(string(a.BytesValue), string(b.BytesValue))
+	case core.FSKindInt64:
+		return cmp.Compare(a.Int64Value, b.Int64Value)
+	case core.FSKindFloat64:
+		return cmp.Compare(a.Float64Value, b.Float64Value)
+	case core.FSKindBool:
+		return compareBools(a.BoolValue, b.BoolValue)
+	}
+	return 0
+}
+
 func (c *SortAction) Run(n *core.Node) <-chan core.NodeActionResult {
 	return c.RunContext(context.Background(), n)
 }
+
+// compareBools orders false before true.
+func compareBools(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,358 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+)
+
+// A subgraph node contains a whole graph, saved inside the parent flow. The
+// graph inputs and outputs of the embedded graph become the ports of the
+// subgraph node (see GraphSignature).
+//
+// GEN:NodeAction
+type SubgraphAction struct {
+	Graph *core.Graph
+}
+
+func NewSubgraphNode(g *core.Graph) *core.Node {
+	n := &core.Node{
+		Name:   "Subgraph",
+		Action: &SubgraphAction{Graph: g},
+	}
+	n.InputPorts, n.OutputPorts = n.Action.(*SubgraphAction).Signature().Ports()
+	return n
+}
+
+var _ core.NodeAction = &SubgraphAction{}
+
+// Signature returns the inputs and outputs of the embedded graph.
+func (a *SubgraphAction) Signature() GraphSignature {
+	return NewGraphSignature(a.Graph, false)
+}
+
+func (a *SubgraphAction) UpdateAndValidate(n *core.Node) {
+	a.Graph.Parent = n.Graph
+	n.Valid = a.Graph.UpdateAndValidate() == nil
+	inputs, outputs := a.Signature().Ports()
+	setFunctionPorts(n, inputs, outputs)
+
+	for _, inner := range a.Graph.Nodes {
+		if !inner.Valid {
+			n.Valid = false
+		}
+	}
+	for i := range n.InputPorts {
+		if !n.InputIsWired(i) {
+			n.Valid = false
+		}
+	}
+}
+
+func (a *SubgraphAction) UI(n *core.Node) {
+	clay.CLAY(clay.IDI("SubgraphUI", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
+	}, func() {
+		clay.CLAY(clay.IDI("SubgraphRow", n.ID), clay.EL{
+			Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.YCENTER},
+		}, func() {
+			clay.TEXT(fmt.Sprintf("%d nodes", len(a.Graph.Nodes)), clay.TextElementConfig{TextColor: core.LightGray})
+			core.UISpacer(clay.IDI("SubgraphSpacer", n.ID), core.GROWH)
+			core.UIButton(clay.IDI("SubgraphOpen", n.ID), core.UIButtonConfig{
+				OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+					core.OpenSubgraph(n, a.Graph)
+				},
+				ZIndex: core.Z_NODE_BUTTON,
+			}, func() {
+				clay.TEXT("Open", clay.TextElementConfig{TextColor: core.White})
+			})
+		})
+		for i := range n.InputPorts {
+			core.UIInputPort(n, i)
+		}
+		for i := range n.ActionOutputPorts() {
+			core.UIOutputPort(n, i)
+		}
+	})
+}
+
+func (a *SubgraphAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *SubgraphAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	go func() {
+		defer close(done)
+
+		a.Graph.Parent = n.Graph
+		args, err := inputValues(n)
+		if err != nil {
+			done <- core.NodeActionResult{Err: err}
+			return
+		}
+		results, err := a.Signature().Call(ctx, args)
+		if err != nil {
+			done <- core.NodeActionResult{Err: fmt.Errorf("subgraph %w", err)}
+			return
+		}
+		done <- core.NodeActionResult{Outputs: results}
+	}()
+	return done
+}
+
+// The embedded graph is saved as a complete graph, with its own version.
+func (a *SubgraphAction) Serialize(s *core.Serializer) bool {
+	if a.Graph == nil {
+		a.Graph = core.NewGraph()
+	}
+
+	var data string
+	if s.Encode {
+		raw, err := core.SerializeGraph(a.Graph)
+		if err != nil {
+			return s.Error(err)
+		}
+		data = string(raw)
+	}
+	if !core.SStr(s, &data) {
+		return false
+	}
+	if !s.Encode {
+		g, err := core.DeserializeGraph([]byte(data))
+		if err != nil {
+			return s.Error(fmt.Errorf("failed to read subgraph: %w", err))
+		}
+		a.Graph = g
+	}
+	return s.Ok()
+}
+
+// In the text format, the embedded graph is written in the text format too.
+func (a *SubgraphAction) MarshalJSON() ([]byte, error) {
+	text, err := core.SerializeGraphText(a.Graph)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct{ Graph json.RawMessage }{text})
+}
+
+func (a *SubgraphAction) UnmarshalJSON(data []byte) error {
+	var settings struct{ Graph json.RawMessage }
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	g, err := core.DeserializeGraphText(settings.Graph)
+	if err != nil {
+		return err
+	}
+	a.Graph = g
+	return nil
+}
+
+// A subgraph has side effects if any node in it does. Its graph inputs are
+// set from the subgraph's inputs, which are already part of the result key.
+// Nodes that fingerprint external state can only do so once their inputs
+// are known, so they make the subgraph uncacheable as well.
+func (a *SubgraphAction) HasSideEffects() bool {
+	return graphHasSideEffects(a.Graph)
+}
+
+func graphHasSideEffects(g *core.Graph) bool {
+	for _, inner := range g.Nodes {
+		if _, ok := inner.Action.(*GraphInputAction); ok {
+			continue
+		}
+		if _, ok := inner.Action.(core.NodeActionWithCacheKey); ok || inner.NoCache {
+			return true
+		}
+		if se, ok := inner.Action.(core.NodeActionWithSideEffects); ok && se.HasSideEffects() {
+			return true
+		}
+	}
+	return false
+}
+
+// A subgraph only waits on its nodes, which are scheduled themselves.
+func (a *SubgraphAction) SchedCategory() string {
+	return core.SchedCategoryCoordinator
+}
+
+// EmbeddedGraph returns the graph embedded in n, if n is a node that can be
+// opened like a subgraph.
+func EmbeddedGraph(n *core.Node) (*core.Graph, bool) {
+	switch a := n.Action.(type) {
+	case *SubgraphAction:
+		return a.Graph, true
+	case *RepeatAction:
+		return a.Body, a.BodyPath == ""
+	}
+	return nil, false
+}
+
+// ClearCachedResults drops the in-memory cached results of every node in g and
+// in the graphs embedded in its nodes.
+func ClearCachedResults(g *core.Graph) {
+	g.ClearCachedResults()
+	for _, n := range g.Nodes {
+		if sub, ok := EmbeddedGraph(n); ok {
+			ClearCachedResults(sub)
+		}
+	}
+}
+
+// CollapseToSubgraph replaces the nodes of g with the given IDs by a single
+// subgraph node containing them. Wires between the collapsed nodes move into
+// the subgraph. Each outside value wired into the collapsed nodes becomes a
+// Graph Input, and each collapsed output wired to the rest of g becomes a
+// Graph Output, typed like the wire it replaces.
+func CollapseToSubgraph(g *core.Graph, ids []int) (*core.Node, error) {
+	selected := make(map[*core.Node]bool)
+	for _, id := range ids {
+		if n, ok := g.GetNode(id); ok {
+			switch n.Action.(type) {
+			case *GraphInputAction, *GraphOutputAction:
+				return nil, fmt.Errorf("%s is a graph input or output and cannot be collapsed", n)
+			}
+			selected[n] = true
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no nodes to collapse")
+	}
+	if collapseWouldCycle(g, selected) {
+		return nil, fmt.Errorf("collapsing these nodes would create a cycle")
+	}
+
+	type portRef struct {
+		node *core.Node
+		port int
+	}
+	var inputs, outputs []portRef
+	inputIndex := make(map[portRef]int)
+	outputIndex := make(map[portRef]int)
+	var inner, incoming, outgoing, outer []*core.Wire
+	for _, w := range g.Wires {
+		start := portRef{w.StartNode, w.StartPort}
+		switch {
+		case selected[w.StartNode] && selected[w.EndNode]:
+			inner = append(inner, w)
+		case selected[w.EndNode]:
+			if _, ok := inputIndex[start]; !ok {
+				inputIndex[start] = len(inputs)
+				inputs = append(inputs, start)
+			}
+			incoming = append(incoming, w)
+		case selected[w.StartNode]:
+			if _, ok := outputIndex[start]; !ok {
+				outputIndex[start] = len(outputs)
+				outputs = append(outputs, start)
+			}
+			outgoing = append(outgoing, w)
+		default:
+			outer = append(outer, w)
+		}
+	}
+
+	sub := core.NewGraph()
+	var minX, minY, maxX float32
+	first := true
+	for _, n := range g.Nodes {
+		if !selected[n] {
+			continue
+		}
+		if first {
+			minX, minY, maxX = n.Pos.X, n.Pos.Y, n.Pos.X
+			first = false
+		}
+		minX, minY, maxX = min(minX, n.Pos.X), min(minY, n.Pos.Y), max(maxX, n.Pos.X)
+		sub.AddNode(n)
+	}
+	sub.Wires = inner
+
+	const spacing = 400
+	names := make(map[string]int)
+	uniqueName := func(name string) string {
+		names[name]++
+		if names[name] > 1 {
+			return fmt.Sprintf("%s %d", name, names[name])
+		}
+		return name
+	}
+
+	inputNodes := make([]*core.Node, len(inputs))
+	for i, ref := range inputs {
+		in := NewGraphInputNode()
+		in.OutputPorts[0].Type = ref.node.OutputPorts[ref.port].Type
+		in.Pos = core.V2{X: minX - spacing, Y: minY + float32(i)*150}
+		sub.AddNode(in)
+		inputNodes[i] = in
+	}
+	for _, w := range incoming {
+		in := inputNodes[inputIndex[portRef{w.StartNode, w.StartPort}]]
+		if in.Action.(*GraphInputAction).Name == "" {
+			in.Action.(*GraphInputAction).Name = uniqueName(w.EndNode.InputPorts[w.EndPort].Name)
+		}
+		sub.AddWire(in, 0, w.EndNode, w.EndPort)
+	}
+
+	clear(names)
+	for i, ref := range outputs {
+		out := NewGraphOutputNode()
+		out.Action.(*GraphOutputAction).Name = uniqueName(ref.node.OutputPorts[ref.port].Name)
+		typ := ref.node.OutputPorts[ref.port].Type
+		out.InputPorts[0].Type = typ
+		out.OutputPorts[0].Type = typ
+		out.Pos = core.V2{X: maxX + spacing, Y: minY + float32(i)*150}
+		sub.AddNode(out)
+		sub.AddWire(ref.node, ref.port, out, 0)
+	}
+
+	n := NewSubgraphNode(sub)
+	n.Pos = core.V2{X: minX, Y: minY}
+	sub.Parent = g
+	g.Nodes = slices.DeleteFunc(g.Nodes, func(n *core.Node) bool { return selected[n] })
+	g.AddNode(n)
+	g.Wires = outer
+	for i, ref := range inputs {
+		g.AddWire(ref.node, ref.port, n, i)
+	}
+	for _, w := range outgoing {
+		g.AddWire(n, outputIndex[portRef{w.StartNode, w.StartPort}], w.EndNode, w.EndPort)
+	}
+	return n, nil
+}
+
+// Collapsing creates a cycle if a path leaves the selected nodes and comes
+// back to them.
+func collapseWouldCycle(g *core.Graph, selected map[*core.Node]bool) bool {
+	visited := make(map[*core.Node]bool)
+	var stack []*core.Node
+	for _, w := range g.Wires {
+		if selected[w.StartNode] && !selected[w.EndNode] && !visited[w.EndNode] {
+			visited[w.EndNode] = true
+			stack = append(stack, w.EndNode)
+		}
+	}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, w := range g.Wires {
+			if w.StartNode != n {
+				continue
+			}
+			if selected[w.EndNode] {
+				return true
+			}
+			if !visited[w.EndNode] {
+				visited[w.EndNode] = true
+				stack = append(stack, w.EndNode)
+			}
+		}
+	}
+	return false
+}
@@ -124,7 +124,7 @@ func (c *TransposeAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 		// NR2 (was C2): v12, v22
 		// NR3 (was C3): v13, v23
 
-		numOldRows := len(input.TableValue)
+		numOldRows := input.NumRows()
 		numOldCols := len(input.Type.ContainedType.Fields)
 
 		if numOldRows == 0 {
@@ -158,10 +158,10 @@ func (c *TransposeAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 			}
 
 			// Check types in this row
-			firstType := input.TableValue[i][0].Value.Type
+			firstType := input.Cell(i, 0).Type
 			mixed := false
 			for j := 1; j < numOldCols; j++ {
-				if input.TableValue[i][j].Value.Type.Kind != firstType.Kind {
+				if input.Cell(i, j).Type.Kind != firstType.Kind {
 					mixed = true
 					break
 				}
@@ -191,7 +191,7 @@ func (c *TransposeAction) RunContext(ctx context.Context, n *core.Node) <-chan c
 
 			var newRow []core.FlowValueField
 			for r := 0; r < numOldRows; r++ {
-				val := input.TableValue[r][c].Value
+				val := input.Cell(r, c)
 				// If new column is Any but val is specific, that's fine, Value stores its own type.
 				// But if we want to enforce schema, we might need to wrap/cast?
 				// core.FlowValue is self-describing, so it's fine.
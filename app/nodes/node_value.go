@@ -3,6 +3,7 @@
 import (
 	"context"
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/bvisness/flowshell/app/core"
 	"github.com/bvisness/flowshell/clay"
@@ -11,8 +12,21 @@ import (
 // GEN:NodeAction
 type ValueAction struct {
 	Value core.FlowValue
+
+	// Value as a literal, and the start of it that is shown in the node. They
+	// are built only when Value changes, since writing out a large table
+	// every frame is slow.
+	literal, shown string
+	// The value as typed, while it is being edited, and why it last failed
+	// to parse.
+	text     string
+	editing  bool
+	parseErr error
 }
 
+// How much of the literal is shown while it is not being edited.
+const valueShownLen = 60
+
 func NewValueNode(v core.FlowValue) *core.Node {
 	return &core.Node{
 		Name: "Value",
@@ -30,6 +44,9 @@ var _ core.NodeAction = &ValueAction{}
 
 func (c *ValueAction) Serialize(s *core.Serializer) bool {
 	core.SThing(s, &c.Value)
+	if !s.Encode {
+		c.literal = ""
+	}
 	return s.Ok()
 }
 
@@ -42,19 +59,72 @@ func (c *ValueAction) UpdateAndValidate(n *core.Node) {
 }
 
 func (c *ValueAction) UI(n *core.Node) {
-	clay.CLAY(clay.IDI("NodeContent", n.ID), clay.EL{
-		Layout: clay.LAY{
-			Sizing:         core.GROWH,
-			ChildAlignment: core.YCENTER,
-		},
+	clay.CLAY(clay.IDI("ValueUI", n.ID), clay.EL{
+		Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S2},
 	}, func() {
-		// Just show the value
-		core.UIFlowValue(clay.IDI("FlowValue", n.ID), c.Value)
-		core.UISpacer(clay.IDI("ValueSpacer", n.ID), core.GROWH)
-		core.UIOutputPort(n, 0)
+		clay.CLAY(clay.IDI("NodeContent", n.ID), clay.EL{
+			Layout: clay.LAY{
+				Sizing:         core.GROWH,
+				ChildAlignment: core.YCENTER,
+			},
+		}, func() {
+			core.UIFlowValue(clay.IDI("FlowValue", n.ID), c.Value)
+			core.UISpacer(clay.IDI("ValueSpacer", n.ID), core.GROWH)
+			core.UIOutputPort(n, 0)
+		})
+
+		// The value can be typed in as a literal, like [1, 2, 3] or {a: "x"}.
+		textID := clay.IDI("ValueText", n.ID)
+		if c.literal == "" {
+			c.literal = c.Value.String()
+			c.shown = c.literal
+			if len(c.shown) > valueShownLen {
+				cut := valueShownLen
+				for !utf8.RuneStart(c.shown[cut]) {
+					cut--
+				}
+				c.shown = c.shown[:cut] + "..."
+			}
+		}
+		if core.IsFocused(textID) {
+			// The whole literal is edited, not just what was shown.
+			if !c.editing && c.parseErr == nil {
+				c.text = c.literal
+			}
+			c.editing = true
+		} else {
+			c.editing = false
+			if c.parseErr == nil {
+				c.text = c.shown
+			}
+		}
+		core.UITextBox(textID, &c.text, core.UITextBoxConfig{
+			El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH}},
+			OnSubmit: func(val string) {
+				c.SetText(n, val)
+			},
+		})
+		if c.parseErr != nil {
+			clay.TEXT(c.parseErr.Error(), clay.TextElementConfig{TextColor: core.Red})
+		}
 	})
 }
 
+// SetText sets the value to the literal in text, as ParseValue reads it. If
+// text does not parse, the value stays as it was, and the error is shown.
+func (c *ValueAction) SetText(n *core.Node, text string) error {
+	v, err := core.ParseValue(text)
+	c.text, c.parseErr = text, err
+	if err != nil {
+		return err
+	}
+	c.Value, c.literal = v, ""
+	n.OutputPorts[0].Type = *v.Type
+	n.ClearResult()
+	core.PushHistory()
+	return nil
+}
+
 func (c *ValueAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
 	done := make(chan core.NodeActionResult)
 	go func() {
@@ -68,15 +68,7 @@ func (a *GetVariableAction) RunContext(ctx context.Context, n *core.Node) <-chan
 
 		val, found := os.LookupEnv(a.VariableName)
 		if !found {
-			if n.Graph != nil {
-				n.Graph.VarMutex.RLock()
-				v, ok := n.Graph.Variables[a.VariableName]
-				n.Graph.VarMutex.RUnlock()
-				if ok {
-					val = v
-					found = true
-				}
-			}
+			val, found = n.Graph.LookupVariable(a.VariableName)
 		}
 
 		if !found {
@@ -95,3 +87,8 @@ func (a *GetVariableAction) Serialize(s *core.Serializer) bool {
 	core.SStr(s, &a.VariableName)
 	return s.Ok()
 }
+
+// Variables live outside the node, in the graph and environment.
+func (a *GetVariableAction) HasSideEffects() bool {
+	return true
+}
@@ -47,7 +47,7 @@ func (a *PluginAction) UI(n *core.Node) {
 			core.UIInputPort(n, i)
 		}
 		// Outputs
-		for i := range n.OutputPorts {
+		for i := range n.ActionOutputPorts() {
 			core.UIOutputPort(n, i)
 		}
 	})
@@ -109,21 +109,23 @@ func (a *PluginAction) Run(n *core.Node) <-chan core.NodeActionResult {
 
 		// Map results to outputs
 		var outputs []core.FlowValue
-		for _, port := range n.OutputPorts {
-			if val, ok := resp.Result[port.Name]; ok {
-				fv, err := core.NativeToFlowValue(val)
+		for _, port := range n.ActionOutputPorts() {
+			val, ok := resp.Result[port.Name]
+			if !ok || val == nil {
+				fv, err := pluginMissingValue(port)
 				if err != nil {
-					// Fallback
-					outputs = append(outputs, core.NewStringValue(fmt.Sprintf("error converting: %v", err)))
-				} else {
-					outputs = append(outputs, fv)
+					done <- core.NodeActionResult{Err: err}
+					return
 				}
+				outputs = append(outputs, fv)
+				continue
+			}
+			fv, err := core.NativeToFlowValue(val)
+			if err != nil {
+				// Fallback
+				outputs = append(outputs, core.NewStringValue(fmt.Sprintf("error converting: %v", err)))
 			} else {
-				// Default/Zero value for type? Or just empty string/nil?
-				// core.NativeToFlowValue(nil) returns empty string value.
-				// But we should probably try to match the port type if possible?
-				// For now, empty string is safe-ish.
-				outputs = append(outputs, core.NewStringValue(""))
+				outputs = append(outputs, fv)
 			}
 		}
 
@@ -132,6 +134,26 @@ func (a *PluginAction) Run(n *core.Node) <-chan core.NodeActionResult {
 	return done
 }
 
+// pluginMissingValue is the value of a result the plugin left out or returned
+// as null. It is null only if the port allows it. Otherwise it is the zero
+// value of the port's kind, as a missing string result was always "".
+func pluginMissingValue(port core.NodePort) (core.FlowValue, error) {
+	if port.Type.Nullable || port.Type.Kind == core.FSKindAny {
+		return core.NewNullValue(port.Type), nil
+	}
+	switch port.Type.Kind {
+	case core.FSKindBytes:
+		return core.NewStringValue(""), nil
+	case core.FSKindInt64:
+		return core.NewInt64Value(0, port.Type.Unit), nil
+	case core.FSKindFloat64:
+		return core.NewFloat64Value(0, port.Type.Unit), nil
+	case core.FSKindBool:
+		return core.NewBoolValue(false), nil
+	}
+	return core.FlowValue{}, fmt.Errorf("plugin returned no value for result %q", port.Name)
+}
+
 func (a *PluginAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
 	return a.Run(n)
 }
@@ -149,6 +171,14 @@ func (a *PluginAction) Serialize(s *core.Serializer) bool {
 	return s.Ok()
 }
 
+func (a *PluginAction) HasSideEffects() bool {
+	return true
+}
+
+func (a *PluginAction) SchedCategory() string {
+	return core.SchedCategoryProcess
+}
+
 // Helpers
 
 // Loader
@@ -231,7 +261,12 @@ func LoadPlugins(dir string) ([]NodeType, error) {
 	return nodes, nil
 }
 
+// parsePluginType parses a type name from a plugin manifest. A trailing "?"
+// makes the type nullable, as in "int?".
 func parsePluginType(t string) core.FlowType {
+	if base, ok := strings.CutSuffix(t, "?"); ok {
+		return core.NullableType(parsePluginType(base))
+	}
 	switch t {
 	case "string":
 		return core.FlowType{Kind: core.FSKindBytes}
@@ -241,6 +276,8 @@ func parsePluginType(t string) core.FlowType {
 		return core.FlowType{Kind: core.FSKindInt64, Unit: 0}
 	case "float":
 		return core.FlowType{Kind: core.FSKindFloat64, Unit: 0}
+	case "bool":
+		return core.FlowType{Kind: core.FSKindBool}
 	default:
 		return core.FlowType{Kind: core.FSKindAny}
 	}
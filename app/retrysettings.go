@@ -0,0 +1,151 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+)
+
+// The node whose retry policy and timeout are being edited, if any. The
+// fields are edited as text and only applied when the user confirms.
+var (
+	RetrySettingsNode *core.Node
+
+	retryAttempts string
+	retryBackoff  string
+	retryErrors   string
+	retryCodes    string
+	retryTimeout  string
+	retryError    string
+)
+
+func OpenRetrySettings(node *core.Node) {
+	RetrySettingsNode = node
+	retryAttempts = strconv.Itoa(max(node.Retry.MaxAttempts, 1))
+	retryBackoff = formatDuration(node.Retry.Backoff)
+	retryErrors = node.Retry.ErrorPattern
+	retryCodes = node.Retry.RetryCodes
+	retryTimeout = formatDuration(node.Timeout)
+	retryError = ""
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func parseDuration(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(str)
+}
+
+func applyRetrySettings() error {
+	attempts, err := strconv.Atoi(strings.TrimSpace(retryAttempts))
+	if err != nil || attempts < 1 {
+		return fmt.Errorf("attempts must be a positive number")
+	}
+	backoff, err := parseDuration(retryBackoff)
+	if err != nil {
+		return fmt.Errorf("bad backoff: %v", err)
+	}
+	timeout, err := parseDuration(retryTimeout)
+	if err != nil || timeout < 0 {
+		return fmt.Errorf("bad timeout: %q", retryTimeout)
+	}
+	policy := core.RetryPolicy{
+		MaxAttempts:  attempts,
+		Backoff:      backoff,
+		ErrorPattern: retryErrors,
+		RetryCodes:   retryCodes,
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	core.PushHistory()
+	RetrySettingsNode.Retry = policy
+	RetrySettingsNode.Timeout = timeout
+	return nil
+}
+
+func uiRetrySettingsField(id string, label string, str *string) {
+	clay.CLAY(clay.AUTO_ID, clay.EL{Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, ChildGap: core.S1}}, func() {
+		clay.TEXT(label, clay.TextElementConfig{TextColor: core.Gray, FontSize: 10})
+		core.UITextBox(clay.ID(id), str, core.UITextBoxConfig{
+			El: clay.EL{Layout: clay.LAY{Sizing: core.GROWH, Padding: core.PA1}, BackgroundColor: clay.Color{R: 40, G: 40, B: 40, A: 255}, CornerRadius: core.RA1},
+		})
+	})
+}
+
+func UIRetrySettings() {
+	if RetrySettingsNode == nil {
+		return
+	}
+
+	core.WithZIndex(core.Z_MODAL, func() {
+		clay.CLAY(clay.ID("OverlayRetryPanel"), clay.EL{
+			Layout:          clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: clay.Sizing{Width: clay.SizingFixed(360)}, Padding: core.PA3, ChildGap: core.S2},
+			BackgroundColor: core.Charcoal,
+			Border:          clay.BorderElementConfig{Width: core.BA2, Color: core.Gray},
+			CornerRadius:    core.RA2,
+			Floating: clay.FLOAT{
+				AttachTo: clay.AttachToParent,
+				AttachPoints: clay.FloatingAttachPoints{
+					Element: clay.AttachPointCenterCenter,
+					Parent:  clay.AttachPointCenterCenter,
+				},
+				ZIndex:             core.Z_MODAL,
+				PointerCaptureMode: clay.PointercaptureModeCapture,
+			},
+		}, func() {
+			clay.OnHover(func(elementID clay.ElementID, pointerData clay.PointerData, userData any) {
+				core.IsHoveringUI = true
+			}, nil)
+
+			clay.TEXT("Retries and Timeout: "+RetrySettingsNode.Name, clay.TextElementConfig{TextColor: core.White, FontID: core.InterBold, FontSize: core.F2})
+
+			uiRetrySettingsField("RetryAttemptsInput", "Max attempts", &retryAttempts)
+			uiRetrySettingsField("RetryBackoffInput", "Backoff before first retry (e.g. 500ms, doubles each retry)", &retryBackoff)
+			uiRetrySettingsField("RetryErrorsInput", "Retry errors matching (regexp, empty for all)", &retryErrors)
+			uiRetrySettingsField("RetryCodesInput", "Also retry on exit/status codes (e.g. 502,503)", &retryCodes)
+			uiRetrySettingsField("RetryTimeoutInput", "Timeout per attempt (e.g. 30s, empty for none)", &retryTimeout)
+
+			if retryError != "" {
+				clay.TEXT(retryError, clay.TextElementConfig{TextColor: core.Red})
+			}
+
+			clay.CLAY(clay.AUTO_ID, clay.EL{Layout: clay.LAY{Sizing: core.GROWH, ChildGap: core.S2}}, func() {
+				clay.CLAY(clay.AUTO_ID, clay.EL{Layout: clay.LAY{Sizing: core.GROWH}}) // Spacer
+				core.UIButton(clay.ID("RetryApply"), core.UIButtonConfig{
+					El: clay.EL{Layout: clay.LAY{Padding: core.PA2}, BackgroundColor: core.Green, CornerRadius: core.RA1},
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						if err := applyRetrySettings(); err != nil {
+							retryError = err.Error()
+							return
+						}
+						RetrySettingsNode = nil
+					},
+				}, func() {
+					clay.TEXT("Apply", clay.TextElementConfig{TextColor: core.White, FontID: core.InterBold})
+				})
+				core.UIButton(clay.ID("RetryCancel"), core.UIButtonConfig{
+					El: clay.EL{Layout: clay.LAY{Padding: core.PA2}, BackgroundColor: core.Red, CornerRadius: core.RA1},
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						RetrySettingsNode = nil
+					},
+				}, func() {
+					clay.TEXT("Cancel", clay.TextElementConfig{TextColor: core.White})
+				})
+			})
+		})
+	})
+}
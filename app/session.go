@@ -0,0 +1,522 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/clay"
+	"github.com/bvisness/flowshell/util"
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// The serialized graph as of the last save or load. The graph has unsaved
+// changes if it no longer serializes to this.
+var savedGraphData []byte
+var HasUnsavedChanges bool
+
+var lastDirtyCheck time.Time
+var lastAutosave time.Time
+var autosaveInFlight atomic.Bool
+
+// The graph as of the last successful autosave, and the error of the last
+// autosave if it failed. They are set by the goroutine that writes the
+// recovery file.
+var autosaveMu sync.Mutex
+var autosavedGraphData []byte
+var autosaveErr error
+
+var windowTitle string
+
+// How often to check the graph for unsaved changes. Serializing the graph is
+// cheap, but not cheap enough to do every frame.
+const dirtyCheckInterval = 500 * time.Millisecond
+
+// MarkSaved records the current graph as saved, e.g. after saving or loading
+// it, and removes any recovery file.
+func MarkSaved() {
+	markClean()
+	RemoveRecovery()
+}
+
+func markClean() {
+	data, err := serializeSession(RootGraph())
+	if err != nil {
+		data = nil
+	}
+	savedGraphData = data
+	autosaveMu.Lock()
+	autosavedGraphData, autosaveErr = nil, nil
+	autosaveMu.Unlock()
+	HasUnsavedChanges = false
+}
+
+// serializeSession serializes a graph for the unsaved changes check and the
+// recovery file. Graphs that cannot be saved in the binary format, such as
+// those with unknown nodes loaded from a text file, use the text format.
+func serializeSession(g *core.Graph) ([]byte, error) {
+	data, err := core.SerializeGraph(g)
+	if err != nil {
+		var textErr error
+		if data, textErr = core.SerializeGraphText(g); textErr != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// CheckUnsavedChanges sets HasUnsavedChanges by comparing the graph with the
+// last save or load, and returns the graph serialized.
+func CheckUnsavedChanges() ([]byte, error) {
+	data, err := serializeSession(RootGraph())
+	HasUnsavedChanges = err != nil || !bytes.Equal(data, savedGraphData)
+	return data, err
+}
+
+// UpdateSession checks for unsaved changes, autosaves the graph if it is time,
+// and keeps the window title up to date. It runs every frame.
+func UpdateSession() {
+	now := time.Now()
+	if now.Sub(lastDirtyCheck) >= dirtyCheckInterval {
+		lastDirtyCheck = now
+		data, err := CheckUnsavedChanges()
+
+		autosaveMu.Lock()
+		autosaved := autosavedGraphData
+		autosaveMu.Unlock()
+
+		interval := time.Duration(CurrentSettings.AutosaveSeconds) * time.Second
+		if HasUnsavedChanges && interval > 0 && now.Sub(lastAutosave) >= interval && !bytes.Equal(data, autosaved) {
+			lastAutosave = now
+			if err != nil {
+				setAutosaveResult(nil, err)
+			} else {
+				autosave(data)
+			}
+		}
+	}
+
+	title := "Flowshell"
+	if CurrentFilename != "" {
+		title = fmt.Sprintf("%s - Flowshell", filepath.Base(CurrentFilename))
+	}
+	if HasUnsavedChanges {
+		title = "* " + title
+		autosaveMu.Lock()
+		if autosaveErr != nil {
+			title += fmt.Sprintf(" (autosave failed: %v)", autosaveErr)
+		}
+		autosaveMu.Unlock()
+	}
+	if title != windowTitle {
+		windowTitle = title
+		rl.SetWindowTitle(title)
+	}
+}
+
+// ---------------------------
+// Saving and loading
+
+// SaveCurrentGraph saves the graph to filename, or asks for a filename if it
+// is empty.
+func SaveCurrentGraph(filename string) {
+	if filename == "" {
+		SaveCurrentGraphAs()
+		return
+	}
+	if err := core.SaveGraph(filename, RootGraph()); err != nil {
+		fmt.Printf("Save error: %v\n", err)
+		core.ShowInfoDialog("Error", fmt.Sprintf("Failed to save file: %v", err))
+		return
+	}
+	CurrentFilename = filename
+	MarkSaved()
+}
+
+func SaveCurrentGraphAs() {
+	initialDir, _ := os.Getwd()
+	if CurrentFilename != "" {
+		initialDir = filepath.Dir(CurrentFilename)
+	}
+	filename, ok, err := core.SaveFileDialog("Save Flow", initialDir, map[string]string{"flow": "Flow Files"})
+	if err != nil {
+		fmt.Printf("Save error: %v\n", err)
+	} else if ok {
+		// Ensure extension
+		if filepath.Ext(filename) != ".flow" && !core.IsTextFormatPath(filename) {
+			filename += ".flow"
+		}
+		SaveCurrentGraph(filename)
+	}
+}
+
+// OpenGraph asks for a flow and loads it, discarding the current graph.
+func OpenGraph() {
+	cwd, _ := os.Getwd()
+	filename, ok, err := core.OpenFileDialog("Open Flow", cwd, map[string]string{"flow": "Flow Files"})
+	if err != nil {
+		fmt.Printf("Load error: %v\n", err)
+		core.ShowInfoDialog("Error", fmt.Sprintf("Failed to open file dialog: %v", err))
+	} else if ok {
+		core.PushHistory()
+		g, err := core.LoadGraph(filename)
+		var unknown *core.UnknownActionError
+		if err == nil {
+			SetRootGraph(g)
+			CurrentFilename = filename
+			MarkSaved()
+		} else if g != nil && errors.As(err, &unknown) {
+			// Open what could be read of an old file as a new, unsaved flow,
+			// so that saving it does not overwrite the original.
+			SetRootGraph(g)
+			CurrentFilename = ""
+			MarkSaved()
+			savedGraphData = nil
+			core.ShowInfoDialog("Partly loaded", fmt.Sprintf("%v\n\nOnly the %d node(s) before it were opened, without wires. Save them under a new name to keep them.", err, len(g.Nodes)))
+		} else {
+			core.ShowInfoDialog("Error", fmt.Sprintf("Failed to load file: %v", err))
+		}
+	}
+}
+
+func NewGraph() {
+	core.PushHistory()
+	SetRootGraph(core.NewGraph())
+	CurrentFilename = ""
+	MarkSaved()
+}
+
+// ---------------------------
+// Confirming discarded changes
+
+type discardConfirmation struct {
+	Message string
+	Button  string
+	Then    func()
+}
+
+var PendingDiscard *discardConfirmation
+
+// ConfirmDiscardChanges runs then right away if there are no unsaved changes,
+// and otherwise asks first. message is the question to ask, and button the
+// label of the button that discards the changes.
+func ConfirmDiscardChanges(message, button string, then func()) {
+	if CheckUnsavedChanges(); !HasUnsavedChanges {
+		then()
+		return
+	}
+	PendingDiscard = &discardConfirmation{Message: message, Button: button, Then: then}
+}
+
+func UIDiscardConfirmation() {
+	if PendingDiscard == nil {
+		return
+	}
+	confirm := PendingDiscard
+	UIConfirmModal("DiscardConfirmation", confirm.Message, "You have unsaved changes.", confirm.Button, "Cancel",
+		func() {
+			PendingDiscard = nil
+			confirm.Then()
+		},
+		func() {
+			PendingDiscard = nil
+		},
+	)
+}
+
+// UIConfirmModal shows a modal with a title, a message, a red confirm button
+// and a gray cancel button.
+func UIConfirmModal(id, title, message, confirmLabel, cancelLabel string, onConfirm, onCancel func()) {
+	core.WithZIndex(core.Z_MODAL, func() {
+		clay.CLAY(clay.ID(id), clay.EL{
+			Layout:          clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: clay.Sizing{Width: clay.SizingFixed(340)}, ChildAlignment: core.ALLCENTER, Padding: core.PA3, ChildGap: core.S2},
+			BackgroundColor: core.Charcoal,
+			Border:          clay.BorderElementConfig{Width: core.BA2, Color: core.Red},
+			CornerRadius:    core.RA2,
+			Floating: clay.FLOAT{
+				AttachTo: clay.AttachToParent,
+				AttachPoints: clay.FloatingAttachPoints{
+					Element: clay.AttachPointCenterCenter,
+					Parent:  clay.AttachPointCenterCenter,
+				},
+				ZIndex:             core.Z_MODAL,
+				PointerCaptureMode: clay.PointercaptureModeCapture,
+			},
+		}, func() {
+			clay.OnHover(func(elementID clay.ElementID, pointerData clay.PointerData, userData any) {
+				core.IsHoveringUI = true
+			}, nil)
+			clay.TEXT(title, clay.TextElementConfig{TextColor: core.White, FontID: core.InterBold, FontSize: core.F2})
+			if message != "" {
+				clay.TEXT(message, clay.TextElementConfig{TextColor: core.LightGray})
+			}
+			clay.CLAY(clay.AUTO_ID, clay.EL{Layout: clay.LAY{ChildGap: core.S3}}, func() {
+				core.UIButton(clay.ID(id+"Confirm"), core.UIButtonConfig{
+					El: clay.EL{Layout: clay.LAY{Padding: core.PA2}, BackgroundColor: core.Red, CornerRadius: core.RA1},
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						onConfirm()
+					},
+				}, func() {
+					clay.TEXT(confirmLabel, clay.TextElementConfig{TextColor: core.White})
+				})
+				core.UIButton(clay.ID(id+"Cancel"), core.UIButtonConfig{
+					El: clay.EL{Layout: clay.LAY{Padding: core.PA2}, BackgroundColor: core.Gray, CornerRadius: core.RA1},
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						onCancel()
+					},
+				}, func() {
+					clay.TEXT(cancelLabel, clay.TextElementConfig{TextColor: core.White})
+				})
+			})
+		})
+	})
+}
+
+// ---------------------------
+// Autosave and recovery
+
+// RecoveryInfo describes a recovery file, and is saved next to it.
+type RecoveryInfo struct {
+	Filename string    `json:"filename"` // the file the session was editing, if any
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// Each instance has its own recovery file, named for its process ID, so that
+// instances running at once do not overwrite each other's. A file whose
+// process is no longer running was left by a session that did not exit
+// cleanly.
+func getRecoveryDir() string {
+	return filepath.Join(filepath.Dir(GetSettingsPath()), "recovery")
+}
+
+// Recovery files are in the binary format unless the graph can only be saved
+// as text, in which case they get a .json extension like other text flows.
+const (
+	recoveryExt     = ".flow"
+	recoveryTextExt = ".flow.json"
+)
+
+// GetRecoveryPath returns the recovery file of this instance.
+func GetRecoveryPath() string {
+	if path := getRecoveryPathFor(os.Getpid(), true); fileExists(path) {
+		return path
+	}
+	return getRecoveryPathFor(os.Getpid(), false)
+}
+
+func getRecoveryPathFor(pid int, text bool) string {
+	return filepath.Join(getRecoveryDir(), fmt.Sprintf("%d%s", pid, util.Tern(text, recoveryTextExt, recoveryExt)))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func getRecoveryInfoPathFor(pid int) string {
+	return filepath.Join(getRecoveryDir(), fmt.Sprintf("%d.json", pid))
+}
+
+// autosave writes the serialized graph to the recovery file in the
+// background. If a write is still in progress, this autosave is skipped.
+func autosave(data []byte) {
+	if !autosaveInFlight.CompareAndSwap(false, true) {
+		return
+	}
+	info := RecoveryInfo{Filename: CurrentFilename, SavedAt: time.Now()}
+	go func() {
+		defer autosaveInFlight.Store(false)
+		setAutosaveResult(data, WriteRecovery(data, info))
+	}()
+}
+
+// setAutosaveResult records the outcome of an autosave of data. The graph only
+// counts as autosaved if it succeeded, so a failed autosave is tried again at
+// the next interval. The error is shown in the window title until then.
+func setAutosaveResult(data []byte, err error) {
+	autosaveMu.Lock()
+	defer autosaveMu.Unlock()
+	if err != nil {
+		// Report each failure once rather than on every attempt.
+		if autosaveErr == nil || err.Error() != autosaveErr.Error() {
+			fmt.Printf("Autosave failed: %v\n", err)
+		}
+	} else {
+		autosavedGraphData = data
+	}
+	autosaveErr = err
+}
+
+// WriteRecovery writes a serialized graph to the recovery file of this
+// instance. The file is replaced in one step, so a crash while writing leaves
+// the old one intact.
+func WriteRecovery(data []byte, info RecoveryInfo) error {
+	infoData, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(getRecoveryDir(), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(getRecoveryInfoPathFor(os.Getpid()), infoData); err != nil {
+		return err
+	}
+	text := core.IsTextFormat(data)
+	if err := writeFileAtomic(getRecoveryPathFor(os.Getpid(), text), data); err != nil {
+		return err
+	}
+	// Remove the file in the other format, left by an earlier autosave.
+	_ = os.Remove(getRecoveryPathFor(os.Getpid(), !text))
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RemoveRecovery deletes the recovery file of this instance. It is called
+// whenever there are no unsaved changes to recover, including on a clean exit.
+func RemoveRecovery() {
+	for autosaveInFlight.Load() {
+		time.Sleep(time.Millisecond)
+	}
+	_ = os.Remove(getRecoveryPathFor(os.Getpid(), false))
+	_ = os.Remove(getRecoveryPathFor(os.Getpid(), true))
+	_ = os.Remove(getRecoveryInfoPathFor(os.Getpid()))
+}
+
+// LoadRecovery loads the graph from a recovery file left by a session that
+// did not exit cleanly. ok is false if there is none.
+//
+// The file is first renamed to this instance's, so that no other instance
+// recovers it too, and so that it stays until the changes are saved or
+// discarded.
+func LoadRecovery() (g *core.Graph, info RecoveryInfo, ok bool, err error) {
+	entries, err := os.ReadDir(getRecoveryDir())
+	if os.IsNotExist(err) {
+		return nil, info, false, nil
+	} else if err != nil {
+		return nil, info, false, err
+	}
+	claimed := false
+	for _, e := range entries {
+		text := strings.HasSuffix(e.Name(), recoveryTextExt)
+		if !text && !strings.HasSuffix(e.Name(), recoveryExt) {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSuffix(e.Name(), util.Tern(text, recoveryTextExt, recoveryExt)))
+		if err != nil || pid == os.Getpid() || processRunning(pid) {
+			continue
+		}
+		// Another instance may claim it first.
+		if os.Rename(getRecoveryPathFor(pid, text), getRecoveryPathFor(os.Getpid(), text)) == nil {
+			_ = os.Remove(getRecoveryPathFor(os.Getpid(), !text))
+			_ = os.Rename(getRecoveryInfoPathFor(pid), getRecoveryInfoPathFor(os.Getpid()))
+			claimed = true
+			break
+		}
+	}
+	if !claimed {
+		return nil, info, false, nil
+	}
+
+	data, err := os.ReadFile(GetRecoveryPath())
+	if err != nil {
+		return nil, info, false, err
+	}
+	if infoData, err := os.ReadFile(getRecoveryInfoPathFor(os.Getpid())); err == nil {
+		_ = json.Unmarshal(infoData, &info)
+	}
+	g, err = core.DeserializeGraph(data)
+	if err != nil {
+		return nil, info, false, err
+	}
+	return g, info, true, nil
+}
+
+// processRunning reports whether the process with the given ID is running.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		// There, FindProcess fails if the process does not exist.
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// A recovered session waiting for the user to restore or discard it.
+type pendingRecovery struct {
+	Graph *core.Graph
+	Info  RecoveryInfo
+}
+
+var PendingRecovery *pendingRecovery
+
+// StartSession starts tracking changes to the initial graph, and looks for a
+// session to recover.
+func StartSession() {
+	markClean()
+	g, info, ok, err := LoadRecovery()
+	if err != nil {
+		fmt.Printf("Failed to read recovery file: %v\n", err)
+		return
+	}
+	if ok {
+		PendingRecovery = &pendingRecovery{Graph: g, Info: info}
+	}
+}
+
+func UIRecoveryPrompt() {
+	if PendingRecovery == nil {
+		return
+	}
+	recovery := PendingRecovery
+	name := "an untitled flow"
+	if recovery.Info.Filename != "" {
+		name = filepath.Base(recovery.Info.Filename)
+	}
+	message := fmt.Sprintf("Flowshell did not exit cleanly. Restore unsaved changes to %s", name)
+	if !recovery.Info.SavedAt.IsZero() {
+		message += fmt.Sprintf(" from %s", recovery.Info.SavedAt.Format("Jan 2 15:04"))
+	}
+	message += "?"
+	UIConfirmModal("RecoveryPrompt", "Restore unsaved session?", message, "Restore", "Discard",
+		func() {
+			PendingRecovery = nil
+			core.PushHistory()
+			SetRootGraph(recovery.Graph)
+			CurrentFilename = recovery.Info.Filename
+			// The restored changes are still unsaved.
+			savedGraphData = nil
+			if recovery.Info.Filename != "" {
+				if saved, err := core.LoadGraph(recovery.Info.Filename); err == nil {
+					savedGraphData, _ = serializeSession(saved)
+				}
+			}
+		},
+		func() {
+			PendingRecovery = nil
+			RemoveRecovery()
+		},
+	)
+}
@@ -3,6 +3,9 @@
 import (
 	"encoding/json"
 	"os"
+	"runtime"
+
+	"github.com/bvisness/flowshell/app/core"
 )
 
 type Settings struct {
@@ -11,6 +14,18 @@ type Settings struct {
 	WindowMaximized  bool   `json:"window_maximized"`
 	Theme            string `json:"theme"`
 	MinimapThreshold int    `json:"minimap_threshold"`
+
+	// Directory for persisted node results. Empty disables the on-disk cache.
+	CacheDir string `json:"cache_dir"`
+
+	// The maximum number of node actions running at once (0 for unlimited),
+	// and limits for particular kinds of nodes, e.g. {"http": 2, "process": 4}.
+	MaxParallelism    int            `json:"max_parallelism"`
+	ConcurrencyLimits map[string]int `json:"concurrency_limits"`
+
+	// How often unsaved changes are written to the recovery file. 0 disables
+	// autosave.
+	AutosaveSeconds int `json:"autosave_seconds"`
 }
 
 var CurrentSettings *Settings
@@ -22,6 +37,11 @@ func DefaultSettings() *Settings {
 		WindowMaximized:  false,
 		Theme:            "Dark",
 		MinimapThreshold: 10,
+		AutosaveSeconds:  30,
+		ConcurrencyLimits: map[string]int{
+			core.SchedCategoryHTTP:    4,
+			core.SchedCategoryProcess: runtime.NumCPU(),
+		},
 	}
 }
 
@@ -37,8 +57,10 @@ func LoadSettings() *Settings {
 		return DefaultSettings()
 	}
 
-	var s Settings
-	if err := json.Unmarshal(data, &s); err != nil {
+	// Start from the defaults so that settings added since the file was
+	// written get sensible values.
+	s := DefaultSettings()
+	if err := json.Unmarshal(data, s); err != nil {
 		return DefaultSettings()
 	}
 
@@ -50,7 +72,12 @@ func LoadSettings() *Settings {
 		s.WindowHeight = 600
 	}
 
-	return &s
+	return s
+}
+
+// Scheduler creates a node scheduler with the configured concurrency limits.
+func (s *Settings) Scheduler() *core.Scheduler {
+	return core.NewScheduler(s.MaxParallelism, s.ConcurrencyLimits)
 }
 
 func SaveSettings(s *Settings) error {
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/bvisness/flowshell/clay"
+)
+
+// While a subgraph is open for editing, CurrentGraph is its embedded graph,
+// and OpenSubgraphs holds the subgraph nodes leading to it from the root
+// graph, outermost first. Saving, history, and running use the root graph.
+var OpenSubgraphs []*core.Node
+
+func init() {
+	core.OpenSubgraphFunc = EnterSubgraph
+}
+
+// RootGraph returns the graph of the open flow, which is CurrentGraph unless a
+// subgraph is open.
+func RootGraph() *core.Graph {
+	if len(OpenSubgraphs) > 0 {
+		return OpenSubgraphs[0].Graph
+	}
+	return CurrentGraph
+}
+
+// EnterSubgraph opens the embedded graph g of the subgraph node n, which must
+// be in CurrentGraph.
+func EnterSubgraph(n *core.Node, g *core.Graph) {
+	g.Parent = n.Graph
+	OpenSubgraphs = append(OpenSubgraphs, n)
+	CurrentGraph = g
+	clear(SelectedNodes)
+	selectedNodeID = 0
+	ContextMenu = nil
+}
+
+// ExitSubgraphs closes subgraphs until depth remain open, selecting the node
+// of the innermost one closed.
+func ExitSubgraphs(depth int) {
+	if depth >= len(OpenSubgraphs) {
+		return
+	}
+	n := OpenSubgraphs[depth]
+	OpenSubgraphs = OpenSubgraphs[:depth]
+	CurrentGraph = n.Graph
+	SelectNode(n.ID, false)
+	ContextMenu = nil
+}
+
+// SetRootGraph replaces the open flow, closing any open subgraphs.
+func SetRootGraph(g *core.Graph) {
+	OpenSubgraphs = nil
+	CurrentGraph = g
+	clear(SelectedNodes)
+	selectedNodeID = 0
+}
+
+// RestoreRootGraph replaces the root graph with another version of it, as on
+// undo, and reopens the same subgraphs as far as they still exist.
+func RestoreRootGraph(g *core.Graph) {
+	path := OpenSubgraphs
+	SetRootGraph(g)
+	for _, old := range path {
+		n, ok := CurrentGraph.GetNode(old.ID)
+		if !ok {
+			break
+		}
+		g, ok := nodes.EmbeddedGraph(n)
+		if !ok {
+			break
+		}
+		EnterSubgraph(n, g)
+	}
+}
+
+// CollapseNodes replaces the nodes with the given IDs by a subgraph node.
+func CollapseNodes(ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+	core.PushHistory()
+	n, err := nodes.CollapseToSubgraph(CurrentGraph, ids)
+	if err != nil {
+		core.ShowInfoDialog("Collapse to Subgraph", fmt.Sprintf("Cannot collapse: %v", err))
+		return
+	}
+	n.Pos = SnapToGrid(n.Pos)
+	SelectNode(n.ID, false)
+}
+
+func CollapseSelectedNodes() {
+	CollapseNodes(slices.Sorted(maps.Keys(SelectedNodes)))
+}
+
+// UISubgraphBar shows the path to the open subgraph, with a button for each
+// enclosing graph.
+func UISubgraphBar() {
+	if len(OpenSubgraphs) == 0 {
+		return
+	}
+	core.WithZIndex(core.Z_CONTEXT_MENU, func() {
+		clay.CLAY(clay.ID("SubgraphBar"), clay.EL{
+			Layout:          clay.LAY{Padding: core.PVH(core.S1, core.S2), ChildGap: core.S1, ChildAlignment: core.YCENTER},
+			BackgroundColor: core.Charcoal,
+			Border:          clay.B{Width: core.BA, Color: core.Gray},
+			CornerRadius:    core.RA1,
+			Floating: clay.FLOAT{
+				AttachTo:           clay.AttachToRoot,
+				AttachPoints:       clay.FloatingAttachPoints{Element: clay.AttachPointCenterTop, Parent: clay.AttachPointCenterTop},
+				Offset:             clay.Vector2{Y: 40},
+				PointerCaptureMode: clay.PointercaptureModeCapture,
+				ZIndex:             core.Z_CONTEXT_MENU,
+			},
+		}, func() {
+			crumb := func(depth int, label string) {
+				core.UIButton(clay.IDI("SubgraphCrumb", depth), core.UIButtonConfig{
+					El: clay.EL{Layout: clay.LAY{Padding: core.PVH(core.S1, core.S2)}},
+					OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
+						ExitSubgraphs(depth)
+					},
+				}, func() {
+					clay.TEXT(label, clay.TextElementConfig{TextColor: core.LightGray})
+				})
+				clay.TEXT("›", clay.TextElementConfig{TextColor: core.Gray})
+			}
+			crumb(0, "Flow")
+			for i, n := range OpenSubgraphs[:len(OpenSubgraphs)-1] {
+				crumb(i+1, n.Name)
+			}
+			clay.TEXT(OpenSubgraphs[len(OpenSubgraphs)-1].Name, clay.TextElementConfig{TextColor: core.White, FontID: core.InterSemibold})
+		})
+	})
+}
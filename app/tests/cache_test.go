@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func runAndWait(t *testing.T, n *core.Node) core.NodeActionResult {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	<-n.Run(ctx, false)
+	res, ok := n.GetResult()
+	assert.True(t, ok)
+	return res
+}
+
+func TestResultCache(t *testing.T) {
+	t.Run("Unchanged node is not re-executed", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		setupGraph(node, core.NewStringValue("hi"))
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "hi!", string(res.Outputs[0].BytesValue))
+
+		res = runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "hi!", string(res.Outputs[0].BytesValue))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("Changed settings re-execute", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		setupGraph(node, core.NewStringValue("hi"))
+
+		runAndWait(t, node)
+		node.Action.(*fakeAction).Text = "?"
+		res := runAndWait(t, node)
+		assert.Equal(t, "hi?", string(res.Outputs[0].BytesValue))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("Changed inputs re-execute", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		g := setupGraph(node, core.NewStringValue("hi"))
+
+		runAndWait(t, node)
+		input := g.Nodes[1]
+		input.SetResult(core.NodeActionResult{Outputs: []core.FlowValue{core.NewStringValue("bye")}})
+		res := runAndWait(t, node)
+		assert.Equal(t, "bye!", string(res.Outputs[0].BytesValue))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		var runs int32
+		a := newCountingNode(&runs)
+		b := newCountingNode(&runs)
+		setupGraph(a, core.NewStringValue("x"))
+		setupGraph(b, core.NewStringValue("x"))
+
+		keyA, ok := a.ResultKey()
+		assert.True(t, ok)
+		keyB, ok := b.ResultKey()
+		assert.True(t, ok)
+		assert.Equal(t, keyA, keyB)
+
+		proc := nodes.NewRunProcessNode("echo hi")
+		_, ok = proc.ResultKey()
+		assert.False(t, ok, "nodes with side effects should not be cached")
+	})
+}
+
+func TestPersistentResultCache(t *testing.T) {
+	core.ResultCacheDir = t.TempDir()
+	defer func() { core.ResultCacheDir = "" }()
+
+	var runs int32
+
+	// A fresh node with the same settings and inputs stands in for reopening
+	// the graph in a new session.
+	first := newCountingNode(&runs)
+	setupGraph(first, core.NewStringValue("disk"))
+	res := runAndWait(t, first)
+	assert.Equal(t, "disk!", string(res.Outputs[0].BytesValue))
+
+	second := newCountingNode(&runs)
+	setupGraph(second, core.NewStringValue("disk"))
+	res = runAndWait(t, second)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, "disk!", string(res.Outputs[0].BytesValue))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs), "result should come from the cache directory")
+
+	t.Run("Opt-out", func(t *testing.T) {
+		optOut := newCountingNode(&runs)
+		optOut.NoCache = true
+		setupGraph(optOut, core.NewStringValue("disk"))
+		runAndWait(t, optOut)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	})
+
+	t.Run("Corrupt entry", func(t *testing.T) {
+		var corruptRuns int32
+		corrupt := newCountingNode(&corruptRuns)
+		setupGraph(corrupt, core.NewStringValue("corrupt"))
+		key, ok := corrupt.ResultKey()
+		assert.True(t, ok)
+		assert.NoError(t, os.WriteFile(filepath.Join(core.ResultCacheDir, hex.EncodeToString(key[:])+".fsresult"), []byte{0xff}, 0o644))
+		events := recordEvents(t, corrupt)
+
+		res := runAndWait(t, corrupt)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "corrupt!", string(res.Outputs[0].BytesValue))
+		assert.Contains(t, eventKinds(events()), core.NodeCacheError)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&corruptRuns))
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		assert.NoError(t, core.ClearResultCache())
+		third := newCountingNode(&runs)
+		setupGraph(third, core.NewStringValue("disk"))
+		runAndWait(t, third)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+	})
+}
+
+func TestPersistentHTTPCache(t *testing.T) {
+	core.ResultCacheDir = t.TempDir()
+	defer func() { core.ResultCacheDir = "" }()
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		w.Write([]byte("response " + string(rune('0'+n))))
+	}))
+	defer server.Close()
+
+	first := nodes.NewHTTPRequestNode()
+	setupGraph(first, core.NewStringValue(server.URL))
+	res := runAndWait(t, first)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, "response 1", string(res.Outputs[1].BytesValue))
+
+	// A fresh node stands in for reopening the graph after a restart.
+	reloaded := nodes.NewHTTPRequestNode()
+	setupGraph(reloaded, core.NewStringValue(server.URL))
+	res = runAndWait(t, reloaded)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, "response 1", string(res.Outputs[1].BytesValue))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&hits), "GET result should come from the cache directory")
+
+	optOut := nodes.NewHTTPRequestNode()
+	optOut.NoCache = true
+	setupGraph(optOut, core.NewStringValue(server.URL))
+	res = runAndWait(t, optOut)
+	assert.NoError(t, res.Err)
+	assert.Equal(t, "response 2", string(res.Outputs[1].BytesValue))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&hits))
+
+	post := nodes.NewHTTPRequestNode()
+	setupGraph(post, core.NewStringValue(server.URL), core.NewStringValue("POST"))
+	_, ok := post.ResultKey()
+	assert.False(t, ok, "POST requests should not be cached")
+}
+
+func TestContainsStream(t *testing.T) {
+	stream := core.FlowValue{Type: &core.FlowType{Kind: core.FSKindStream}}
+	assert.True(t, core.ContainsStream(stream))
+	assert.False(t, core.ContainsStream(core.NewStringValue("hi")))
+	assert.True(t, core.ContainsStream(core.NewListValue(*stream.Type, []core.FlowValue{stream})))
+	assert.True(t, core.ContainsStream(core.NewMapValue(*stream.Type, []core.FlowValueField{{Name: "out", Value: stream}})))
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+// Builds a flow with inputs "text" and "sep" and outputs "parts" (text split
+// on sep) and "upper" (text in upper case).
+func setupSplitFlow() *core.Graph {
+	g := core.NewGraph()
+	text := nodes.NewGraphInputNode()
+	text.Action.(*nodes.GraphInputAction).Name = "text"
+	sep := nodes.NewGraphInputNode()
+	sep.Action.(*nodes.GraphInputAction).Name = "sep"
+	split := nodes.NewSplitTextNode()
+	upper := nodes.NewCaseConvertNode()
+	parts := nodes.NewGraphOutputNode()
+	parts.Action.(*nodes.GraphOutputAction).Name = "parts"
+	upperOut := nodes.NewGraphOutputNode()
+	upperOut.Action.(*nodes.GraphOutputAction).Name = "upper"
+	unnamed := nodes.NewGraphOutputNode()
+	for _, n := range []*core.Node{text, sep, split, upper, parts, upperOut, unnamed} {
+		g.AddNode(n)
+	}
+	g.AddWire(text, 0, split, 0)
+	g.AddWire(sep, 0, split, 1)
+	g.AddWire(text, 0, upper, 0)
+	g.AddWire(split, 0, parts, 0)
+	g.AddWire(upper, 0, upperOut, 0)
+	g.AddWire(upper, 0, unnamed, 0)
+	return g
+}
+
+func TestCallFlow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "split.flow")
+	assert.NoError(t, core.SaveGraph(path, setupSplitFlow()))
+
+	t.Run("Ports", func(t *testing.T) {
+		node := nodes.NewCallFlowNode()
+		node.Action.(*nodes.CallFlowAction).Path = path
+		node.Action.UpdateAndValidate(node)
+
+		bytesType := core.FlowType{Kind: core.FSKindBytes}
+		assert.Equal(t, []core.NodePort{{Name: "text", Type: bytesType}, {Name: "sep", Type: bytesType}}, node.InputPorts)
+		assert.Equal(t, []core.NodePort{
+			{Name: "parts", Type: core.NewListType(bytesType)},
+			{Name: "upper", Type: bytesType},
+		}, node.OutputPorts)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		node := nodes.NewCallFlowNode()
+		node.Action.(*nodes.CallFlowAction).Path = path
+		node.Action.UpdateAndValidate(node)
+		setupGraph(node, core.NewStringValue("a,b"), core.NewStringValue(","))
+
+		res := runAndWait(t, node)
+		if !assert.NoError(t, res.Err) {
+			return
+		}
+		assert.Len(t, res.Outputs[0].ListValue, 2)
+		assert.Equal(t, "b", string(res.Outputs[0].ListValue[1].BytesValue))
+		assert.Equal(t, "A,B", string(res.Outputs[1].BytesValue))
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		node := nodes.NewCallFlowNode()
+		node.Action.(*nodes.CallFlowAction).Path = filepath.Join(t.TempDir(), "missing.flow")
+		node.Action.UpdateAndValidate(node)
+		assert.False(t, node.Valid)
+
+		res := runAndWait(t, node)
+		assert.ErrorContains(t, res.Err, "failed to load flow")
+	})
+
+	t.Run("Map with several inputs", func(t *testing.T) {
+		node := nodes.NewMapNode()
+		action := node.Action.(*nodes.MapAction)
+		action.CachedGraph = setupSplitFlow()
+		item := func(text, sep string) core.FlowValue {
+			v, err := core.NativeToFlowValue(map[string]any{"text": text, "sep": sep})
+			assert.NoError(t, err)
+			return v
+		}
+		setupGraph(node, core.NewListValue(core.FlowType{Kind: core.FSKindAny}, []core.FlowValue{item("x y", " "), item("p", " ")}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		res := <-action.RunContext(ctx, node)
+		if !assert.NoError(t, res.Err) {
+			return
+		}
+		results := res.Outputs[0].ListValue
+		assert.Len(t, results, 2)
+		// The unnamed output gets a numbered name.
+		fields := results[0].RecordValue
+		assert.Equal(t, []string{"parts", "upper", "Output 3"}, []string{fields[0].Name, fields[1].Name, fields[2].Name})
+		assert.Equal(t, "X Y", string(fields[1].Value.BytesValue))
+	})
+
+	t.Run("Map over table rows in parallel", func(t *testing.T) {
+		node := nodes.NewMapNode()
+		action := node.Action.(*nodes.MapAction)
+		action.SubflowPath = path
+		action.Parallelism = 4
+		bytesType := core.FlowType{Kind: core.FSKindBytes}
+		tableType := core.NewTableType([]core.FlowField{{Name: "sep", Type: &bytesType}, {Name: "text", Type: &bytesType}})
+		table := core.FlowValue{Type: &tableType}
+		for i := range 20 {
+			table.TableValue = append(table.TableValue, []core.FlowValueField{
+				{Name: "sep", Value: core.NewStringValue(" ")},
+				{Name: "text", Value: core.NewStringValue(fmt.Sprintf("row %d", i))},
+			})
+		}
+		setupGraph(node, table)
+		action.UpdateAndValidate(node)
+		assert.True(t, node.Valid)
+
+		res := runAndWait(t, node)
+		if !assert.NoError(t, res.Err) {
+			return
+		}
+		results := res.Outputs[0].ListValue
+		assert.Len(t, results, 20)
+		for i, result := range results {
+			assert.Equal(t, fmt.Sprintf("ROW %d", i), string(result.RecordValue[1].Value.BytesValue))
+		}
+	})
+
+	t.Run("Map reloads a modified subflow", func(t *testing.T) {
+		subPath := filepath.Join(t.TempDir(), "upper.flow")
+		saveCase := func(mode nodes.CaseMode, modTime time.Time) {
+			g := core.NewGraph()
+			in, convert, out := nodes.NewGraphInputNode(), nodes.NewCaseConvertNode(), nodes.NewGraphOutputNode()
+			convert.Action.(*nodes.CaseConvertAction).Mode = mode
+			for _, n := range []*core.Node{in, convert, out} {
+				g.AddNode(n)
+			}
+			g.AddWire(in, 0, convert, 0)
+			g.AddWire(convert, 0, out, 0)
+			assert.NoError(t, core.SaveGraph(subPath, g))
+			assert.NoError(t, os.Chtimes(subPath, modTime, modTime))
+		}
+
+		node := nodes.NewMapNode()
+		node.Action.(*nodes.MapAction).SubflowPath = subPath
+		setupGraph(node, core.NewListValue(core.FlowType{Kind: core.FSKindBytes}, []core.FlowValue{core.NewStringValue("Hi")}))
+
+		saveCase(nodes.CaseUpper, time.Now().Add(-time.Hour))
+		res := runAndWait(t, node)
+		if assert.NoError(t, res.Err) {
+			assert.Equal(t, "HI", string(res.Outputs[0].ListValue[0].BytesValue))
+		}
+
+		saveCase(nodes.CaseLower, time.Now())
+		res = runAndWait(t, node)
+		if assert.NoError(t, res.Err) {
+			assert.Equal(t, "hi", string(res.Outputs[0].ListValue[0].BytesValue))
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func errorField(t *testing.T, v core.FlowValue, name string) string {
+	for _, f := range v.RecordValue {
+		if f.Name == name {
+			return string(f.Value.BytesValue)
+		}
+	}
+	t.Fatalf("error value has no field %q", name)
+	return ""
+}
+
+func TestErrorPort(t *testing.T) {
+	t.Run("Failure is routed to the error port", func(t *testing.T) {
+		g := core.NewGraph()
+		failing := newFailingNode()
+		g.AddNode(failing)
+		failing.SetErrorPort(true)
+
+		var runs int32
+		downstream := newCountingNode(&runs)
+		g.AddNode(downstream)
+		g.AddWire(failing, 0, downstream, 0)
+		events := recordEvents(t, failing)
+
+		res := runAndWait(t, downstream)
+		assert.NoError(t, res.Err)
+		assert.True(t, res.Outputs[0].Skipped)
+		assert.Equal(t, int32(0), atomic.LoadInt32(&runs))
+
+		res, _ = failing.GetResult()
+		assert.NoError(t, res.Err)
+		assert.Len(t, res.Outputs, 2)
+		assert.True(t, res.Outputs[0].Skipped)
+		assert.Equal(t, "boom", errorField(t, res.Outputs[1], "message"))
+		assert.Equal(t, failing.String(), errorField(t, res.Outputs[1], "node"))
+
+		evs := events()
+		assert.Equal(t, core.NodeFailed, evs[len(evs)-1].Kind)
+		assert.EqualError(t, evs[len(evs)-1].Err, "boom")
+	})
+
+	t.Run("Error port is skipped on success", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		setupGraph(node, core.NewStringValue("hi"))
+		node.SetErrorPort(true)
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "hi!", string(res.Outputs[0].BytesValue))
+		assert.True(t, res.Outputs[1].Skipped)
+	})
+
+	t.Run("Removing the error port removes its wires", func(t *testing.T) {
+		g := core.NewGraph()
+		failing := newFailingNode()
+		g.AddNode(failing)
+		failing.SetErrorPort(true)
+		try := nodes.NewTryNode()
+		g.AddNode(try)
+		g.AddWire(failing, failing.ErrorPortIndex(), try, 0)
+
+		failing.SetErrorPort(false)
+		assert.Len(t, failing.OutputPorts, 1)
+		assert.Empty(t, g.Wires)
+	})
+}
+
+func TestTryNode(t *testing.T) {
+	t.Run("Failure goes to Error", func(t *testing.T) {
+		g := core.NewGraph()
+		failing := newFailingNode()
+		g.AddNode(failing)
+		try := nodes.NewTryNode()
+		g.AddNode(try)
+		g.AddWire(failing, 0, try, 0)
+
+		res := runAndWait(t, try)
+		assert.NoError(t, res.Err)
+		assert.True(t, res.Outputs[0].Skipped)
+		assert.False(t, res.Outputs[1].Skipped)
+		assert.Equal(t, "boom", errorField(t, res.Outputs[1], "message"))
+	})
+
+	t.Run("Success goes to Value", func(t *testing.T) {
+		try := nodes.NewTryNode()
+		setupGraph(try, core.NewStringValue("hi"))
+
+		res := runAndWait(t, try)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "hi", string(res.Outputs[0].BytesValue))
+		assert.True(t, res.Outputs[1].Skipped)
+	})
+
+	t.Run("Value has the type of its input", func(t *testing.T) {
+		g := core.NewGraph()
+		try := nodes.NewTryNode()
+		g.AddNode(try)
+		try.Action.UpdateAndValidate(try)
+		assert.False(t, try.Valid)
+
+		value := nodes.NewValueNode(core.NewStringValue("hi"))
+		g.AddNode(value)
+		g.AddWire(value, 0, try, 0)
+		try.Action.UpdateAndValidate(try)
+		assert.True(t, try.Valid)
+		assert.Equal(t, core.FSKindBytes, try.OutputPorts[0].Type.Kind)
+	})
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordEvents collects the events for the given nodes until the test ends.
+func recordEvents(t *testing.T, nodes ...*core.Node) func() []core.NodeEvent {
+	var mu sync.Mutex
+	var events []core.NodeEvent
+	unsubscribe := core.Events.Subscribe(func(ev core.NodeEvent) {
+		for _, n := range nodes {
+			if ev.Node == n {
+				mu.Lock()
+				events = append(events, ev)
+				mu.Unlock()
+				return
+			}
+		}
+	})
+	t.Cleanup(unsubscribe)
+
+	return func() []core.NodeEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]core.NodeEvent(nil), events...)
+	}
+}
+
+func eventKinds(events []core.NodeEvent) []core.NodeEventKind {
+	var kinds []core.NodeEventKind
+	for _, ev := range events {
+		kinds = append(kinds, ev.Kind)
+	}
+	return kinds
+}
+
+func TestNodeEvents(t *testing.T) {
+	t.Run("Finished", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		setupGraph(node, core.NewStringValue("hi"))
+		events := recordEvents(t, node)
+
+		runAndWait(t, node)
+		runAndWait(t, node)
+
+		evs := events()
+		assert.Equal(t, []core.NodeEventKind{
+			core.NodeQueued, core.NodeStarted, core.NodeFinished,
+			core.NodeQueued, core.NodeFinished,
+		}, eventKinds(evs))
+		assert.False(t, evs[2].Cached)
+		assert.True(t, evs[4].Cached)
+		assert.False(t, evs[2].Time.Before(evs[0].Time))
+	})
+
+	t.Run("Failed", func(t *testing.T) {
+		node := newFailingNode()
+		core.NewGraph().AddNode(node)
+		events := recordEvents(t, node)
+
+		runAndWait(t, node)
+
+		evs := events()
+		assert.Equal(t, []core.NodeEventKind{core.NodeQueued, core.NodeStarted, core.NodeFailed}, eventKinds(evs))
+		assert.EqualError(t, evs[2].Err, "boom")
+	})
+
+	t.Run("Skipped", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		setupGraph(node, core.FlowValue{Type: &core.FlowType{Kind: core.FSKindBytes}, Skipped: true})
+		events := recordEvents(t, node)
+
+		runAndWait(t, node)
+
+		assert.Equal(t, []core.NodeEventKind{core.NodeQueued, core.NodeSkipped}, eventKinds(events()))
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		setupGraph(node, core.NewStringValue("hi"))
+		events := recordEvents(t, node)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		cancel()
+		<-node.Run(ctx, false)
+
+		assert.Equal(t, []core.NodeEventKind{core.NodeQueued, core.NodeCancelled}, eventKinds(events()))
+	})
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/bvisness/flowshell/app/core"
+)
+
+// fakeAction is a node action for tests. Each test sets only the behavior it
+// needs; anything left unset does nothing.
+type fakeAction struct {
+	// The saved settings. Count is only saved from schema version 1.
+	Text  string
+	Count int
+
+	tag         string // "fakeAction" if empty
+	schema      int
+	sideEffects bool
+	category    string
+
+	// run computes the result of each run, numbered from 1. Without it, runs
+	// succeed with no outputs.
+	run  func(ctx context.Context, n *core.Node, run int32) core.NodeActionResult
+	runs atomic.Int32
+
+	resultCode func(res core.NodeActionResult) (int, bool)
+}
+
+func (a *fakeAction) UpdateAndValidate(n *core.Node) { n.Valid = true }
+func (a *fakeAction) UI(n *core.Node)                {}
+func (a *fakeAction) SchemaVersion() int             { return a.schema }
+func (a *fakeAction) HasSideEffects() bool           { return a.sideEffects }
+func (a *fakeAction) SchedCategory() string          { return a.category }
+
+func (a *fakeAction) Tag() string {
+	if a.tag == "" {
+		return "fakeAction"
+	}
+	return a.tag
+}
+
+func (a *fakeAction) Run(n *core.Node) <-chan core.NodeActionResult {
+	return a.RunContext(context.Background(), n)
+}
+
+func (a *fakeAction) RunContext(ctx context.Context, n *core.Node) <-chan core.NodeActionResult {
+	done := make(chan core.NodeActionResult, 1)
+	run := a.runs.Add(1)
+	go func() {
+		if a.run == nil {
+			done <- core.NodeActionResult{}
+			return
+		}
+		done <- a.run(ctx, n, run)
+	}()
+	return done
+}
+
+func (a *fakeAction) ResultCode(res core.NodeActionResult) (int, bool) {
+	if a.resultCode == nil {
+		return 0, false
+	}
+	return a.resultCode(res)
+}
+
+func (a *fakeAction) Serialize(s *core.Serializer) bool {
+	core.SStr(s, &a.Text)
+	if a.schema >= 1 {
+		core.SInt(s, &a.Count)
+	}
+	return s.Ok()
+}
+
+// newCountingNode makes a node that appends its action's Text to its input,
+// "!" to begin with, and counts its runs in runs.
+func newCountingNode(runs *int32) *core.Node {
+	a := &fakeAction{tag: "countingAction", Text: "!"}
+	a.run = func(ctx context.Context, n *core.Node, run int32) core.NodeActionResult {
+		atomic.AddInt32(runs, 1)
+		in, _, err := n.GetInputValue(0)
+		if err != nil {
+			return core.NodeActionResult{Err: err}
+		}
+		return core.NodeActionResult{Outputs: []core.FlowValue{core.NewStringValue(string(in.BytesValue) + a.Text)}}
+	}
+	return &core.Node{
+		Name:        "Counting",
+		InputPorts:  []core.NodePort{{Name: "In", Type: core.FlowType{Kind: core.FSKindBytes}}},
+		OutputPorts: []core.NodePort{{Name: "Out", Type: core.FlowType{Kind: core.FSKindBytes}}},
+		Action:      a,
+	}
+}
+
+// newFailingNode makes a node that always fails with "boom".
+func newFailingNode() *core.Node {
+	return &core.Node{
+		Name:        "Fails",
+		OutputPorts: []core.NodePort{{Name: "Out", Type: core.FlowType{Kind: core.FSKindBytes}}},
+		Action: &fakeAction{tag: "failingAction", run: func(ctx context.Context, n *core.Node, run int32) core.NodeActionResult {
+			return core.NodeActionResult{Err: errors.New("boom")}
+		}},
+	}
+}
+
+// newSlowNode makes a node that runs until its context is done.
+func newSlowNode() *core.Node {
+	return &core.Node{
+		Name:        "Slow",
+		OutputPorts: []core.NodePort{{Name: "Out", Type: core.FlowType{Kind: core.FSKindBytes}}},
+		Action: &fakeAction{tag: "slowAction", run: func(ctx context.Context, n *core.Node, run int32) core.NodeActionResult {
+			<-ctx.Done()
+			return core.NodeActionResult{Err: ctx.Err()}
+		}},
+	}
+}
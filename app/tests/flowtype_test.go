@@ -0,0 +1,483 @@
+package tests
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoolAndNull(t *testing.T) {
+	intType := core.FlowType{Kind: core.FSKindInt64}
+
+	t.Run("Typecheck", func(t *testing.T) {
+		assert.NoError(t, core.Typecheck(intType, core.NullableType(intType)))
+		assert.ErrorContains(t, core.Typecheck(core.NullableType(intType), intType), "may be null")
+		assert.NoError(t, core.Typecheck(*core.NewNullValue(core.FlowType{Kind: core.FSKindAny}).Type, core.NullableType(intType)))
+		assert.Error(t, core.Typecheck(core.FlowType{Kind: core.FSKindBool}, intType))
+		assert.Equal(t, "Int64?", core.NullableType(intType).String())
+	})
+
+	t.Run("Native values", func(t *testing.T) {
+		v, err := core.NativeToFlowValue(true)
+		assert.NoError(t, err)
+		assert.Equal(t, core.FSKindBool, v.Type.Kind)
+		assert.True(t, v.BoolValue)
+		assert.Equal(t, true, core.FlowValueToNative(v))
+
+		v, err = core.NativeToFlowValue([]any{1.0, nil})
+		assert.NoError(t, err)
+		assert.Equal(t, "List[Int64?]", v.Type.String())
+		assert.True(t, v.ListValue[1].Null)
+		assert.Equal(t, []any{int64(1), nil}, core.FlowValueToNative(v))
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		g := core.NewGraph()
+		g.AddNode(nodes.NewValueNode(core.NewBoolValue(true)))
+		g.AddNode(nodes.NewValueNode(core.NewNullValue(intType)))
+
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		loaded, err := core.DeserializeGraph(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		b := loaded.Nodes[0].Action.(*nodes.ValueAction).Value
+		assert.True(t, b.BoolValue)
+		null := loaded.Nodes[1].Action.(*nodes.ValueAction).Value
+		assert.True(t, null.Null)
+		assert.Equal(t, core.NullableType(intType), *null.Type)
+
+		text, err := core.SerializeGraphText(g)
+		assert.NoError(t, err)
+		loaded, err = core.DeserializeGraph(text)
+		assert.NoError(t, err)
+		again, err := core.SerializeGraph(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "in.csv")
+		assert.NoError(t, os.WriteFile(path, []byte("name,age,member\nAlice,30,true\nBob,,FALSE\nCarol,25,\n"), 0o644))
+
+		load := nodes.NewLoadFileNode(path)
+		load.Action.(*nodes.LoadFileAction).Format.SelectByValue("csv")
+		res := runAndWait(t, load)
+		if !assert.NoError(t, res.Err) {
+			return
+		}
+		table := res.Outputs[0]
+		assert.Equal(t, "Table[name:Bytes, age:Int64?, member:Bool?]", table.Type.String())
+		assert.True(t, table.Cell(1, 1).Null)
+		assert.False(t, table.Cell(1, 2).BoolValue)
+		assert.True(t, table.Cell(2, 2).Null)
+
+		outPath := filepath.Join(dir, "out.csv")
+		save := nodes.NewSaveFileNode()
+		save.Action.(*nodes.SaveFileAction).Path = outPath
+		save.Action.(*nodes.SaveFileAction).Format = "csv"
+		setupGraph(save, table)
+		res = runAndWait(t, save)
+		assert.NoError(t, res.Err)
+		out, err := os.ReadFile(outPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "name,age,member\nAlice,30,true\nBob,,false\nCarol,25,\n", string(out))
+	})
+}
+
+func TestRecordSubtyping(t *testing.T) {
+	bytesType := core.FlowType{Kind: core.FSKindBytes}
+	intType := core.FlowType{Kind: core.FSKindInt64}
+	abType := core.NewTableType([]core.FlowField{{Name: "a", Type: &bytesType}, {Name: "b", Type: &intType}})
+	bacType := core.NewTableType([]core.FlowField{{Name: "b", Type: &intType}, {Name: "a", Type: &bytesType}, {Name: "c", Type: &bytesType}})
+	ab := core.FlowValue{Type: &abType, TableValue: [][]core.FlowValueField{
+		{{Name: "a", Value: core.NewStringValue("x")}, {Name: "b", Value: core.NewInt64Value(1, 0)}},
+	}}
+	bac := core.FlowValue{Type: &bacType, TableValue: [][]core.FlowValueField{
+		{{Name: "b", Value: core.NewInt64Value(2, 0)}, {Name: "a", Value: core.NewStringValue("y")}, {Name: "c", Value: core.NewStringValue("z")}},
+	}}
+
+	t.Run("Typecheck", func(t *testing.T) {
+		assert.NoError(t, core.Typecheck(bacType, abType))
+		assert.ErrorContains(t, core.Typecheck(abType, bacType), "has no field c")
+		badType := core.NewTableType([]core.FlowField{{Name: "a", Type: &intType}, {Name: "b", Type: &intType}})
+		assert.ErrorContains(t, core.Typecheck(bacType, badType), "bad type for field a")
+	})
+
+	t.Run("Field lookup", func(t *testing.T) {
+		assert.Equal(t, 1, bac.Type.FieldIndex("a"))
+		assert.Equal(t, -1, bac.Type.FieldIndex("d"))
+		a, ok := bac.Row(0).Field("a")
+		assert.True(t, ok)
+		assert.Equal(t, "y", string(a.BytesValue))
+		col, ok := bac.Column("c")
+		assert.True(t, ok)
+		assert.Equal(t, "z", string(col[0].BytesValue))
+		_, ok = bac.Column("d")
+		assert.False(t, ok)
+	})
+
+	t.Run("Concat tables", func(t *testing.T) {
+		node := nodes.NewConcatTablesNode()
+		node.InputPorts = append(node.InputPorts, core.NodePort{Name: "Table 2", Type: core.NewAnyTableType()})
+		setupGraph(node, ab, bac)
+		res := runAndWait(t, node)
+		if !assert.NoError(t, res.Err) {
+			return
+		}
+		row := res.Outputs[0].TableValue[1]
+		assert.Equal(t, []string{"a", "b"}, []string{row[0].Name, row[1].Name})
+		assert.Equal(t, "y", string(row[0].Value.BytesValue))
+	})
+
+	t.Run("Formula by name", func(t *testing.T) {
+		node := nodes.NewFormulaNode()
+		node.Action.(*nodes.FormulaAction).Expression = `col("a") + col("c")`
+		setupGraph(node, bac)
+		res := runAndWait(t, node)
+		if assert.NoError(t, res.Err) {
+			assert.Equal(t, "yz", string(res.Outputs[0].ListValue[0].BytesValue))
+		}
+	})
+}
+
+func TestMapKind(t *testing.T) {
+	usersJSON := core.NewStringValue(`{"users": {"u2": {"name": "Bo"}, "u1": {"name": "Al"}}}`)
+
+	query := func(mode nodes.JsonObjectMode) core.FlowValue {
+		node := nodes.NewJsonQueryNode()
+		node.Action.(*nodes.JsonQueryAction).Query = "users"
+		node.Action.(*nodes.JsonQueryAction).Objects = mode
+		setupGraph(node, usersJSON)
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		return res.Outputs[0]
+	}
+
+	t.Run("JSON Query", func(t *testing.T) {
+		assert.Equal(t, core.FSKindBytes, query(nodes.JsonObjectsAsText).Type.Kind)
+		assert.Equal(t, "Record[u1:Record[name:Bytes], u2:Record[name:Bytes]]", query(nodes.JsonObjectsAsRecords).Type.String())
+
+		users := query(nodes.JsonObjectsAsMaps)
+		assert.Equal(t, "Map[Map[Bytes]]", users.Type.String())
+		bo, ok := users.MapEntry("u2")
+		assert.True(t, ok)
+		name, _ := bo.MapEntry("name")
+		assert.Equal(t, "Bo", string(name.BytesValue))
+	})
+
+	t.Run("Keys, values and entries", func(t *testing.T) {
+		users := query(nodes.JsonObjectsAsMaps)
+
+		keys := nodes.NewMapKeysNode()
+		setupGraph(keys, users)
+		res := runAndWait(t, keys)
+		if assert.NoError(t, res.Err) {
+			assert.Equal(t, []any{"u1", "u2"}, core.FlowValueToNative(res.Outputs[0]))
+		}
+
+		values := nodes.NewMapValuesNode()
+		setupGraph(values, users)
+		values.Action.UpdateAndValidate(values)
+		assert.Equal(t, "List[Map[Bytes]]", values.OutputPorts[0].Type.String())
+		res = runAndWait(t, values)
+		if assert.NoError(t, res.Err) {
+			assert.Len(t, res.Outputs[0].ListValue, 2)
+		}
+
+		entries := nodes.NewMapEntriesNode()
+		setupGraph(entries, users)
+		res = runAndWait(t, entries)
+		if assert.NoError(t, res.Err) {
+			table := res.Outputs[0]
+			assert.Equal(t, "Table[key:Bytes, value:Map[Bytes]]", table.Type.String())
+			assert.Equal(t, "u1", string(table.TableValue[0][0].Value.BytesValue))
+		}
+	})
+
+	t.Run("Convert", func(t *testing.T) {
+		m, err := nodes.ConvertValue(core.NewStringValue(`{"b": 2, "a": null}`), core.FSKindMap)
+		assert.NoError(t, err)
+		assert.Equal(t, "Map[Int64?]", m.Type.String())
+		assert.Equal(t, "a", m.MapValue[0].Name)
+
+		text, err := nodes.ConvertValue(m, core.FSKindBytes)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"a":null,"b":2}`, string(text.BytesValue))
+
+		rec, err := core.NativeToFlowValue(map[string]any{"x": "1", "y": int64(2)})
+		assert.NoError(t, err)
+		m, err = nodes.ConvertValue(rec, core.FSKindMap)
+		assert.NoError(t, err)
+		assert.Equal(t, "Map[Any]", m.Type.String())
+	})
+
+	t.Run("Formula", func(t *testing.T) {
+		users := query(nodes.JsonObjectsAsMaps)
+		for expression, want := range map[string]string{
+			`Input`:                    "Map[Map[Bytes]]",
+			`col("u1")`:                "Map[Bytes]",
+			`asMap({"k": len(Input)})`: "Map[Int64]",
+			`{"k": len(Input)}`:        "Record[k:Int64]",
+		} {
+			node := nodes.NewFormulaNode()
+			node.Action.(*nodes.FormulaAction).Expression = expression
+			setupGraph(node, users)
+			res := runAndWait(t, node)
+			if assert.NoError(t, res.Err, expression) {
+				assert.Equal(t, want, res.Outputs[0].Type.String(), expression)
+			}
+		}
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		g := core.NewGraph()
+		g.AddNode(nodes.NewValueNode(query(nodes.JsonObjectsAsMaps)))
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		loaded, err := core.DeserializeGraph(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		v := loaded.Nodes[0].Action.(*nodes.ValueAction).Value
+		assert.Equal(t, "Map[Map[Bytes]]", v.Type.String())
+		assert.Len(t, v.MapValue, 2)
+
+		text, err := core.SerializeGraphText(g)
+		assert.NoError(t, err)
+		loaded, err = core.DeserializeGraph(text)
+		assert.NoError(t, err)
+		again, err := core.SerializeGraph(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+	})
+}
+
+func TestColumnarTable(t *testing.T) {
+	intType := core.FlowType{Kind: core.FSKindInt64}
+	nullableInt := core.NullableType(intType)
+	fields := []core.FlowField{
+		{Name: "name", Type: &core.FlowType{Kind: core.FSKindBytes}},
+		{Name: "n", Type: &nullableInt},
+	}
+	tableType := core.NewTableType(fields)
+	rows := [][]core.FlowValueField{
+		{{Name: "name", Value: core.NewStringValue("c")}, {Name: "n", Value: core.NewInt64Value(3, 0)}},
+		{{Name: "name", Value: core.NewStringValue("a")}, {Name: "n", Value: core.NewNullValue(intType)}},
+		{{Name: "name", Value: core.NewStringValue("b")}, {Name: "n", Value: core.NewInt64Value(1, 0)}},
+	}
+	table := core.NewTable(fields)
+	for _, row := range rows {
+		table.AppendRow(row)
+	}
+	columnar := core.NewTableValue(table)
+	byRow := core.FlowValue{Type: &tableType, TableValue: rows}
+
+	t.Run("Accessors", func(t *testing.T) {
+		assert.Equal(t, 3, columnar.NumRows())
+		assert.Equal(t, "a", string(columnar.Cell(1, 0).BytesValue))
+		assert.True(t, columnar.Cell(1, 1).Null)
+		assert.Equal(t, byRow.ColumnValues(1), columnar.ColumnValues(1))
+		assert.Equal(t, byRow.TableRows(), columnar.TableRows())
+
+		ints, ok := table.Columns[1].Int64s()
+		assert.True(t, ok)
+		assert.Equal(t, []int64{3, 0, 1}, ints)
+		assert.Equal(t, []bool{false, true, false}, table.Columns[1].Nulls())
+
+		taken := table.Take([]int{2, 0})
+		assert.Equal(t, "b", string(taken.Row(0)[0].Value.BytesValue))
+		assert.Equal(t, int64(3), taken.Columns[1].Value(1).Int64Value)
+		assert.Equal(t, "n", table.Select([]int{1}).Fields[0].Name)
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		g := core.NewGraph()
+		g.AddNode(nodes.NewValueNode(byRow))
+		want, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+
+		g = core.NewGraph()
+		g.AddNode(nodes.NewValueNode(columnar))
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		assert.Equal(t, want, data)
+
+		text, err := core.SerializeGraphText(g)
+		assert.NoError(t, err)
+		loaded, err := core.DeserializeGraph(text)
+		assert.NoError(t, err)
+		again, err := core.SerializeGraph(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, want, again)
+	})
+
+	t.Run("Load file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "in.csv")
+		assert.NoError(t, os.WriteFile(path, []byte("name,n\nc,3\na,\nb,1\n"), 0o644))
+		load := nodes.NewLoadFileNode(path)
+		load.Action.(*nodes.LoadFileAction).Format.SelectByValue("csv")
+		res := runAndWait(t, load)
+		if assert.NoError(t, res.Err) {
+			assert.NotNil(t, res.Outputs[0].Table)
+			assert.Equal(t, byRow.TableRows(), res.Outputs[0].TableRows())
+		}
+	})
+
+	t.Run("Aggregate", func(t *testing.T) {
+		nType := core.NewTableType(fields[1:])
+		nByRow := core.FlowValue{Type: &nType}
+		for _, row := range rows {
+			nByRow.TableValue = append(nByRow.TableValue, row[1:])
+		}
+		nColumnar := core.NewTableValue(table.Select([]int{1}))
+
+		for _, op := range []string{"Min", "Max", "Mean"} {
+			var results []core.FlowValue
+			for _, input := range []core.FlowValue{nByRow, nColumnar} {
+				node := nodes.NewAggregateNode(op)
+				setupGraph(node, input)
+				res := runAndWait(t, node)
+				assert.NoError(t, res.Err, op)
+				results = append(results, res.Outputs[0].Cell(0, 0))
+			}
+			assert.Equal(t, results[0], results[1], op)
+			assert.False(t, results[1].Null, op)
+		}
+	})
+
+	t.Run("Sort", func(t *testing.T) {
+		for _, input := range []core.FlowValue{byRow, columnar} {
+			node := nodes.NewSortNode()
+			node.Action.(*nodes.SortAction).Column = "n"
+			setupGraph(node, input)
+			node.Action.UpdateAndValidate(node)
+			assert.True(t, node.Valid)
+			res := runAndWait(t, node)
+			if assert.NoError(t, res.Err) {
+				col, _ := res.Outputs[0].Column("name")
+				assert.Equal(t, []any{"a", "b", "c"}, core.FlowValueToNative(core.NewListValue(*fields[0].Type, col)))
+			}
+		}
+	})
+}
+
+func TestValueLiterals(t *testing.T) {
+	intType := core.FlowType{Kind: core.FSKindInt64}
+	bytesType := core.FlowType{Kind: core.FSKindBytes}
+	recordType := core.NewRecordType([]core.FlowField{{Name: "name", Type: &bytesType}, {Name: "first name", Type: &bytesType}})
+
+	t.Run("Round trip", func(t *testing.T) {
+		for want, v := range map[string]core.FlowValue{
+			`"say \"hi\""`:                      core.NewStringValue(`say "hi"`),
+			`-42`:                               core.NewInt64Value(-42, 0),
+			`1024B`:                             core.NewInt64Value(1024, core.FSUnitBytes),
+			`1.5s`:                              core.NewFloat64Value(1.5, core.FSUnitSeconds),
+			`2.0`:                               core.NewFloat64Value(2, 0),
+			`-inf`:                              core.NewFloat64Value(math.Inf(-1), 0),
+			`infs`:                              core.NewFloat64Value(math.Inf(1), core.FSUnitSeconds),
+			`-infB`:                             core.NewFloat64Value(math.Inf(-1), core.FSUnitBytes),
+			`true`:                              core.NewBoolValue(true),
+			`[1, 2]`:                            core.NewListValue(intType, []core.FlowValue{core.NewInt64Value(1, 0), core.NewInt64Value(2, 0)}),
+			`map{"a": 1}`:                       core.MapFromEntries([]core.FlowValueField{{Name: "a", Value: core.NewInt64Value(1, 0)}}),
+			`timestamp("2024-01-02T03:04:05Z")`: core.NewTimestampValue(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+			`timestamp(253402300800)`:           core.NewTimestampValue(time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)),
+			`timestamp(-62167219201)`:           core.NewTimestampValue(time.Date(-1, 12, 31, 23, 59, 59, 0, time.UTC)),
+			`{name: "Al", "first name": "Al"}`: {Type: &recordType, RecordValue: []core.FlowValueField{
+				{Name: "name", Value: core.NewStringValue("Al")},
+				{Name: "first name", Value: core.NewStringValue("Al")},
+			}},
+		} {
+			assert.Equal(t, want, v.String())
+			parsed, err := core.ParseValue(want)
+			if assert.NoError(t, err, want) {
+				assert.Equal(t, v, parsed, want)
+			}
+		}
+
+		// NaN is not equal to itself, so check it apart.
+		for _, unit := range []core.FlowUnit{0, core.FSUnitBytes, core.FSUnitSeconds} {
+			v := core.NewFloat64Value(math.NaN(), unit)
+			parsed, err := core.ParseValue(v.String())
+			if assert.NoError(t, err, v.String()) {
+				assert.True(t, math.IsNaN(parsed.Float64Value), v.String())
+				assert.Equal(t, v.Type, parsed.Type, v.String())
+			}
+		}
+	})
+
+	t.Run("Inferred types", func(t *testing.T) {
+		for src, want := range map[string]string{
+			`[1, null]`: "List[Int64?]",
+			`[1, "a"]`:  "List[Any]",
+			`[]`:        "List[Any]",
+			`table[{a: 1, b: "x"}, {a: null, b: "y"}]`: "Table[a:Int64?, b:Bytes]",
+		} {
+			v, err := core.ParseValue(src)
+			if assert.NoError(t, err, src) {
+				assert.Equal(t, src, v.String())
+				assert.Equal(t, want, v.Type.String(), src)
+			}
+		}
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		src := `table[file{name: "a.txt", path: "/a.txt", type: "file", size: 12B, modified: timestamp("2024-01-02T03:04:05Z")}]`
+		v, err := core.ParseValue(src)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, src, v.String())
+		assert.NoError(t, core.Typecheck(*v.Type, core.FlowType{Kind: core.FSKindTable, ContainedType: core.FSFile}))
+		assert.Equal(t, core.FSWKTFile, v.Type.ContainedType.WellKnownType)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for src, want := range map[string]string{
+			`{a: 1, a: 2}`:          `"a" appears twice`,
+			`12kg`:                  `unknown unit "kg"`,
+			`[1, 2`:                 `expected ']', but the value ended`,
+			`table[{a: 1}, {b: 2}]`: "same fields",
+			`nope`:                  `unknown word "nope"`,
+			`1 2`:                   "after value",
+			`file{name: "a.txt"}`:   "bad file",
+		} {
+			_, err := core.ParseValue(src)
+			assert.ErrorContains(t, err, want, src)
+		}
+
+		_, err := nodes.ConvertValue(core.NewStringValue("abc"), core.FSKindInt64)
+		assert.ErrorContains(t, err, `cannot convert "abc" to Int64`)
+		assert.ErrorIs(t, err, strconv.ErrSyntax)
+	})
+
+	t.Run("Value node", func(t *testing.T) {
+		defer func(prev func()) { core.PushHistoryFunc = prev }(core.PushHistoryFunc)
+		pushes := 0
+		core.PushHistoryFunc = func() { pushes++ }
+
+		node := nodes.NewValueNode(core.NewInt64Value(1, 0))
+		action := node.Action.(*nodes.ValueAction)
+		assert.Error(t, action.SetText(node, `[1,`))
+		assert.Equal(t, int64(1), action.Value.Int64Value)
+		assert.Equal(t, 0, pushes)
+
+		assert.NoError(t, action.SetText(node, `[1, 2, 3]`))
+		assert.Equal(t, 1, pushes, "a new value is an undo step")
+		assert.Equal(t, "List[Int64]", node.OutputPorts[0].Type.String())
+		setupGraph(node)
+		res := runAndWait(t, node)
+		if assert.NoError(t, res.Err) {
+			assert.Equal(t, "[1, 2, 3]", res.Outputs[0].String())
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app"
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunGraphToCompletion(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var runs int32
+		node := newCountingNode(&runs)
+		g := setupGraph(node, core.NewStringValue("hi"))
+
+		summary := app.RunGraphToCompletion(context.Background(), g)
+		assert.Equal(t, 2, summary.Succeeded)
+		assert.Empty(t, summary.Failed)
+		assert.Equal(t, app.ExitOK, summary.ExitCode())
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		g := core.NewGraph()
+		failing := newFailingNode()
+		g.AddNode(failing)
+		var runs int32
+		downstream := newCountingNode(&runs)
+		g.AddNode(downstream)
+		g.AddWire(failing, 0, downstream, 0)
+
+		summary := app.RunGraphToCompletion(context.Background(), g)
+		assert.Equal(t, 0, summary.Succeeded)
+		assert.ElementsMatch(t, []*core.Node{failing, downstream}, summary.Failed)
+		assert.Equal(t, app.ExitNodeFailed, summary.ExitCode())
+	})
+
+	t.Run("Handled failure", func(t *testing.T) {
+		g := core.NewGraph()
+		failing := newFailingNode()
+		g.AddNode(failing)
+		failing.SetErrorPort(true)
+
+		summary := app.RunGraphToCompletion(context.Background(), g)
+		assert.Equal(t, app.ExitOK, summary.ExitCode())
+	})
+
+	t.Run("Interrupted", func(t *testing.T) {
+		g := core.NewGraph()
+		g.AddNode(newSlowNode())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		summary := app.RunGraphToCompletion(ctx, g)
+		assert.True(t, summary.Interrupted)
+		assert.Equal(t, app.ExitInterrupted, summary.ExitCode())
+	})
+}
+
+func TestHeadlessInputs(t *testing.T) {
+	t.Run("Parse values", func(t *testing.T) {
+		v, err := app.ParseInputValue("42")
+		assert.NoError(t, err)
+		assert.Equal(t, core.FSKindInt64, v.Type.Kind)
+		assert.Equal(t, int64(42), v.Int64Value)
+
+		v, err = app.ParseInputValue(`["a", "b"]`)
+		assert.NoError(t, err)
+		assert.Equal(t, core.FSKindList, v.Type.Kind)
+		assert.Len(t, v.ListValue, 2)
+
+		v, err = app.ParseInputValue("Alice")
+		assert.NoError(t, err)
+		assert.Equal(t, "Alice", string(v.BytesValue))
+	})
+
+	t.Run("Feed named graph inputs", func(t *testing.T) {
+		g := core.NewGraph()
+		input := nodes.NewGraphInputNode()
+		input.Action.(*nodes.GraphInputAction).Name = "who"
+		g.AddNode(input)
+		var runs int32
+		node := newCountingNode(&runs)
+		g.AddNode(node)
+		g.AddWire(input, 0, node, 0)
+
+		assert.Error(t, nodes.SetGraphInput(g, "nobody", core.NewStringValue("x")))
+		assert.NoError(t, nodes.SetGraphInput(g, "who", core.NewStringValue("Alice")))
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "Alice!", string(res.Outputs[0].BytesValue))
+	})
+
+	t.Run("Missing input value", func(t *testing.T) {
+		input := nodes.NewGraphInputNode()
+		core.NewGraph().AddNode(input)
+
+		res := runAndWait(t, input)
+		assert.ErrorContains(t, res.Err, "no value provided")
+	})
+}
+
+// Builds a graph with a named output fed by the given value.
+func setupOutputGraph(t *testing.T, name string, value core.FlowValue) *core.Graph {
+	output := nodes.NewGraphOutputNode()
+	output.Action.(*nodes.GraphOutputAction).Name = name
+	g := setupGraph(output, value)
+	runAndWait(t, output)
+	return g
+}
+
+func TestWriteGraphOutputs(t *testing.T) {
+	t.Run("JSON", func(t *testing.T) {
+		g := setupOutputGraph(t, "count", core.NewInt64Value(3, 0))
+
+		var buf bytes.Buffer
+		assert.NoError(t, app.WriteGraphOutputs(&buf, g, app.OutputFormatJSON))
+		assert.JSONEq(t, `{"count": 3}`, buf.String())
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		g := setupOutputGraph(t, "greeting", core.NewStringValue("hi"))
+
+		var buf bytes.Buffer
+		assert.NoError(t, app.WriteGraphOutputs(&buf, g, app.OutputFormatNDJSON))
+		assert.Equal(t, `{"name":"greeting","value":"hi"}`+"\n", buf.String())
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		tableType := core.NewTableType([]core.FlowField{
+			{Name: "name", Type: &core.FlowType{Kind: core.FSKindBytes}},
+			{Name: "age", Type: &core.FlowType{Kind: core.FSKindInt64}},
+		})
+		table := core.FlowValue{Type: &tableType, TableValue: [][]core.FlowValueField{
+			{{Name: "name", Value: core.NewStringValue("Alice")}, {Name: "age", Value: core.NewInt64Value(30, 0)}},
+			{{Name: "name", Value: core.NewStringValue("Bob, Jr.")}, {Name: "age", Value: core.NewInt64Value(4, 0)}},
+		}}
+		g := setupOutputGraph(t, "people", table)
+
+		var buf bytes.Buffer
+		assert.NoError(t, app.WriteGraphOutputs(&buf, g, app.OutputFormatCSV))
+		assert.Equal(t, "name,age\nAlice,30\n\"Bob, Jr.\",4\n", buf.String())
+
+		g = setupOutputGraph(t, "scalar", core.NewStringValue("hi"))
+		assert.Error(t, app.WriteGraphOutputs(&buf, g, app.OutputFormatCSV))
+	})
+
+	t.Run("Duplicate names", func(t *testing.T) {
+		g := setupOutputGraph(t, "count", core.NewInt64Value(3, 0))
+		other := nodes.NewGraphOutputNode()
+		other.Action.(*nodes.GraphOutputAction).Name = "count"
+		g.AddNode(other)
+
+		for _, format := range []string{app.OutputFormatJSON, app.OutputFormatNDJSON, app.OutputFormatCSV} {
+			var buf bytes.Buffer
+			assert.ErrorContains(t, app.WriteGraphOutputs(&buf, g, format), `both named "count"`, format)
+			assert.Empty(t, buf.String(), format)
+		}
+	})
+}
+
+func TestHeadlessRun(t *testing.T) {
+	defer func(prev *core.Scheduler) { core.NodeScheduler = prev }(core.NodeScheduler)
+	defer func(prev string) { core.ResultCacheDir = prev }(core.ResultCacheDir)
+
+	g := core.NewGraph()
+	input := nodes.NewGraphInputNode()
+	input.Action.(*nodes.GraphInputAction).Name = "who"
+	g.AddNode(input)
+	output := nodes.NewGraphOutputNode()
+	output.Action.(*nodes.GraphOutputAction).Name = "greeting"
+	g.AddNode(output)
+	g.AddWire(input, 0, output, 0)
+	path := filepath.Join(t.TempDir(), "greet.flow")
+	assert.NoError(t, core.SaveGraph(path, g))
+
+	var out, log bytes.Buffer
+	code := app.HeadlessRun(path, app.HeadlessOptions{
+		NoCache:      true,
+		Inputs:       map[string]string{"who": "Alice"},
+		OutputFormat: app.OutputFormatNDJSON,
+		Output:       &out,
+		Log:          &log,
+	})
+	assert.Equal(t, app.ExitOK, code)
+	// Only the outputs go to Output, so that it can be piped.
+	assert.Equal(t, `{"name":"greeting","value":"Alice"}`+"\n", out.String())
+	assert.Contains(t, log.String(), "Done: 2 succeeded, 0 failed.")
+}
+
+// Empty cells in numeric CSV columns load as null. Flows from before nullable
+// types were added must still run on such files, with the empty cells as 0.
+func TestHeadlessRunCSVWithEmptyCells(t *testing.T) {
+	defer func(prev *core.Scheduler) { core.NodeScheduler = prev }(core.NodeScheduler)
+	defer func(prev string) { core.ResultCacheDir = prev }(core.ResultCacheDir)
+
+	dir := t.TempDir()
+	gapsPath := filepath.Join(dir, "gaps.csv")
+	assert.NoError(t, os.WriteFile(gapsPath, []byte(
+		"Time to main (us),Time to first frame (us),Avg build (us),Avg draw (us),Avg frame (us)\n"+
+			"938000,993000,170,8497,8667\n"+
+			"987000,1040000,,8492,8657\n"+
+			"1000000,,165,8480,\n"), 0o644))
+
+	run := func(t *testing.T, g *core.Graph) {
+		path := filepath.Join(t.TempDir(), "run.flow")
+		assert.NoError(t, core.SaveGraph(path, g))
+		var log bytes.Buffer
+		code := app.HeadlessRun(path, app.HeadlessOptions{NoCache: true, Log: &log})
+		assert.Equal(t, app.ExitOK, code, log.String())
+	}
+
+	t.Run("Example", func(t *testing.T) {
+		// Load File -> Filter Empty on "Avg build (us)" -> Select Columns -> Save File
+		g, err := core.LoadGraph("../../examples/history/v4/csv_pipeline.flow")
+		if !assert.NoError(t, err) {
+			return
+		}
+		outPath := filepath.Join(dir, "cleaned.csv")
+		for _, n := range g.Nodes {
+			switch a := n.Action.(type) {
+			case *nodes.LoadFileAction:
+				a.Path = gapsPath
+			case *nodes.SaveFileAction:
+				a.Path = outPath
+			}
+		}
+		run(t, g)
+
+		out, err := os.ReadFile(outPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "Avg build (us),Avg frame (us)\n170,8667\n165,0\n", string(out))
+	})
+
+	t.Run("Concatenated with a table without gaps", func(t *testing.T) {
+		flutePath, err := filepath.Abs("../../corpus/flute1.csv")
+		assert.NoError(t, err)
+		outPath := filepath.Join(dir, "all.csv")
+
+		g := core.NewGraph()
+		full, gaps := nodes.NewLoadFileNode(flutePath), nodes.NewLoadFileNode(gapsPath)
+		concat := nodes.NewConcatTablesNode()
+		concat.InputPorts = append(concat.InputPorts, core.NodePort{Name: "Table 2", Type: core.NewAnyTableType()})
+		save := nodes.NewSaveFileNode()
+		save.Action.(*nodes.SaveFileAction).Path = outPath
+		save.Action.(*nodes.SaveFileAction).Format = "csv"
+		for _, n := range []*core.Node{full, gaps, concat, save} {
+			g.AddNode(n)
+		}
+		g.AddWire(full, 0, concat, 0)
+		g.AddWire(gaps, 0, concat, 1)
+		g.AddWire(concat, 0, save, 0)
+		run(t, g)
+
+		out, err := os.ReadFile(outPath)
+		assert.NoError(t, err)
+		assert.Contains(t, string(out), "\n987000,1040000,,8492,8657\n1000000,,165,8480,\n")
+	})
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -127,6 +128,21 @@ func TestConcatTablesNode(t *testing.T) {
 	assert.Equal(t, "B", string(res.Outputs[0].TableValue[1][0].Value.BytesValue))
 }
 
+func TestConcatTablesMissingColumn(t *testing.T) {
+	node := nodes.NewConcatTablesNode()
+	node.InputPorts = append(node.InputPorts, core.NodePort{Name: "Table 2", Type: core.NewAnyTableType()})
+
+	// The second table's rows lack the column its type claims.
+	fields := []core.FlowField{{Name: "col1", Type: &core.FlowType{Kind: core.FSKindBytes}}}
+	tableType := &core.FlowType{Kind: core.FSKindTable, ContainedType: &core.FlowType{Kind: core.FSKindRecord, Fields: fields}}
+	t1 := core.FlowValue{Type: tableType, TableValue: [][]core.FlowValueField{{{Name: "col1", Value: core.NewStringValue("A")}}}}
+	t2 := core.FlowValue{Type: tableType, TableValue: [][]core.FlowValueField{{{Name: "col2", Value: core.NewStringValue("B")}}}}
+	setupGraph(node, t1, t2)
+
+	res := runAndWait(t, node)
+	assert.EqualError(t, res.Err, `Table 2: row 0 is missing column "col1"`)
+}
+
 func TestTransposeNode(t *testing.T) {
 	node := nodes.NewTransposeNode()
 	action := node.Action.(*nodes.TransposeAction)
@@ -259,3 +275,109 @@ func TestMapNode(t *testing.T) {
 	assert.Equal(t, "B", string(outList[1].BytesValue))
 	assert.Equal(t, "C", string(outList[2].BytesValue))
 }
+
+func TestReduceNode(t *testing.T) {
+	ints := core.NewListValue(core.FlowType{Kind: core.FSKindInt64}, []core.FlowValue{
+		core.NewInt64Value(1, 0),
+		core.NewInt64Value(2, 0),
+		core.NewInt64Value(3, 0),
+		core.NewInt64Value(4, 0),
+	})
+
+	t.Run("Expression", func(t *testing.T) {
+		node := nodes.NewReduceNode()
+		setupGraph(node, ints)
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, int64(10), res.Outputs[0].Int64Value)
+	})
+
+	t.Run("Initial value over table rows", func(t *testing.T) {
+		node := nodes.NewReduceNode()
+		node.Action.(*nodes.ReduceAction).Expression = `Acc + "," + col("name")`
+
+		fields := []core.FlowField{{Name: "name", Type: &core.FlowType{Kind: core.FSKindBytes}}}
+		table := core.FlowValue{Type: &core.FlowType{Kind: core.FSKindTable, ContainedType: &core.FlowType{Kind: core.FSKindRecord, Fields: fields}}}
+		for _, name := range []string{"a", "b"} {
+			table.TableValue = append(table.TableValue, []core.FlowValueField{{Name: "name", Value: core.NewStringValue(name)}})
+		}
+		setupGraph(node, table, core.NewStringValue("start"))
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "start,a,b", string(res.Outputs[0].BytesValue))
+	})
+
+	t.Run("No items", func(t *testing.T) {
+		node := nodes.NewReduceNode()
+		setupGraph(node, core.NewListValue(core.FlowType{Kind: core.FSKindInt64}, nil))
+
+		res := runAndWait(t, node)
+		assert.ErrorContains(t, res.Err, "without an initial value")
+	})
+}
+
+func TestFilterNode(t *testing.T) {
+	t.Run("List by expression", func(t *testing.T) {
+		node := nodes.NewFilterNode()
+		node.Action.(*nodes.FilterAction).Expression = "Input % 2 == 0"
+		setupGraph(node, core.NewListValue(core.FlowType{Kind: core.FSKindInt64}, []core.FlowValue{
+			core.NewInt64Value(1, 0),
+			core.NewInt64Value(2, 0),
+			core.NewInt64Value(4, 0),
+		}))
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, core.FSKindInt64, res.Outputs[0].Type.ContainedType.Kind)
+		assert.Len(t, res.Outputs[0].ListValue, 2)
+		assert.Equal(t, int64(4), res.Outputs[0].ListValue[1].Int64Value)
+	})
+
+	t.Run("Table keeps its schema", func(t *testing.T) {
+		node := nodes.NewFilterNode()
+		node.Action.(*nodes.FilterAction).Expression = `col("n") > 1`
+
+		fields := []core.FlowField{{Name: "n", Type: &core.FlowType{Kind: core.FSKindInt64}}}
+		tableType := &core.FlowType{Kind: core.FSKindTable, ContainedType: &core.FlowType{Kind: core.FSKindRecord, Fields: fields}}
+		table := core.FlowValue{Type: tableType}
+		for i := range 3 {
+			table.TableValue = append(table.TableValue, []core.FlowValueField{{Name: "n", Value: core.NewInt64Value(int64(i), 0)}})
+		}
+		setupGraph(node, table)
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Same(t, tableType, res.Outputs[0].Type)
+		assert.Len(t, res.Outputs[0].TableValue, 1)
+		assert.Equal(t, int64(2), res.Outputs[0].TableValue[0][0].Value.Int64Value)
+	})
+
+	t.Run("Subflow", func(t *testing.T) {
+		sub := core.NewGraph()
+		in, trim, out := nodes.NewGraphInputNode(), nodes.NewTrimSpacesNode(), nodes.NewGraphOutputNode()
+		for _, n := range []*core.Node{in, trim, out} {
+			sub.AddNode(n)
+		}
+		sub.AddWire(in, 0, trim, 0)
+		sub.AddWire(trim, 0, out, 0)
+		path := filepath.Join(t.TempDir(), "trim.flow")
+		assert.NoError(t, core.SaveGraph(path, sub))
+
+		node := nodes.NewFilterNode()
+		action := node.Action.(*nodes.FilterAction)
+		action.UseSubflow = true
+		action.SubflowPath = path
+		setupGraph(node, core.NewListValue(core.FlowType{Kind: core.FSKindBytes}, []core.FlowValue{
+			core.NewStringValue("a"),
+			core.NewStringValue("  "),
+			core.NewStringValue("b"),
+		}))
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Len(t, res.Outputs[0].ListValue, 2)
+		assert.Equal(t, "b", string(res.Outputs[0].ListValue[1].BytesValue))
+	})
+}
@@ -51,7 +51,8 @@ func TestRegexNodes(t *testing.T) {
 		res := <-done
 
 		assert.NoError(t, res.Err)
-		assert.Equal(t, int64(1), res.Outputs[0].Int64Value)
+		assert.Equal(t, core.FSKindBool, res.Outputs[0].Type.Kind)
+		assert.True(t, res.Outputs[0].BoolValue)
 	})
 
 	t.Run("FindAll", func(t *testing.T) {
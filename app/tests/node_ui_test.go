@@ -141,6 +141,13 @@ func TestAllNodesInstantiation(t *testing.T) {
 		nodes.NewFormulaNode,
 		func() *core.Node { return nodes.NewLoadFileNode("test.txt") },
 		nodes.NewMapNode,
+		nodes.NewReduceNode,
+		nodes.NewFilterNode,
+		nodes.NewMapKeysNode,
+		nodes.NewMapValuesNode,
+		nodes.NewMapEntriesNode,
+		nodes.NewCallFlowNode,
+		nodes.NewRepeatNode,
 		nodes.NewSelectColumnsNode,
 		nodes.NewSortNode,
 		nodes.NewGateNode,
@@ -4,6 +4,7 @@ import (
 	"github.com/bvisness/flowshell/app/nodes"
 	"os"
 	"testing"
+	"time"
 
 	
 	"github.com/bvisness/flowshell/app/core"
@@ -13,7 +14,9 @@ func TestSaveLoadGraph(t *testing.T) {
 	// Setup some nodes and wires
 	g := core.NewGraph()
 	n1 := &core.Node{ID: 1, Name: "Node 1", Pos: core.V2{X: 10, Y: 10}, Action: &nodes.TrimSpacesAction{}}
-	n2 := &core.Node{ID: 2, Name: "Node 2", Pos: core.V2{X: 100, Y: 100}, Action: &nodes.TrimSpacesAction{}}
+	n2 := &core.Node{ID: 2, Name: "Node 2", Pos: core.V2{X: 100, Y: 100}, NoCache: true, Action: &nodes.TrimSpacesAction{}}
+	n2.Retry = core.RetryPolicy{MaxAttempts: 3, Backoff: time.Second, ErrorPattern: "timeout", RetryCodes: "502,503"}
+	n2.Timeout = 30 * time.Second
 	// Manually adding to ensure IDs are preserved for the test
 	g.Nodes = append(g.Nodes, n1, n2)
 	g.Wires = []*core.Wire{
@@ -63,6 +66,12 @@ func TestSaveLoadGraph(t *testing.T) {
 	if loadedG.Wires[0].StartNode.ID != 1 || loadedG.Wires[0].EndNode.ID != 2 {
 		t.Errorf("Wire connections mismatch")
 	}
+	if ln1.NoCache || !ln2.NoCache {
+		t.Errorf("NoCache flag not preserved")
+	}
+	if ln2.Retry != n2.Retry || ln2.Timeout != n2.Timeout {
+		t.Errorf("Retry policy not preserved: got %+v, timeout %v", ln2.Retry, ln2.Timeout)
+	}
 }
 
 func TestSaveLoadGraphComplex(t *testing.T) {
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilerChromeTrace(t *testing.T) {
+	var runs int32
+	node := newCountingNode(&runs)
+	g := setupGraph(node, core.NewStringValue("hi"))
+
+	profiler := core.StartProfiler(g)
+	runAndWait(t, node)
+	profiler.Stop()
+
+	// Runs after Stop are not recorded.
+	node.Action.(*fakeAction).Text = "?"
+	runAndWait(t, node)
+
+	var buf bytes.Buffer
+	assert.NoError(t, profiler.WriteChromeTrace(&buf))
+
+	var trace struct {
+		TraceEvents []struct {
+			Name string         `json:"name"`
+			Cat  string         `json:"cat"`
+			Ph   string         `json:"ph"`
+			TS   int64          `json:"ts"`
+			Dur  int64          `json:"dur"`
+			TID  int            `json:"tid"`
+			Args map[string]any `json:"args"`
+		} `json:"traceEvents"`
+	}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &trace))
+
+	var phases []string
+	for _, ev := range trace.TraceEvents {
+		assert.Equal(t, node.ID, ev.TID)
+		phases = append(phases, ev.Ph+":"+ev.Cat)
+	}
+	assert.Equal(t, []string{"M:", "X:wait", "X:run"}, phases)
+
+	wait, run := trace.TraceEvents[1], trace.TraceEvents[2]
+	assert.Equal(t, "Counting", run.Name)
+	assert.Equal(t, "finished", run.Args["result"])
+	assert.LessOrEqual(t, wait.TS+wait.Dur, run.TS+1, "the run slice should follow the wait slice")
+}
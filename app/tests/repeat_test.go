@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeat(t *testing.T) {
+	// Makes a Repeat node whose embedded body doubles its input.
+	setup := func(condition string, maxIterations int) (*core.Node, *nodes.RepeatAction) {
+		node := nodes.NewRepeatNode()
+		action := node.Action.(*nodes.RepeatAction)
+		action.Condition = condition
+		action.MaxIterations = maxIterations
+
+		in, double, out := nodes.NewGraphInputNode(), nodes.NewFormulaNode(), nodes.NewGraphOutputNode()
+		double.Action.(*nodes.FormulaAction).Expression = "Input * 2"
+		for _, n := range []*core.Node{in, double, out} {
+			action.Body.AddNode(n)
+		}
+		action.Body.AddWire(in, 0, double, 0)
+		action.Body.AddWire(double, 0, out, 0)
+		setupGraph(node, core.NewInt64Value(1, 0))
+		return node, action
+	}
+
+	t.Run("While condition holds", func(t *testing.T) {
+		node, action := setup("Input < 100", 100)
+		node.Action.UpdateAndValidate(node)
+		assert.True(t, node.Valid)
+
+		res := runAndWait(t, node)
+		if !assert.NoError(t, res.Err) {
+			return
+		}
+		assert.Equal(t, int64(128), res.Outputs[0].Int64Value)
+		assert.Equal(t, int64(7), res.Outputs[1].Int64Value)
+		// Only the last few iterations are kept.
+		iterations, count := action.Iterations()
+		assert.Equal(t, 7, count)
+		if assert.Len(t, iterations, 5) {
+			assert.Equal(t, int64(8), iterations[0].Int64Value)
+			assert.Equal(t, int64(128), iterations[4].Int64Value)
+		}
+	})
+
+	t.Run("Without condition", func(t *testing.T) {
+		node, _ := setup("", 4)
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, int64(16), res.Outputs[0].Int64Value)
+	})
+
+	t.Run("Limit reached", func(t *testing.T) {
+		node, action := setup("Input > 0", 3)
+		res := runAndWait(t, node)
+		assert.ErrorContains(t, res.Err, "still true after 3 iterations")
+		iterations, count := action.Iterations()
+		assert.Len(t, iterations, 3)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		node, _ := setup("Input < 100", 10)
+		g := node.Graph
+
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		text, err := core.SerializeGraphText(g)
+		assert.NoError(t, err)
+		assert.Contains(t, string(text), `"Input * 2"`)
+		loaded, err := core.DeserializeGraph(text)
+		assert.NoError(t, err)
+		again, err := core.SerializeGraph(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+	})
+}
@@ -51,9 +51,8 @@ func TestLoadFileNode_CSV(t *testing.T) {
 		if table.Type.Kind != core.FSKindTable {
 			t.Fatalf("Expected Table output, got %v", table.Type.Kind)
 		}
-		rows := table.TableValue
-		if len(rows) != 2 {
-			t.Errorf("Expected 2 rows, got %d", len(rows))
+		if n := table.NumRows(); n != 2 {
+			t.Errorf("Expected 2 rows, got %d", n)
 		}
 	case <-ctx.Done():
 		t.Fatal("Node execution timed out")
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/stretchr/testify/assert"
+)
+
+// newFlakyAction fails with errText for its first failTimes runs, then
+// succeeds with the run number.
+func newFlakyAction(failTimes int32, errText string) *fakeAction {
+	return &fakeAction{tag: "flakyAction", sideEffects: true, run: func(ctx context.Context, n *core.Node, run int32) core.NodeActionResult {
+		if run <= failTimes {
+			return core.NodeActionResult{Err: errors.New(errText)}
+		}
+		return core.NodeActionResult{Outputs: []core.FlowValue{core.NewInt64Value(int64(run), 0)}}
+	}}
+}
+
+func newFlakyNode(action *fakeAction, policy core.RetryPolicy) *core.Node {
+	n := &core.Node{
+		Name:        "Flaky",
+		OutputPorts: []core.NodePort{{Name: "Run", Type: core.FlowType{Kind: core.FSKindInt64}}},
+		Action:      action,
+		Retry:       policy,
+	}
+	core.NewGraph().AddNode(n)
+	return n
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Retries until success", func(t *testing.T) {
+		action := newFlakyAction(2, "flaky")
+		node := newFlakyNode(action, core.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+		events := recordEvents(t, node)
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, int64(3), res.Outputs[0].Int64Value)
+		assert.Equal(t, []core.NodeEventKind{
+			core.NodeQueued, core.NodeStarted, core.NodeRetrying, core.NodeRetrying, core.NodeFinished,
+		}, eventKinds(events()))
+	})
+
+	t.Run("Gives up after max attempts", func(t *testing.T) {
+		action := newFlakyAction(5, "flaky")
+		node := newFlakyNode(action, core.RetryPolicy{MaxAttempts: 2})
+
+		res := runAndWait(t, node)
+		assert.EqualError(t, res.Err, "flaky")
+		assert.Equal(t, int32(2), action.runs.Load())
+	})
+
+	t.Run("Only matching errors are retried", func(t *testing.T) {
+		action := newFlakyAction(1, "permission denied")
+		node := newFlakyNode(action, core.RetryPolicy{MaxAttempts: 3, ErrorPattern: "timeout|refused"})
+
+		res := runAndWait(t, node)
+		assert.EqualError(t, res.Err, "permission denied")
+		assert.Equal(t, int32(1), action.runs.Load())
+	})
+
+	t.Run("Retries on result codes", func(t *testing.T) {
+		// Each run's result code is the entry for its run number.
+		codes := []int{503, 503, 200}
+		action := newFlakyAction(0, "")
+		action.resultCode = func(res core.NodeActionResult) (int, bool) {
+			return codes[res.Outputs[0].Int64Value-1], true
+		}
+		node := newFlakyNode(action, core.RetryPolicy{MaxAttempts: 5, RetryCodes: "502, 503"})
+
+		res := runAndWait(t, node)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, int64(3), res.Outputs[0].Int64Value)
+	})
+
+	t.Run("Backoff gives up the scheduler slot", func(t *testing.T) {
+		defer func(prev *core.Scheduler) { core.NodeScheduler = prev }(core.NodeScheduler)
+		core.NodeScheduler = core.NewScheduler(1, nil)
+
+		flaky := newFlakyNode(newFlakyAction(1, "flaky"), core.RetryPolicy{MaxAttempts: 2, Backoff: 300 * time.Millisecond})
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		flakyDone := flaky.Run(ctx, false)
+		time.Sleep(50 * time.Millisecond)
+
+		// Runs while flaky waits to retry.
+		other := newFlakyNode(newFlakyAction(0, ""), core.RetryPolicy{})
+		res := runAndWait(t, other)
+		assert.NoError(t, res.Err)
+		select {
+		case <-flakyDone:
+			t.Fatal("flaky node finished before the other node ran")
+		default:
+		}
+
+		<-flakyDone
+		res, _ = flaky.GetResult()
+		assert.NoError(t, res.Err)
+	})
+
+	t.Run("Invalid settings", func(t *testing.T) {
+		assert.Error(t, (&core.RetryPolicy{ErrorPattern: "("}).Validate())
+		assert.Error(t, (&core.RetryPolicy{RetryCodes: "5xx"}).Validate())
+		assert.NoError(t, (&core.RetryPolicy{MaxAttempts: 2, RetryCodes: "1, 2"}).Validate())
+	})
+}
+
+func TestNodeTimeout(t *testing.T) {
+	node := newSlowNode()
+	node.Timeout = 10 * time.Millisecond
+	node.Retry = core.RetryPolicy{MaxAttempts: 2}
+	core.NewGraph().AddNode(node)
+	events := recordEvents(t, node)
+
+	res := runAndWait(t, node)
+	assert.ErrorContains(t, res.Err, "timed out after 10ms")
+	assert.ErrorIs(t, res.Err, context.DeadlineExceeded)
+	assert.Equal(t, []core.NodeEventKind{
+		core.NodeQueued, core.NodeStarted, core.NodeRetrying, core.NodeFailed,
+	}, eventKinds(events()))
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyTracker tracks how many actions run at once.
+type concurrencyTracker struct {
+	mu           sync.Mutex
+	current, max int
+}
+
+// Runs count actions of the given category that each sleep briefly, and
+// returns how many ran at once.
+func runConcurrently(t *testing.T, category string, count int) int {
+	tracker := &concurrencyTracker{}
+	g := core.NewGraph()
+	for range count {
+		g.AddNode(&core.Node{Name: "Sleep", Action: &fakeAction{
+			tag:         "concurrencyAction",
+			sideEffects: true,
+			category:    category,
+			run: func(ctx context.Context, n *core.Node, run int32) core.NodeActionResult {
+				tracker.mu.Lock()
+				tracker.current++
+				tracker.max = max(tracker.max, tracker.current)
+				tracker.mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				tracker.mu.Lock()
+				tracker.current--
+				tracker.mu.Unlock()
+				return core.NodeActionResult{}
+			},
+		}})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var dones []<-chan struct{}
+	for _, n := range g.Nodes {
+		dones = append(dones, n.Run(ctx, false))
+	}
+	for _, done := range dones {
+		<-done
+	}
+	for _, n := range g.Nodes {
+		res, ok := n.GetResult()
+		assert.True(t, ok)
+		assert.NoError(t, res.Err)
+	}
+	return tracker.max
+}
+
+func TestScheduler(t *testing.T) {
+	defer func(prev *core.Scheduler) { core.NodeScheduler = prev }(core.NodeScheduler)
+
+	t.Run("Global limit", func(t *testing.T) {
+		core.NodeScheduler = core.NewScheduler(2, nil)
+		assert.LessOrEqual(t, runConcurrently(t, "", 6), 2)
+	})
+
+	t.Run("Category limit", func(t *testing.T) {
+		core.NodeScheduler = core.NewScheduler(0, map[string]int{core.SchedCategoryHTTP: 1})
+		assert.Equal(t, 1, runConcurrently(t, core.SchedCategoryHTTP, 4))
+	})
+
+	t.Run("Coordinators are not limited", func(t *testing.T) {
+		core.NodeScheduler = core.NewScheduler(1, nil)
+		release, err := core.NodeScheduler.Acquire(context.Background(), "")
+		assert.NoError(t, err)
+		defer release()
+
+		_, err = core.NodeScheduler.Acquire(context.Background(), core.SchedCategoryCoordinator)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Subflows under a global limit of 1", func(t *testing.T) {
+		core.NodeScheduler = core.NewScheduler(1, nil)
+
+		// The subflow doubles its Input.
+		sub := core.NewGraph()
+		in, double, out := nodes.NewGraphInputNode(), nodes.NewFormulaNode(), nodes.NewGraphOutputNode()
+		in.Action.(*nodes.GraphInputAction).Name = "Input"
+		double.Action.(*nodes.FormulaAction).Expression = "Input * 2"
+		for _, n := range []*core.Node{in, double, out} {
+			sub.AddNode(n)
+		}
+		sub.AddWire(in, 0, double, 0)
+		sub.AddWire(double, 0, out, 0)
+		path := filepath.Join(t.TempDir(), "double.flow")
+		assert.NoError(t, core.SaveGraph(path, sub))
+
+		ints := core.NewListValue(core.FlowType{Kind: core.FSKindInt64}, []core.FlowValue{
+			core.NewInt64Value(1, 0),
+			core.NewInt64Value(2, 0),
+			core.NewInt64Value(3, 0),
+		})
+
+		reduce := nodes.NewReduceNode()
+		reduce.Action.(*nodes.ReduceAction).UseSubflow = true
+		reduce.Action.(*nodes.ReduceAction).SubflowPath = path
+		setupGraph(reduce, ints)
+		res := runAndWait(t, reduce)
+		if assert.NoError(t, res.Err) {
+			assert.Equal(t, int64(6), res.Outputs[0].Int64Value)
+		}
+
+		filter := nodes.NewFilterNode()
+		filter.Action.(*nodes.FilterAction).UseSubflow = true
+		filter.Action.(*nodes.FilterAction).SubflowPath = path
+		setupGraph(filter, ints)
+		res = runAndWait(t, filter)
+		if assert.NoError(t, res.Err) {
+			assert.Len(t, res.Outputs[0].ListValue, 3)
+		}
+
+		// Expressions are evaluated by the nodes themselves, so they are
+		// limited like any other node.
+		reduce.Action.(*nodes.ReduceAction).UseSubflow = false
+		filter.Action.(*nodes.FilterAction).UseSubflow = false
+		assert.Equal(t, "", reduce.Action.(*nodes.ReduceAction).SchedCategory())
+		assert.Equal(t, "", filter.Action.(*nodes.FilterAction).SchedCategory())
+	})
+
+	t.Run("Cancelled while waiting", func(t *testing.T) {
+		s := core.NewScheduler(1, nil)
+		release, err := s.Acquire(context.Background(), "")
+		assert.NoError(t, err)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = s.Acquire(ctx, "")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
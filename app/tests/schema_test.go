@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+// A greeter is at schema version 1, which added Count. Version 0 saves only
+// the greeting.
+func newGreeter(greeting string, count, schema int) *fakeAction {
+	return &fakeAction{tag: "greeterAction", Text: greeting, Count: count, schema: schema}
+}
+
+func init() {
+	core.RegisterNodeAction("greeterAction", func() core.NodeAction { return newGreeter("", 0, 1) })
+	core.RegisterNodeActionUpgrade("greeterAction", 0, func(s *core.Serializer, a core.NodeAction) bool {
+		g := a.(*fakeAction)
+		core.SStr(s, &g.Text)
+		g.Count = 1
+		return s.Ok()
+	})
+}
+
+func roundTripGreeter(t *testing.T, saved *fakeAction) (*core.Graph, error) {
+	g := core.NewGraph()
+	g.AddNode(&core.Node{Name: "Greeter", Action: saved})
+	data, err := core.SerializeGraph(g)
+	assert.NoError(t, err)
+	return core.DeserializeGraph(data)
+}
+
+func TestActionSchemaVersions(t *testing.T) {
+	t.Run("Current version", func(t *testing.T) {
+		loaded, err := roundTripGreeter(t, newGreeter("hi", 3, 1))
+		assert.NoError(t, err)
+		assert.Equal(t, newGreeter("hi", 3, 1), loaded.Nodes[0].Action)
+	})
+
+	t.Run("Upgrade from older version", func(t *testing.T) {
+		loaded, err := roundTripGreeter(t, newGreeter("hi", 3, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, newGreeter("hi", 1, 1), loaded.Nodes[0].Action)
+	})
+
+	t.Run("Newer version", func(t *testing.T) {
+		_, err := roundTripGreeter(t, newGreeter("hi", 0, 2))
+		assert.ErrorContains(t, err, "greeterAction settings have schema version 2")
+	})
+}
+
+// Every flow in examples/, including the old saves kept in examples/history,
+// must still load, and must save and load again without changing.
+func TestLoadExamples(t *testing.T) {
+	var paths []string
+	err := filepath.WalkDir("../../examples", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".flow" {
+			paths = append(paths, path)
+		}
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			g, err := core.LoadGraph(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Empty(t, g.LoadProblems)
+			for _, n := range g.Nodes {
+				_, unknown := n.Action.(*core.UnknownAction)
+				assert.False(t, unknown, "%s has an unknown action type", n)
+			}
+
+			data, err := core.SerializeGraph(g)
+			assert.NoError(t, err)
+			loaded, err := core.DeserializeGraph(data)
+			assert.NoError(t, err)
+			again, err := core.SerializeGraph(loaded)
+			assert.NoError(t, err)
+			assert.Equal(t, data, again)
+		})
+	}
+
+	t.Run("Chart without a size", func(t *testing.T) {
+		g, err := core.LoadGraph("../../examples/history/v4/http_dashboard.flow")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, g.Wires, 3)
+		chart := g.Nodes[3].Action.(*nodes.BarChartAction)
+		assert.Equal(t, float32(400), chart.Width)
+		assert.Equal(t, float32(200), chart.Height)
+
+		// Here the chart is the last node, so only the wires follow it.
+		g, err = core.LoadGraph("../../examples/history/v4/debug_chart.flow")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, g.Wires, 1)
+		chart = g.Nodes[1].Action.(*nodes.BarChartAction)
+		assert.Equal(t, float32(400), chart.Width)
+		assert.Equal(t, float32(200), chart.Height)
+	})
+
+	t.Run("CSV and regex nodes before nullable types", func(t *testing.T) {
+		g, err := core.LoadGraph("../../examples/history/v4/log_analysis.flow")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.False(t, g.Nodes[0].Action.(*nodes.LoadFileAction).NullEmptyCells)
+		assert.False(t, g.Nodes[2].Action.(*nodes.RegexMatchAction).BoolMatch)
+		assert.Equal(t, core.FSKindInt64, g.Nodes[2].OutputPorts[0].Type.Kind)
+		assert.True(t, nodes.NewLoadFileNode("").Action.(*nodes.LoadFileAction).NullEmptyCells)
+		assert.True(t, nodes.NewRegexMatchNode().Action.(*nodes.RegexMatchAction).BoolMatch)
+	})
+
+	t.Run("Chart with a size", func(t *testing.T) {
+		// examples/history/v4/debug_chart.flow with a size of 500x250 after the
+		// chart's columns, as saved by builds that added it.
+		g, err := core.LoadGraph("testdata/bar_chart_size_v4.flow")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, g.Wires, 1)
+		chart := g.Nodes[1].Action.(*nodes.BarChartAction)
+		assert.Equal(t, float32(500), chart.Width)
+		assert.Equal(t, float32(250), chart.Height)
+	})
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app"
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the ID of a process that has exited.
+func exitedPID(t *testing.T) int {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	assert.NoError(t, cmd.Run())
+	return cmd.Process.Pid
+}
+
+// Moves this instance's recovery file to the one of another process, as if
+// that process had written it.
+func moveRecovery(t *testing.T, pid int) {
+	dir := filepath.Dir(app.GetRecoveryPath())
+	name := strings.Replace(filepath.Base(app.GetRecoveryPath()), strconv.Itoa(os.Getpid()), strconv.Itoa(pid), 1)
+	assert.NoError(t, os.Rename(app.GetRecoveryPath(), filepath.Join(dir, name)))
+	assert.NoError(t, os.Rename(filepath.Join(dir, fmt.Sprintf("%d.json", os.Getpid())), filepath.Join(dir, fmt.Sprintf("%d.json", pid))))
+}
+
+func TestRecovery(t *testing.T) {
+	g := core.NewGraph()
+	g.AddNode(nodes.NewValueNode(core.NewStringValue("unsaved")))
+	data, err := core.SerializeGraph(g)
+	assert.NoError(t, err)
+	info := app.RecoveryInfo{Filename: "work.flow", SavedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	t.Run("Left by an exited instance", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		assert.NoError(t, app.WriteRecovery(data, info))
+
+		// This instance's own file is not a crashed session.
+		_, _, ok, err := app.LoadRecovery()
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		moveRecovery(t, exitedPID(t))
+		loaded, loadedInfo, ok, err := app.LoadRecovery()
+		assert.NoError(t, err)
+		if assert.True(t, ok) {
+			assert.Len(t, loaded.Nodes, 1)
+			assert.Equal(t, "work.flow", loadedInfo.Filename)
+			assert.True(t, info.SavedAt.Equal(loadedInfo.SavedAt))
+		}
+
+		// It is now this instance's, so it is not recovered twice.
+		assert.FileExists(t, app.GetRecoveryPath())
+		_, _, ok, _ = app.LoadRecovery()
+		assert.False(t, ok)
+
+		app.RemoveRecovery()
+		assert.NoFileExists(t, app.GetRecoveryPath())
+	})
+
+	t.Run("Left by a running instance", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		assert.NoError(t, app.WriteRecovery(data, info))
+		moveRecovery(t, os.Getppid())
+
+		_, _, ok, err := app.LoadRecovery()
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		_, _, ok, err := app.LoadRecovery()
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
+
+func TestUnsavedChanges(t *testing.T) {
+	defer func(prev *core.Graph) { app.SetRootGraph(prev) }(app.CurrentGraph)
+	t.Chdir(t.TempDir())
+
+	g := core.NewGraph()
+	app.SetRootGraph(g)
+	app.MarkSaved()
+	app.CheckUnsavedChanges()
+	assert.False(t, app.HasUnsavedChanges)
+
+	discarded := false
+	app.ConfirmDiscardChanges("Discard?", "Discard", func() { discarded = true })
+	assert.True(t, discarded, "nothing to discard, so no need to ask")
+	assert.Nil(t, app.PendingDiscard)
+
+	g.AddNode(nodes.NewValueNode(core.NewInt64Value(1, 0)))
+	app.CheckUnsavedChanges()
+	assert.True(t, app.HasUnsavedChanges)
+
+	discarded = false
+	app.ConfirmDiscardChanges("Discard?", "Discard", func() { discarded = true })
+	assert.False(t, discarded)
+	if assert.NotNil(t, app.PendingDiscard) {
+		assert.Equal(t, "Discard", app.PendingDiscard.Button)
+	}
+	app.PendingDiscard = nil
+
+	// Saving, and so marking the graph clean, removes the recovery file.
+	assert.NoError(t, app.WriteRecovery([]byte("stale"), app.RecoveryInfo{}))
+	app.MarkSaved()
+	assert.False(t, app.HasUnsavedChanges)
+	assert.NoFileExists(t, app.GetRecoveryPath())
+	app.CheckUnsavedChanges()
+	assert.False(t, app.HasUnsavedChanges)
+}
+
+// A text flow with a node whose type is not registered. Its settings can only
+// be written back as text.
+const unknownNodeTextFlow = `{
+  "format": "flowshell",
+  "version": 12,
+  "nodes": [
+    {
+      "id": 1,
+      "name": "Missing Plugin",
+      "pos": [0, 0],
+      "action": "removedAction",
+      "settings": {"Pattern": "x"}
+    }
+  ],
+  "wires": []
+}
+`
+
+func TestUnsavedChangesTextOnly(t *testing.T) {
+	defer func(prev *core.Graph) { app.SetRootGraph(prev) }(app.CurrentGraph)
+	t.Chdir(t.TempDir())
+
+	g, err := core.DeserializeGraph([]byte(unknownNodeTextFlow))
+	assert.NoError(t, err)
+	_, err = core.SerializeGraph(g)
+	assert.Error(t, err, "unknown JSON settings should not be saved as binary")
+
+	app.SetRootGraph(g)
+	app.MarkSaved()
+	app.CheckUnsavedChanges()
+	assert.False(t, app.HasUnsavedChanges)
+
+	g.AddNode(nodes.NewValueNode(core.NewInt64Value(1, 0)))
+	data, err := app.CheckUnsavedChanges()
+	assert.NoError(t, err)
+	assert.True(t, app.HasUnsavedChanges)
+
+	// The recovery file is written as text.
+	assert.NoError(t, app.WriteRecovery(data, app.RecoveryInfo{}))
+	assert.True(t, strings.HasSuffix(app.GetRecoveryPath(), ".json"), app.GetRecoveryPath())
+
+	moveRecovery(t, exitedPID(t))
+	loaded, _, ok, err := app.LoadRecovery()
+	assert.NoError(t, err)
+	if assert.True(t, ok) && assert.Len(t, loaded.Nodes, 2) {
+		unknown, isUnknown := loaded.Nodes[0].Action.(*core.UnknownAction)
+		if assert.True(t, isUnknown) {
+			assert.Equal(t, "removedAction", unknown.OriginalTag)
+			assert.JSONEq(t, `{"Pattern": "x"}`, string(unknown.SettingsJSON))
+		}
+	}
+
+	app.MarkSaved()
+	assert.False(t, app.HasUnsavedChanges)
+	assert.NoFileExists(t, app.GetRecoveryPath())
+}
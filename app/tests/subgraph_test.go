@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubgraph(t *testing.T) {
+	setup := func() (g *core.Graph, value, trim, upper, after *core.Node) {
+		g = core.NewGraph()
+		value = nodes.NewValueNode(core.NewStringValue("  hi  "))
+		trim = nodes.NewTrimSpacesNode()
+		upper = nodes.NewCaseConvertNode()
+		after = nodes.NewTrimSpacesNode()
+		for _, n := range []*core.Node{value, trim, upper, after} {
+			g.AddNode(n)
+		}
+		g.AddWire(value, 0, trim, 0)
+		g.AddWire(trim, 0, upper, 0)
+		g.AddWire(upper, 0, after, 0)
+		return
+	}
+
+	t.Run("Collapse", func(t *testing.T) {
+		g, value, trim, upper, after := setup()
+		sub, err := nodes.CollapseToSubgraph(g, []int{trim.ID, upper.ID})
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, []*core.Node{value, after, sub}, g.Nodes)
+		assert.Len(t, g.Wires, 2)
+		assert.Equal(t, []core.NodePort{{Name: "Text", Type: core.FlowType{Kind: core.FSKindBytes}}}, sub.InputPorts)
+		assert.Equal(t, []core.NodePort{{Name: "Text", Type: core.FlowType{Kind: core.FSKindBytes}}}, sub.OutputPorts)
+
+		embedded := sub.Action.(*nodes.SubgraphAction).Graph
+		assert.Len(t, embedded.Nodes, 4)
+		assert.Len(t, embedded.Wires, 3)
+		assert.Same(t, embedded, trim.Graph)
+
+		assert.NoError(t, g.UpdateAndValidate())
+		assert.True(t, sub.Valid)
+		res := runAndWait(t, after)
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "HI", string(res.Outputs[0].BytesValue))
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		g, _, trim, upper, _ := setup()
+		_, err := nodes.CollapseToSubgraph(g, []int{trim.ID, upper.ID})
+		assert.NoError(t, err)
+
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		loaded, err := core.DeserializeGraph(data)
+		assert.NoError(t, err)
+		again, err := core.SerializeGraph(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+
+		// The embedded graph is readable in the text format.
+		text, err := core.SerializeGraphText(loaded)
+		assert.NoError(t, err)
+		assert.Contains(t, string(text), `"action": "TrimSpacesAction"`)
+		fromText, err := core.DeserializeGraph(text)
+		assert.NoError(t, err)
+		again, err = core.SerializeGraph(fromText)
+		assert.NoError(t, err)
+		assert.Equal(t, data, again)
+
+		res := runAndWait(t, fromText.Nodes[1])
+		assert.NoError(t, res.Err)
+		assert.Equal(t, "HI", string(res.Outputs[0].BytesValue))
+	})
+
+	t.Run("Ports follow graph inputs and outputs", func(t *testing.T) {
+		g, _, trim, upper, after := setup()
+		sub, err := nodes.CollapseToSubgraph(g, []int{trim.ID, upper.ID})
+		assert.NoError(t, err)
+		sub.SetErrorPort(true)
+		g.AddWire(sub, 1, after, 0)
+
+		embedded := sub.Action.(*nodes.SubgraphAction).Graph
+		out := nodes.NewGraphOutputNode()
+		out.Action.(*nodes.GraphOutputAction).Name = "Raw"
+		embedded.AddNode(out)
+		embedded.AddWire(trim, 0, out, 0)
+
+		sub.Action.UpdateAndValidate(sub)
+		assert.Equal(t, []string{"Text", "Raw", core.ErrorPortName}, portNames(sub.OutputPorts))
+		// The wire from the error port moved with it.
+		assert.Equal(t, 2, g.Wires[len(g.Wires)-1].StartPort)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		g, value, _, upper, _ := setup()
+		_, err := nodes.CollapseToSubgraph(g, []int{value.ID, upper.ID})
+		assert.ErrorContains(t, err, "would create a cycle")
+		assert.Len(t, g.Nodes, 4)
+	})
+
+	t.Run("Clear cached results", func(t *testing.T) {
+		g, _, trim, upper, after := setup()
+		_, err := nodes.CollapseToSubgraph(g, []int{trim.ID, upper.ID})
+		assert.NoError(t, err)
+		assert.NoError(t, g.UpdateAndValidate())
+
+		rerun := func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			<-after.Run(ctx, true)
+		}
+		rerun()
+
+		// Nodes inside the subgraph are cleared along with the outer ones.
+		events := recordEvents(t, upper)
+		nodes.ClearCachedResults(g)
+		rerun()
+		for _, ev := range events() {
+			if ev.Kind == core.NodeFinished {
+				assert.False(t, ev.Cached, "%s should run again", ev.Node)
+			}
+		}
+		assert.Contains(t, eventKinds(events()), core.NodeStarted)
+	})
+}
+
+func portNames(ports []core.NodePort) []string {
+	var names []string
+	for _, p := range ports {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTextFormatGraph() *core.Graph {
+	b := core.NewGraphBuilder()
+	load := b.Add(nodes.NewLoadFileNode("people.csv")).SetPosition(100, 100)
+	selectCols := b.Add(nodes.NewSelectColumnsNode()).SetPosition(400, 100.5)
+	selectCols.Node.Action.(*nodes.SelectColumnsAction).SelectedColumns = []string{"name", "age"}
+	save := b.Add(nodes.NewSaveFileNode()).SetPosition(700, 100)
+	save.Node.Action.(*nodes.SaveFileAction).Path = "out.csv"
+	load.To(selectCols).To(save)
+
+	value := b.Add(nodes.NewValueNode(core.NewInt64Value(42, 0))).SetPosition(100, 300)
+	value.Node.Retry = core.RetryPolicy{MaxAttempts: 3, Backoff: 1500 * time.Millisecond, RetryCodes: "503"}
+	value.Node.Timeout = time.Minute
+	selectCols.Node.SetErrorPort(true)
+
+	b.Graph.AddGroup(&core.Group{Title: "Cleanup"})
+	b.Graph.Variables["DIR"] = "/tmp"
+	return b.Graph
+}
+
+func TestTextFormat(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		g := setupTextFormatGraph()
+		binary, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+
+		text, err := core.SerializeGraphText(g)
+		assert.NoError(t, err)
+		assert.True(t, core.IsTextFormat(text))
+		assert.False(t, core.IsTextFormat(binary))
+		assert.Contains(t, string(text), `"SelectedColumns": [`)
+		assert.Contains(t, string(text), `"timeout": "1m0s"`)
+
+		loaded, err := core.DeserializeGraph(text)
+		assert.NoError(t, err)
+		again, err := core.SerializeGraph(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, binary, again)
+
+		// Text written from the loaded graph is identical, too.
+		textAgain, err := core.SerializeGraphText(loaded)
+		assert.NoError(t, err)
+		assert.Equal(t, string(text), string(textAgain))
+	})
+
+	t.Run("Save and load by extension", func(t *testing.T) {
+		g := setupTextFormatGraph()
+		dir := t.TempDir()
+		textPath := filepath.Join(dir, "graph.flow.json")
+		binaryPath := filepath.Join(dir, "graph.flow")
+
+		assert.NoError(t, core.SaveGraph(textPath, g))
+		data, err := os.ReadFile(textPath)
+		assert.NoError(t, err)
+		assert.True(t, core.IsTextFormat(data))
+
+		loaded, err := core.LoadGraph(textPath)
+		assert.NoError(t, err)
+		assert.NoError(t, core.SaveGraph(binaryPath, loaded))
+		data, err = os.ReadFile(binaryPath)
+		assert.NoError(t, err)
+		assert.False(t, core.IsTextFormat(data))
+
+		loaded, err = core.LoadGraph(binaryPath)
+		assert.NoError(t, err)
+		assert.Len(t, loaded.Nodes, 4)
+		assert.Len(t, loaded.Wires, 2)
+		assert.Equal(t, "/tmp", loaded.Variables["DIR"])
+	})
+
+	t.Run("Unknown action", func(t *testing.T) {
+		g, err := core.DeserializeGraph([]byte(`{"format": "flowshell", "version": 10, "nodes": [{"id": 1, "name": "Mystery", "pos": [0, 0], "action": "mysteryAction", "settings": {"Answer": 42}}], "wires": []}`))
+		assert.NoError(t, err)
+		unknown, ok := g.Nodes[0].Action.(*core.UnknownAction)
+		if assert.True(t, ok) {
+			assert.Equal(t, "mysteryAction", unknown.OriginalTag)
+		}
+
+		text, err := core.SerializeGraphText(g)
+		assert.NoError(t, err)
+		assert.Contains(t, string(text), `"Answer": 42`)
+
+		_, err = core.SerializeGraph(g)
+		assert.ErrorContains(t, err, "can only be saved in the text format")
+	})
+}
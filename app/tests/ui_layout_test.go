@@ -60,6 +60,13 @@ func TestNodeUILayouts(t *testing.T) {
 		nodes.NewFormulaNode,
 		func() *core.Node { return nodes.NewLoadFileNode("test.txt") },
 		nodes.NewMapNode,
+		nodes.NewReduceNode,
+		nodes.NewFilterNode,
+		nodes.NewMapKeysNode,
+		nodes.NewMapValuesNode,
+		nodes.NewMapEntriesNode,
+		nodes.NewCallFlowNode,
+		nodes.NewRepeatNode,
 		nodes.NewSelectColumnsNode,
 		nodes.NewSortNode,
 		nodes.NewGateNode,
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bvisness/flowshell/app"
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownAction(t *testing.T) {
+	g := core.NewGraph()
+	value := nodes.NewValueNode(core.NewStringValue("hi"))
+	g.AddNode(value)
+	missing := &core.Node{
+		Name:        "Missing Plugin",
+		InputPorts:  []core.NodePort{{Name: "In", Type: core.FlowType{Kind: core.FSKindBytes}}},
+		OutputPorts: []core.NodePort{{Name: "Out", Type: core.FlowType{Kind: core.FSKindBytes}}},
+		Action:      &core.UnknownAction{OriginalTag: "removedAction", Settings: []byte{1, 2, 3}, Version: core.SerializeVersion},
+	}
+	g.AddNode(missing)
+	trim := nodes.NewTrimSpacesNode()
+	g.AddNode(trim)
+	g.AddWire(value, 0, missing, 0)
+	g.AddWire(missing, 0, trim, 0)
+
+	data, err := core.SerializeGraph(g)
+	assert.NoError(t, err)
+	loaded, err := core.DeserializeGraph(data)
+	assert.NoError(t, err)
+
+	unknown, ok := loaded.Nodes[1].Action.(*core.UnknownAction)
+	if assert.True(t, ok) {
+		assert.Equal(t, "removedAction", unknown.OriginalTag)
+		assert.Equal(t, []byte{1, 2, 3}, unknown.Settings)
+	}
+	assert.Len(t, loaded.Nodes[1].InputPorts, 1)
+	assert.Len(t, loaded.Wires, 2)
+
+	// The nodes after the unknown one are read correctly.
+	_, isTrim := loaded.Nodes[2].Action.(*nodes.TrimSpacesAction)
+	assert.True(t, isTrim)
+
+	again, err := core.SerializeGraph(loaded)
+	assert.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	// The unknown settings survive the text format, too.
+	text, err := core.SerializeGraphText(loaded)
+	assert.NoError(t, err)
+	fromText, err := core.DeserializeGraph(text)
+	assert.NoError(t, err)
+	again, err = core.SerializeGraph(fromText)
+	assert.NoError(t, err)
+	assert.Equal(t, data, again)
+
+	res := runAndWait(t, loaded.Nodes[1])
+	assert.ErrorContains(t, res.Err, `unknown node type "removedAction"`)
+}
+
+// Settings read from an older binary file are not written into a file of the
+// current version, since their layout may depend on the version. The text
+// format keeps them with their version.
+func TestUnknownActionFromOlderVersion(t *testing.T) {
+	g := core.NewGraph()
+	g.AddNode(&core.Node{
+		Name:   "Missing Plugin",
+		Action: &core.UnknownAction{OriginalTag: "removedAction", Settings: []byte{1, 2, 3}, Version: 11, Schema: 2},
+	})
+
+	_, err := core.SerializeGraph(g)
+	assert.ErrorContains(t, err, "from a version 11 file, so they can only be saved in the text format")
+
+	text, err := core.SerializeGraphText(g)
+	assert.NoError(t, err)
+	loaded, err := core.DeserializeGraph(text)
+	assert.NoError(t, err)
+	unknown, ok := loaded.Nodes[0].Action.(*core.UnknownAction)
+	if assert.True(t, ok) {
+		assert.Equal(t, []byte{1, 2, 3}, unknown.Settings)
+		assert.Equal(t, 11, unknown.Version)
+		assert.Equal(t, 2, unknown.Schema)
+	}
+}
+
+func TestUnknownActionBeforeVersion10(t *testing.T) {
+	// examples/history/v4/csv_pipeline.flow, with the tag of its third node
+	// changed to one that is not registered.
+	path := "testdata/unknown_node_v4.flow"
+
+	g, err := core.LoadGraph(path)
+	var unknown *core.UnknownActionError
+	if assert.ErrorAs(t, err, &unknown) {
+		assert.Equal(t, "RemovedColumnAction", unknown.Tag)
+		assert.Equal(t, 4, unknown.Version)
+	}
+	assert.ErrorContains(t, err, `unknown node type "RemovedColumnAction"`)
+	assert.ErrorContains(t, err, "before unknown nodes could be skipped")
+
+	// The nodes before it are kept.
+	if assert.NotNil(t, g) {
+		if assert.Len(t, g.Nodes, 2) {
+			assert.Equal(t, "Load File", g.Nodes[0].Name)
+			assert.Equal(t, "Filter Empty", g.Nodes[1].Name)
+		}
+		assert.Empty(t, g.Wires)
+		assert.Equal(t, []string{"read only 2 of 4 nodes, and no wires, groups or variables"}, g.LoadProblems)
+	}
+
+	assert.Equal(t, app.ExitLoadFailed, app.ValidateFlow(path))
+}
@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/bvisness/flowshell/app/core"
+	"github.com/bvisness/flowshell/app/nodes"
+	"github.com/stretchr/testify/assert"
+)
+
+func issueMessages(issues []core.ValidationIssue) []string {
+	var msgs []string
+	for _, issue := range issues {
+		msgs = append(msgs, issue.String())
+	}
+	return msgs
+}
+
+func TestValidateGraph(t *testing.T) {
+	t.Run("Valid graph", func(t *testing.T) {
+		g := core.NewGraph()
+		value := nodes.NewValueNode(core.NewStringValue("  hi  "))
+		trim := nodes.NewTrimSpacesNode()
+		g.AddNode(value)
+		g.AddNode(trim)
+		g.AddWire(value, 0, trim, 0)
+
+		assert.Empty(t, issueMessages(core.ValidateGraph(g)))
+	})
+
+	t.Run("Type mismatch", func(t *testing.T) {
+		g := core.NewGraph()
+		value := nodes.NewValueNode(core.NewInt64Value(3, 0))
+		trim := nodes.NewTrimSpacesNode()
+		g.AddNode(value)
+		g.AddNode(trim)
+		g.AddWire(value, 0, trim, 0)
+
+		issues := core.ValidateGraph(g)
+		if assert.Len(t, issues, 1) {
+			assert.Equal(t, trim, issues[0].Node)
+			assert.Contains(t, issues[0].Message, "input port 0")
+		}
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		g := core.NewGraph()
+		a := nodes.NewTrimSpacesNode()
+		b := nodes.NewTrimSpacesNode()
+		g.AddNode(a)
+		g.AddNode(b)
+		g.AddWire(a, 0, b, 0)
+		g.AddWire(b, 0, a, 0)
+
+		assert.Contains(t, issueMessages(core.ValidateGraph(g)), "cycle detected among "+a.String()+", "+b.String())
+	})
+
+	t.Run("Missing port", func(t *testing.T) {
+		g := core.NewGraph()
+		value := nodes.NewValueNode(core.NewStringValue("hi"))
+		trim := nodes.NewTrimSpacesNode()
+		g.AddNode(value)
+		g.AddNode(trim)
+		g.AddWire(value, 0, trim, 3)
+
+		issues := core.ValidateGraph(g)
+		if assert.Len(t, issues, 1) {
+			assert.Equal(t, trim, issues[0].Node)
+			assert.Equal(t, "wire ends at missing input port 3", issues[0].Message)
+		}
+	})
+
+	t.Run("Dangling wire", func(t *testing.T) {
+		g := core.NewGraph()
+		value := nodes.NewValueNode(core.NewStringValue("hi"))
+		trim := nodes.NewTrimSpacesNode()
+		g.AddNode(value)
+		g.AddNode(trim)
+		g.AddWire(value, 0, trim, 0)
+		g.Nodes = g.Nodes[1:] // drop the value node but keep its wire
+
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		loaded, err := core.DeserializeGraph(data)
+		assert.NoError(t, err)
+
+		assert.Contains(t, issueMessages(core.ValidateGraph(loaded)), "skipped wire with missing nodes (1 -> 2)")
+	})
+
+	t.Run("Unknown action", func(t *testing.T) {
+		g := core.NewGraph()
+		var runs int32
+		g.AddNode(newCountingNode(&runs)) // not registered
+
+		data, err := core.SerializeGraph(g)
+		assert.NoError(t, err)
+		loaded, err := core.DeserializeGraph(data)
+		assert.NoError(t, err)
+		assert.Contains(t, issueMessages(core.ValidateGraph(loaded)), `Node#1(Counting): unknown action type "countingAction"`)
+	})
+}
+
+func TestLoadKeepsNodeIDs(t *testing.T) {
+	g := core.NewGraph()
+	value := nodes.NewValueNode(core.NewStringValue("hi"))
+	trim := nodes.NewTrimSpacesNode()
+	g.AddNode(value)
+	g.AddNode(&core.Node{Name: "Deleted", Action: &nodes.TrimSpacesAction{}})
+	g.AddNode(trim)
+	g.AddWire(value, 0, trim, 0)
+	g.DeleteNode(2)
+
+	data, err := core.SerializeGraph(g)
+	assert.NoError(t, err)
+	loaded, err := core.DeserializeGraph(data)
+	assert.NoError(t, err)
+
+	if assert.Len(t, loaded.Nodes, 2) && assert.Len(t, loaded.Wires, 1) {
+		assert.Equal(t, 1, loaded.Nodes[0].ID)
+		assert.Equal(t, 3, loaded.Nodes[1].ID)
+		assert.Equal(t, loaded.Nodes[1], loaded.Wires[0].EndNode)
+	}
+	assert.Equal(t, 3, loaded.NextNodeID)
+}
@@ -3,12 +3,12 @@ package app
 import (
 	"context"
 	"fmt"
-	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bvisness/flowshell/app/core"
@@ -37,10 +37,28 @@ const NodeMinWidth = 360
 
 var CurrentGraph = core.NewGraph()
 var CurrentFilename string
+
+// The profile of the most recent run started from the Run button. It is set
+// when the run finishes, on the run's goroutine, so it is behind a mutex.
+var lastRunProfile *core.Profiler
+var lastRunProfileMu sync.Mutex
+
+func LastRunProfile() *core.Profiler {
+	lastRunProfileMu.Lock()
+	defer lastRunProfileMu.Unlock()
+	return lastRunProfile
+}
+
+func setLastRunProfile(p *core.Profiler) {
+	lastRunProfileMu.Lock()
+	defer lastRunProfileMu.Unlock()
+	lastRunProfile = p
+}
+
 var History *HistoryManager
 
 func InitHistory() {
-	History = NewHistoryManager(CurrentGraph)
+	History = NewHistoryManager(RootGraph())
 	core.PushHistoryFunc = PushHistory
 }
 
@@ -48,7 +66,7 @@ func PushHistory() {
 	if History == nil {
 		InitHistory()
 	}
-	History.Push(CurrentGraph)
+	History.Push(RootGraph())
 }
 
 type NodeType struct {
@@ -96,10 +114,18 @@ var nodeTypes = []NodeType{
 	{Name: "Parse Time", Category: "Data", Create: func() *core.Node { return nodes.NewParseTimeNode() }},
 	{Name: "JSON Query", Category: "Data", Create: func() *core.Node { return nodes.NewJsonQueryNode() }},
 	{Name: "XML Query", Category: "Data", Create: func() *core.Node { return nodes.NewXmlQueryNode() }},
+	{Name: "Map Keys", Category: "Data", Create: func() *core.Node { return nodes.NewMapKeysNode() }},
+	{Name: "Map Values", Category: "Data", Create: func() *core.Node { return nodes.NewMapValuesNode() }},
+	{Name: "Map Entries", Category: "Data", Create: func() *core.Node { return nodes.NewMapEntriesNode() }},
 	{Name: "Get Variable", Category: "Core", Create: func() *core.Node { return nodes.NewGetVariableNode() }},
 	{Name: "Map", Category: "Table", Create: func() *core.Node { return nodes.NewMapNode() }},
+	{Name: "Reduce", Category: "Table", Create: func() *core.Node { return nodes.NewReduceNode() }},
+	{Name: "Filter", Category: "Table", Create: func() *core.Node { return nodes.NewFilterNode() }},
 	{Name: "Graph Input", Category: "Graph", Create: func() *core.Node { return nodes.NewGraphInputNode() }},
 	{Name: "Graph Output", Category: "Graph", Create: func() *core.Node { return nodes.NewGraphOutputNode() }},
+	{Name: "Call Flow", Category: "Graph", Create: func() *core.Node { return nodes.NewCallFlowNode() }},
+	{Name: "Repeat", Category: "Graph", Create: func() *core.Node { return nodes.NewRepeatNode() }},
+	{Name: "Subgraph", Category: "Graph", Create: func() *core.Node { return nodes.NewSubgraphNode(core.NewGraph()) }},
 	{Name: "Line Chart", Category: "Visualization", Create: func() *core.Node { return nodes.NewLineChartNode() }},
 	{Name: "Bar Chart", Category: "Visualization", Create: func() *core.Node { return nodes.NewBarChartNode() }},
 	{Name: "Scatter Plot", Category: "Visualization", Create: func() *core.Node { return nodes.NewScatterPlotNode() }},
@@ -107,6 +133,7 @@ var nodeTypes = []NodeType{
 	{Name: "If / Else", Category: "Logic", Create: func() *core.Node { return nodes.NewIfElseNode() }},
 	{Name: "Gate", Category: "Logic", Create: func() *core.Node { return nodes.NewGateNode() }},
 	{Name: "Merge", Category: "Logic", Create: func() *core.Node { return nodes.NewMergeNode() }},
+	{Name: "Try", Category: "Logic", Create: func() *core.Node { return nodes.NewTryNode() }},
 }
 
 func init() {
@@ -256,7 +283,7 @@ func DeleteSelectedNodes() {
 func DuplicateNode(original *core.Node) {
 	core.PushHistory()
 	// Clone via Serialization
-	s := core.NewEncoder(1) // version 1
+	s := core.NewEncoder(core.SerializeVersion)
 	original.Serialize(s)
 	data := s.Bytes()
 
@@ -320,7 +347,24 @@ func processInput() {
 							core.PushHistory()
 							node.Pinned = !node.Pinned
 						}},
+						{Label: util.Tern(node.NoCache, "Enable Caching", "Disable Caching"), Action: func() {
+							core.PushHistory()
+							node.NoCache = !node.NoCache
+						}},
+						{Label: util.Tern(node.ErrorPort, "Remove Error Port", "Add Error Port"), Action: func() {
+							core.PushHistory()
+							node.SetErrorPort(!node.ErrorPort)
+						}},
+						{Label: "Retries and Timeout...", Action: func() { OpenRetrySettings(node) }},
 						{Label: "Duplicate", Action: func() { DuplicateNode(node) }}, // DuplicateNode calls core.PushHistory
+						{Label: "Collapse to Subgraph", Action: func() {
+							// Collapse the whole selection if the node is part of it.
+							if IsNodeSelected(node.ID) {
+								CollapseSelectedNodes()
+							} else {
+								CollapseNodes([]int{node.ID})
+							}
+						}},
 						{Label: "Delete", Action: func() {
 							// DeleteSelectedNodes calls core.PushHistory, but here we might delete a single node
 							// that isn't selected? Or we should select it first?
@@ -333,6 +377,9 @@ func processInput() {
 							}
 						}},
 					}
+					if sub, ok := nodes.EmbeddedGraph(node); ok {
+						items = append([]ContextMenuItem{{Label: "Open Subgraph", Action: func() { EnterSubgraph(node, sub) }}}, items...)
+					}
 
 					ContextMenu = &ContextMenuState{
 						Pos:    V2(rl.GetMousePosition()),
@@ -396,66 +443,34 @@ func processInput() {
 	if actions && rl.IsKeyPressed(rl.KeyZ) {
 		if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
 			if g := History.Redo(); g != nil {
-				CurrentGraph = g
 				// Clear selection on undo/redo to avoid ghost selections
 				// Or try to restore? Restoring is hard.
-				clear(SelectedNodes)
-				selectedNodeID = 0
+				RestoreRootGraph(g)
 			}
 		} else {
 			if g := History.Undo(); g != nil {
-				CurrentGraph = g
-				clear(SelectedNodes)
-				selectedNodeID = 0
+				RestoreRootGraph(g)
 			}
 		}
 	}
 	if actions && rl.IsKeyPressed(rl.KeyY) {
 		if g := History.Redo(); g != nil {
-			CurrentGraph = g
-			clear(SelectedNodes)
-			selectedNodeID = 0
+			RestoreRootGraph(g)
 		}
 	}
 
 	// Save Graph
 	if rl.IsKeyPressed(rl.KeyS) && rl.IsKeyDown(rl.KeyLeftControl) {
-		initialDir, _ := os.Getwd()
-		if CurrentFilename != "" {
-			initialDir = filepath.Dir(CurrentFilename)
-		}
-		filename, ok, err := core.SaveFileDialog("Save Flow", initialDir, map[string]string{"flow": "Flow Files"})
-		if err != nil {
-			fmt.Printf("Save error: %v\n", err)
-		} else if ok {
-			// Ensure extension
-			if filepath.Ext(filename) != ".flow" {
-				filename += ".flow"
-			}
-			err := core.SaveGraph(filename, CurrentGraph)
-			if err != nil {
-				fmt.Printf("Failed to save: %v\n", err)
-			}
+		if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+			SaveCurrentGraphAs()
+		} else {
+			SaveCurrentGraph(CurrentFilename)
 		}
 	}
 
 	// Load Graph
 	if rl.IsKeyPressed(rl.KeyL) && rl.IsKeyDown(rl.KeyLeftControl) {
-		cwd, _ := os.Getwd()
-		filename, ok, err := core.OpenFileDialog("Open Flow", cwd, map[string]string{"flow": "Flow Files"})
-		if err != nil {
-			fmt.Printf("Load error: %v\n", err)
-			core.ShowInfoDialog("Error", fmt.Sprintf("Failed to open file dialog: %v", err))
-		} else if ok {
-			core.PushHistory()
-			if g, err := core.LoadGraph(filename); err == nil {
-				CurrentGraph = g
-				clear(SelectedNodes)
-				selectedNodeID = 0
-			} else {
-				fmt.Printf("Failed to load: %v\n", err)
-			}
-		}
+		ConfirmDiscardChanges("Discard changes and load?", "Load", OpenGraph)
 	}
 
 	if rl.IsKeyPressed(rl.KeyC) && (rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)) {
@@ -467,7 +482,11 @@ func processInput() {
 	}
 
 	if rl.IsKeyPressed(rl.KeyG) && (rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)) {
-		CreateGroup()
+		if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+			CollapseSelectedNodes()
+		} else {
+			CreateGroup()
+		}
 	}
 
 	// Create node shortcuts
@@ -526,9 +545,9 @@ func processInput() {
 		}
 
 		if !handled && len(files) > 0 {
-			// Check for .flow file import
-			ext := strings.ToLower(filepath.Ext(files[0]))
-			if ext == ".flow" {
+			// Check for .flow or .flow.json file import
+			name := strings.ToLower(files[0])
+			if strings.HasSuffix(name, ".flow") || strings.HasSuffix(name, ".flow.json") {
 				core.PushHistory()
 				if g, err := core.LoadGraph(files[0]); err == nil {
 					core.MergeGraph(CurrentGraph, g)
@@ -832,7 +851,6 @@ var NewWireSourcePort int
 var NewNodeName string
 var SelectedNodeCategory string
 
-var ShowLoadConfirmation bool
 var ShowVariables bool
 var NewVarKey string
 var NewVarValue string
@@ -858,15 +876,7 @@ var IsOutputCollapsed = false
 
 func UpdateGraph() error {
 	// Sweep the graph, validating all nodes
-	sortedNodes, topoErr := core.Toposort(CurrentGraph.Nodes, CurrentGraph.Wires)
-	if topoErr != nil {
-		// If there is a cycle, we can't toposort. Just use the default order.
-		sortedNodes = CurrentGraph.Nodes
-	}
-	for _, node := range sortedNodes {
-		node.Action.UpdateAndValidate(node)
-	}
-	return topoErr
+	return CurrentGraph.UpdateAndValidate()
 }
 
 func UINodes(topoErr error) {
@@ -914,64 +924,9 @@ func UIOverlay(topoErr error) {
 			})
 		}
 
-		if ShowLoadConfirmation {
-			core.WithZIndex(core.Z_MODAL, func() {
-				clay.CLAY(clay.ID("LoadConfirmation"), clay.EL{
-					Layout:          clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: clay.Sizing{Width: clay.SizingFixed(300)}, ChildAlignment: core.ALLCENTER, Padding: core.PA3, ChildGap: core.S2},
-					BackgroundColor: core.Charcoal,
-					Border:          clay.BorderElementConfig{Width: core.BA2, Color: core.Red},
-					CornerRadius:    core.RA2,
-					Floating: clay.FLOAT{
-						AttachTo: clay.AttachToParent,
-						AttachPoints: clay.FloatingAttachPoints{
-							Element: clay.AttachPointCenterCenter,
-							Parent:  clay.AttachPointCenterCenter,
-						},
-						ZIndex:             core.Z_MODAL,
-						PointerCaptureMode: clay.PointercaptureModeCapture,
-					},
-				}, func() {
-					clay.OnHover(func(elementID clay.ElementID, pointerData clay.PointerData, userData any) {
-						core.IsHoveringUI = true
-					}, nil)
-					clay.TEXT("Discard changes and load?", clay.TextElementConfig{TextColor: core.White, FontID: core.InterBold, FontSize: core.F2})
-					clay.CLAY(clay.AUTO_ID, clay.EL{Layout: clay.LAY{ChildGap: core.S3}}, func() {
-						core.UIButton(clay.ID("ConfirmLoad"), core.UIButtonConfig{
-							El: clay.EL{Layout: clay.LAY{Padding: core.PA2}, BackgroundColor: core.Red, CornerRadius: core.RA1},
-							OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
-								ShowLoadConfirmation = false
-								cwd, _ := os.Getwd()
-								filename, ok, err := core.OpenFileDialog("Open Flow", cwd, map[string]string{"flow": "Flow Files"})
-								if err != nil {
-									fmt.Printf("Load error: %v\n", err)
-									core.ShowInfoDialog("Error", fmt.Sprintf("Failed to open file dialog: %v", err))
-								} else if ok {
-									core.PushHistory()
-									if g, err := core.LoadGraph(filename); err == nil {
-										CurrentGraph = g
-										CurrentFilename = filename
-										clear(SelectedNodes)
-										selectedNodeID = 0
-									} else {
-										core.ShowInfoDialog("Error", fmt.Sprintf("Failed to load file: %v", err))
-									}
-								}
-							},
-						}, func() {
-							clay.TEXT("Load", clay.TextElementConfig{TextColor: core.White})
-						})
-						core.UIButton(clay.ID("CancelLoad"), core.UIButtonConfig{
-							El: clay.EL{Layout: clay.LAY{Padding: core.PA2}, BackgroundColor: core.Gray, CornerRadius: core.RA1},
-							OnClick: func(_ clay.ElementID, _ clay.PointerData, _ any) {
-								ShowLoadConfirmation = false
-							},
-						}, func() {
-							clay.TEXT("Cancel", clay.TextElementConfig{TextColor: core.White})
-						})
-					})
-				})
-			})
-		}
+		UISubgraphBar()
+		UIDiscardConfirmation()
+		UIRecoveryPrompt()
 
 		// Prompt Modal
 		if core.CurrentPrompt != nil {
@@ -1143,6 +1098,8 @@ func UIOverlay(topoErr error) {
 			})
 		}
 
+		UIRetrySettings()
+
 		if topoErr != nil {
 			core.WithZIndex(core.Z_CYCLE_WARNING, func() {
 				clay.CLAY(clay.AUTO_ID, clay.EL{
@@ -1228,7 +1185,11 @@ func UIOverlay(topoErr error) {
 								var ctx context.Context
 								ctx, RunCancel = context.WithCancel(context.Background())
 								RunCtx = ctx
-								err := RunGraph(ctx, CurrentGraph, func(err error) {
+								profiler := core.StartProfiler(RootGraph())
+								err := RunGraph(ctx, RootGraph(), func(err error) {
+									profiler.Stop()
+									setLastRunProfile(profiler)
+
 									// Reset state on UI thread?
 									// Warning: onComplete is called from goroutine.
 									// We are accessing global vars RunCtx/RunCancel.
@@ -1249,6 +1210,7 @@ func UIOverlay(topoErr error) {
 									}
 								})
 								if err != nil {
+									profiler.Stop()
 									fmt.Printf("Run error (immediate): %v\n", err)
 									RunCtx = nil
 									RunCancel = nil
@@ -1604,8 +1566,7 @@ func UIOverlay(topoErr error) {
 													core.UITooltip("Save Flow (Ctrl+S)")
 												},
 												OnClick: func(elementID clay.ElementID, pointerData clay.PointerData, userData any) {
-													core.PushHistory()
-													_ = core.SaveGraph("saved.flow", CurrentGraph)
+													SaveCurrentGraph(CurrentFilename)
 													core.UIFocus = nil // Close menu
 												},
 											}, func() {
@@ -1619,7 +1580,7 @@ func UIOverlay(topoErr error) {
 													core.UITooltip("Load Flow (Ctrl+L)")
 												},
 												OnClick: func(elementID clay.ElementID, pointerData clay.PointerData, userData any) {
-													ShowLoadConfirmation = true
+													ConfirmDiscardChanges("Discard changes and load?", "Load", OpenGraph)
 													core.UIFocus = nil // Close menu
 												},
 											}, func() {
@@ -2157,9 +2118,16 @@ func UINode(node *core.Node, disabled bool) {
 				)
 			})
 			clay.CLAY(clay.IDI("NodeBody", node.ID), clay.EL{ // core.Node body
-				Layout: clay.LAY{Sizing: core.GROWH, Padding: core.PA2},
+				Layout: clay.LAY{LayoutDirection: clay.TopToBottom, Sizing: core.GROWH, Padding: core.PA2, ChildGap: core.S2},
 			}, func() {
 				node.Action.UI(node)
+				if node.ErrorPort {
+					clay.CLAY(clay.IDI("NodeErrorPort", node.ID), clay.EL{
+						Layout: clay.LAY{Sizing: core.GROWH, ChildAlignment: core.XRIGHT},
+					}, func() {
+						core.UIOutputPort(node, node.ErrorPortIndex())
+					})
+				}
 			})
 		})
 	})
@@ -1,22 +1,177 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/bvisness/flowshell/app"
 )
 
 func main() {
 	if len(os.Args) > 1 {
-		if os.Args[1] == "run" {
-			if len(os.Args) < 3 {
-				fmt.Println("Usage: flowshell run <file.flow>")
-				return
-			}
-			app.HeadlessRun(os.Args[2])
+		switch os.Args[1] {
+		case "run":
+			runCommand(os.Args[2:])
+			return
+		case "validate":
+			validateCommand(os.Args[2:])
+			return
+		case "convert":
+			convertCommand(os.Args[2:])
+			return
+		case "clear-cache":
+			clearCacheCommand(os.Args[2:])
 			return
 		}
 	}
 	app.Main()
 }
+
+func runCommand(args []string) {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	cacheDir := flags.String("cache-dir", "", "directory for cached node results (default: cache_dir from settings.json)")
+	noCache := flags.Bool("no-cache", false, "do not read or write cached node results")
+	maxParallel := flags.Int("max-parallel", 0, "maximum number of nodes executing at once (default: max_parallelism from settings.json)")
+	limits := limitFlags{}
+	flags.Var(limits, "limit", "per-category concurrency limit as `category=N`, e.g. http=2 or process=4 (repeatable)")
+	profile := flags.String("profile", "", "write a Chrome trace of the run to this `file` (open in chrome://tracing or Perfetto)")
+	vars := keyValueFlags{}
+	flags.Var(vars, "var", "set a graph variable as `KEY=VALUE` (repeatable)")
+	outputFormat := flags.String("output-format", "", "write graph output values to stdout as `json`, ndjson, or csv")
+	inputs := keyValueFlags{}
+	flags.Var(inputs, "input", "feed the graph input with this name, as `name=value`; the value is parsed as JSON if possible (repeatable)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: flowshell run [flags] <file.flow> [flags]")
+		flags.PrintDefaults()
+	}
+	path := parseInterspersed(flags, args)
+
+	if path == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	os.Exit(app.HeadlessRun(path, app.HeadlessOptions{
+		CacheDir:    *cacheDir,
+		NoCache:     *noCache,
+		ProfilePath: *profile,
+
+		MaxParallelism:    *maxParallel,
+		ConcurrencyLimits: limits,
+
+		Variables:    vars,
+		Inputs:       inputs,
+		OutputFormat: *outputFormat,
+	}))
+}
+
+// parseInterspersed parses flags both before and after the first positional
+// argument, so that `flowshell run graph.flow --var K=V` works. It returns the
+// positional argument, or "" if there is none. Like a flag error, a second
+// positional argument prints the usage and exits.
+func parseInterspersed(flags *flag.FlagSet, args []string) string {
+	_ = flags.Parse(args)
+	if flags.NArg() < 1 {
+		return ""
+	}
+	path := flags.Arg(0)
+	_ = flags.Parse(flags.Args()[1:])
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected argument %q\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+	return path
+}
+
+// limitFlags collects repeated -limit category=N flags.
+type limitFlags map[string]int
+
+func (l limitFlags) String() string {
+	var parts []string
+	for category, limit := range l {
+		parts = append(parts, fmt.Sprintf("%s=%d", category, limit))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (l limitFlags) Set(value string) error {
+	category, limitStr, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected category=N, got %q", value)
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		return fmt.Errorf("bad limit for %s: %v", category, err)
+	}
+	l[category] = limit
+	return nil
+}
+
+// keyValueFlags collects repeated -flag key=value flags.
+type keyValueFlags map[string]string
+
+func (kv keyValueFlags) String() string {
+	var parts []string
+	for k, v := range kv {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (kv keyValueFlags) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	kv[k] = v
+	return nil
+}
+
+func validateCommand(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: flowshell validate <file.flow>...")
+		fmt.Fprintln(flags.Output(), "Checks flows for unknown node types, invalid nodes, bad wires, cycles and type mismatches without running them.")
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	exitCode := 0
+	for _, path := range flags.Args() {
+		exitCode = max(exitCode, app.ValidateFlow(path))
+	}
+	os.Exit(exitCode)
+}
+
+func convertCommand(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: flowshell convert <in.flow> <out.flow[.json]>")
+		fmt.Fprintln(flags.Output(), "Converts a flow between the binary format and the JSON text format. The output is JSON if its name ends in .json.")
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	os.Exit(app.ConvertFlow(flags.Arg(0), flags.Arg(1)))
+}
+
+func clearCacheCommand(args []string) {
+	flags := flag.NewFlagSet("clear-cache", flag.ExitOnError)
+	cacheDir := flags.String("cache-dir", "", "directory for cached node results (default: cache_dir from settings.json)")
+	_ = flags.Parse(args)
+
+	if err := app.ClearResultCache(*cacheDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error clearing cache: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Result cache cleared.")
+}